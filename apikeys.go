@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	apiKeyHeader = "X-API-Key"
+	// apiKeyPrefix starts every API key, so that leaked keys are easy to
+	// spot, e.g. by secret scanners.
+	apiKeyPrefix = "gea_"
+	// apiKeyShownLength is how much of a key is stored in clear to tell keys
+	// apart when listing them.
+	apiKeyShownLength = len(apiKeyPrefix) + 8
+	// apiKeyUsedInterval is how often the last use of a key is recorded,
+	// sparing a write on every request.
+	apiKeyUsedInterval = time.Minute
+)
+
+// apiKeyModel is an API key of a service calling the API. Only the hash of
+// the key is stored. Zero times are unset.
+type apiKeyModel struct {
+	ID   string
+	Name string
+	// Prefix is the start of the key, enough to recognize it.
+	Prefix string
+	// Scopes are the permissions the key grants, see rolePolicy.
+	Scopes     []string
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	LastUsedAt time.Time
+	RevokedAt  time.Time
+}
+
+var (
+	// errAPIKeyNotFound is returned when no API key matches an id or hash.
+	errAPIKeyNotFound = errors.New("API key not found")
+	// errDuplicateAPIKeyName is returned when creating an API key with the
+	// name of another one.
+	errDuplicateAPIKeyName = errors.New("an API key with this name already exists")
+	// errInvalidAPIKey is returned for keys that are unknown, revoked or
+	// expired.
+	errInvalidAPIKey = errors.New("invalid API key")
+)
+
+// newAPIKey returns a random key and its hash.
+func newAPIKey() (key, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	key = apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+	return key, hashAPIKey(key), nil
+}
+
+// hashAPIKey returns the hash under which a key is stored. Keys are random,
+// so a fast hash is enough to keep them from being recovered.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// parseScopes parses a comma separated list of permissions.
+func parseScopes(s string) ([]string, error) {
+	var scopes []string
+	for _, scope := range strings.Split(s, ",") {
+		scope = strings.TrimSpace(scope)
+		switch scope {
+		case "":
+			continue
+		case permissionRead, permissionWrite, permissionDelete, permissionAudit:
+			if !slices.Contains(scopes, scope) {
+				scopes = append(scopes, scope)
+			}
+		default:
+			return nil, fmt.Errorf("unknown scope %q, use %s, %s, %s or %s", scope, permissionRead, permissionWrite, permissionDelete, permissionAudit)
+		}
+	}
+	if len(scopes) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+	return scopes, nil
+}
+
+// authenticateAPIKey returns the caller holding key, recording that the key
+// was used. It fails with errInvalidAPIKey unless the key is known, not
+// revoked and not expired.
+func authenticateAPIKey(ctx context.Context, keys APIKeyRepository, key string, now time.Time) (caller, error) {
+	apiKey, err := keys.FindAPIKey(ctx, hashAPIKey(key))
+	if errors.Is(err, errAPIKeyNotFound) {
+		return caller{}, errInvalidAPIKey
+	}
+	if err != nil {
+		return caller{}, err
+	}
+	if !apiKey.RevokedAt.IsZero() {
+		return caller{}, fmt.Errorf("%w: key %s was revoked", errInvalidAPIKey, apiKey.ID)
+	}
+	if !apiKey.ExpiresAt.IsZero() && !now.Before(apiKey.ExpiresAt) {
+		return caller{}, fmt.Errorf("%w: key %s expired", errInvalidAPIKey, apiKey.ID)
+	}
+
+	if now.Sub(apiKey.LastUsedAt) >= apiKeyUsedInterval {
+		// Failing to record the use must not fail the request
+		if err := keys.TouchAPIKey(ctx, apiKey.ID, now); err != nil {
+			log.Ctx(ctx).Error().Msg("Error recording use of API key " + apiKey.ID + ": " + err.Error())
+		}
+	}
+
+	return caller{Name: "api_key:" + apiKey.Name, Scopes: apiKey.Scopes}, nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseScopes(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []string
+		wantErr bool
+	}{
+		{in: "read", want: []string{"read"}},
+		{in: " read, write ,read,", want: []string{"read", "write"}},
+		{in: "read,write,delete", want: []string{"read", "write", "delete"}},
+		{in: "", wantErr: true},
+		{in: ",", wantErr: true},
+		{in: "read,admin", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseScopes(tt.in)
+			if (err != nil) != tt.wantErr || !slices.Equal(got, tt.want) {
+				t.Errorf("parseScopes() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+// createAPIKey stores a new key with the settings of key in repo and returns
+// the key in clear along with the stored one.
+func createAPIKey(t *testing.T, repo APIKeyRepository, key apiKeyModel) (string, apiKeyModel) {
+	t.Helper()
+	plain, hash, err := newAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(plain, apiKeyPrefix) || hash != hashAPIKey(plain) {
+		t.Fatalf("newAPIKey() = %s, %s", plain, hash)
+	}
+	key.Prefix = plain[:apiKeyShownLength]
+	stored, err := repo.CreateAPIKey(context.Background(), key, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return plain, stored
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryProjectRepository()
+	now := time.Date(2030, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	active, _ := createAPIKey(t, repo, apiKeyModel{Name: "export", Scopes: []string{"read"}, CreatedAt: now})
+	expiring, _ := createAPIKey(t, repo, apiKeyModel{Name: "import", Scopes: []string{"read", "write"}, CreatedAt: now, ExpiresAt: now.Add(time.Second)})
+	expired, _ := createAPIKey(t, repo, apiKeyModel{Name: "old", Scopes: []string{"read"}, CreatedAt: now.Add(-time.Hour), ExpiresAt: now})
+	revoked, revokedKey := createAPIKey(t, repo, apiKeyModel{Name: "leaked", Scopes: []string{"read"}, CreatedAt: now})
+	if err := repo.RevokeAPIKey(ctx, revokedKey.ID, now.Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		key  string
+		want caller
+		err  string
+	}{
+		{name: "active", key: active, want: caller{Name: "api_key:export", Scopes: []string{"read"}}},
+		{name: "expiring", key: expiring, want: caller{Name: "api_key:import", Scopes: []string{"read", "write"}}},
+		{name: "expired", key: expired, err: "expired"},
+		{name: "revoked", key: revoked, err: "revoked"},
+		{name: "unknown", key: apiKeyPrefix + "unknown", err: "invalid API key"},
+		{name: "prefix only", key: active[:apiKeyShownLength], err: "invalid API key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := authenticateAPIKey(ctx, repo, tt.key, now)
+			if tt.err != "" {
+				if !errors.Is(err, errInvalidAPIKey) || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("authenticateAPIKey() error = %v, want an invalid key error containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil || got.Name != tt.want.Name || !slices.Equal(got.Scopes, tt.want.Scopes) {
+				t.Errorf("authenticateAPIKey() = %+v, %v, want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIKeyLastUse(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryProjectRepository()
+	now := time.Date(2030, 6, 15, 12, 0, 0, 0, time.UTC)
+	key, created := createAPIKey(t, repo, apiKeyModel{Name: "export", Scopes: []string{"read"}, CreatedAt: now})
+
+	lastUsed := func() time.Time {
+		t.Helper()
+		stored, err := repo.FindAPIKey(ctx, hashAPIKey(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return stored.LastUsedAt
+	}
+
+	// Uses are recorded at most once per apiKeyUsedInterval
+	uses := []struct {
+		at   time.Time
+		want time.Time
+	}{
+		{at: now, want: now},
+		{at: now.Add(30 * time.Second), want: now},
+		{at: now.Add(apiKeyUsedInterval), want: now.Add(apiKeyUsedInterval)},
+	}
+	for _, use := range uses {
+		if _, err := authenticateAPIKey(ctx, repo, key, use.at); err != nil {
+			t.Fatal(err)
+		}
+		if got := lastUsed(); !got.Equal(use.want) {
+			t.Errorf("last used at %v after using key %s at %v, want %v", got, created.ID, use.at, use.want)
+		}
+	}
+}
+
+func TestAPIKeyRoutes(t *testing.T) {
+	router, repo := newAuthTestServer(t)
+	reader, _ := createAPIKey(t, repo, apiKeyModel{Name: "export", Scopes: []string{"read"}, CreatedAt: time.Now()})
+	revoked, revokedKey := createAPIKey(t, repo, apiKeyModel{Name: "leaked", Scopes: []string{"read", "write"}, CreatedAt: time.Now()})
+	if err := repo.RevokeAPIKey(context.Background(), revokedKey.ID, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	expired, _ := createAPIKey(t, repo, apiKeyModel{Name: "old", Scopes: []string{"read"}, CreatedAt: time.Now().Add(-time.Hour), ExpiresAt: time.Now().Add(-time.Minute)})
+
+	tests := []struct {
+		name   string
+		method string
+		key    string
+		status int
+		code   string
+	}{
+		{name: "read with a read scope", method: http.MethodGet, key: reader, status: http.StatusOK},
+		{name: "write with a read scope", method: http.MethodPost, key: reader, status: http.StatusForbidden, code: codeForbidden},
+		{name: "revoked", method: http.MethodGet, key: revoked, status: http.StatusUnauthorized, code: codeUnauthorized},
+		{name: "expired", method: http.MethodGet, key: expired, status: http.StatusUnauthorized, code: codeUnauthorized},
+		{name: "unknown", method: http.MethodGet, key: apiKeyPrefix + "unknown", status: http.StatusUnauthorized, code: codeUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := request(router, tt.method, "/api/v1/people", `{"name": "Jane Doe"}`, apiKeyHeader, tt.key)
+			if tt.code != "" {
+				checkProblem(t, w, tt.status, tt.code)
+				return
+			}
+			checkStatus(t, w, tt.status)
+		})
+	}
+}
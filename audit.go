@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Actions recorded in the audit log.
+const (
+	auditProjectCreate     = "project.create"
+	auditProjectUpdate     = "project.update"
+	auditProjectDelete     = "project.delete"
+	auditProjectTransition = "project.transition"
+	auditPaymentCreate     = "payment.create"
+	auditPaymentDelete     = "payment.delete"
+	auditMemberSet         = "member.set"
+	auditMemberDelete      = "member.delete"
+)
+
+var auditActions = []string{
+	auditProjectCreate, auditProjectUpdate, auditProjectDelete, auditProjectTransition,
+	auditPaymentCreate, auditPaymentDelete, auditMemberSet, auditMemberDelete,
+}
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+	// anonymousActor is the actor of the changes made while authentication
+	// is disabled.
+	anonymousActor = "anonymous"
+)
+
+// auditChange is the value of a field before and after a change, null when
+// the field did not exist.
+type auditChange struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+// auditEntry records a change to a project, written in the same transaction
+// as the change itself. Entries are never updated nor deleted, and outlive
+// the project they are about.
+type auditEntry struct {
+	ID        string   `json:"id" example:"42"`
+	At        dateTime `json:"at" swaggertype:"string" format:"date-time" example:"2024-03-01T09:30:00Z"`
+	Actor     string   `json:"actor" example:"auth0|5f7c8ec7c33c6c004bbafe82"`
+	Action    string   `json:"action" enums:"project.create,project.update,project.delete,project.transition,payment.create,payment.delete,member.set,member.delete" example:"project.update"`
+	ProjectID string   `json:"project_id" example:"7"`
+	RequestID string   `json:"request_id" example:"8f14e45fceea167a5a36dedd4bea2543"`
+	// Changes maps the JSON Pointer of every field that changed, within the
+	// project as returned by the API, to its values before and after.
+	// Payments are under /payments/<id> and members under /members/<id>.
+	Changes map[string]auditChange `json:"changes"`
+}
+
+type auditList struct {
+	Data  []auditEntry `json:"data"`
+	Links pageLinks    `json:"links"`
+}
+
+// auditQuery describes which page of the audit log an AuditRepository
+// should return, newest entries first.
+type auditQuery struct {
+	Limit int
+	// Before keeps the entries older than the one with this id, continuing
+	// from a previous page. Zero starts from the newest entry.
+	Before int64
+
+	ProjectID string
+	Actor     string
+	Action    string
+	// Since and Until bound the time of the entries, the former inclusively
+	// and the latter exclusively.
+	Since *time.Time
+	Until *time.Time
+}
+
+// parseAuditQuery reads pagination and filter parameters from the query
+// string.
+func parseAuditQuery(values url.Values) (auditQuery, error) {
+	query := auditQuery{Limit: defaultAuditLimit}
+
+	if v := values.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxAuditLimit {
+			return query, fmt.Errorf("limit must be between 1 and %d", maxAuditLimit)
+		}
+		query.Limit = limit
+	}
+
+	if v := values.Get("before"); v != "" {
+		before, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || before < 1 {
+			return query, errors.New("before must be the id of an audit entry")
+		}
+		query.Before = before
+	}
+
+	query.ProjectID = values.Get("project_id")
+	query.Actor = values.Get("actor")
+
+	if v := values.Get("action"); v != "" {
+		if !slices.Contains(auditActions, v) {
+			return query, errors.New("action must be one of " + strings.Join(auditActions, ", "))
+		}
+		query.Action = v
+	}
+
+	var err error
+	if query.Since, err = parseOptionalTime(values, "since"); err != nil {
+		return query, err
+	}
+	if query.Until, err = parseOptionalTime(values, "until"); err != nil {
+		return query, err
+	}
+
+	return query, nil
+}
+
+// matches reports whether entry passes the filters of query.
+func (query auditQuery) matches(entry auditEntry) bool {
+	if query.Before != 0 && auditEntryID(entry) >= query.Before {
+		return false
+	}
+	if query.ProjectID != "" && entry.ProjectID != query.ProjectID {
+		return false
+	}
+	if query.Actor != "" && entry.Actor != query.Actor {
+		return false
+	}
+	if query.Action != "" && entry.Action != query.Action {
+		return false
+	}
+	if query.Since != nil && entry.At.Before(*query.Since) {
+		return false
+	}
+	if query.Until != nil && !entry.At.Before(*query.Until) {
+		return false
+	}
+	return true
+}
+
+func auditEntryID(entry auditEntry) int64 {
+	id, _ := strconv.ParseInt(entry.ID, 10, 64)
+	return id
+}
+
+// newAuditEntry returns the entry recording that the caller of the request
+// of ctx changed before into after. before and after are the resource under
+// path within the project, nil when the change created or deleted it. The
+// entry has no changes when before and after are the same.
+func newAuditEntry(ctx context.Context, action, projectID, path string, before, after any) (auditEntry, error) {
+	changes, err := diffJSON(path, before, after)
+	if err != nil {
+		return auditEntry{}, err
+	}
+
+	actor := anonymousActor
+	if who, ok := callerFromContext(ctx); ok {
+		actor = who.Name
+	}
+	return auditEntry{
+		At:        newDateTime(time.Now()),
+		Actor:     actor,
+		Action:    action,
+		ProjectID: projectID,
+		RequestID: requestIDFromContext(ctx),
+		Changes:   changes,
+	}, nil
+}
+
+// diffJSON compares the JSON representations of before and after and
+// returns the changed fields by their JSON Pointer under path. Objects are
+// compared field by field, other values as a whole.
+func diffJSON(path string, before, after any) (map[string]auditChange, error) {
+	var from, to any
+	if err := remarshal(before, &from); err != nil {
+		return nil, err
+	}
+	if err := remarshal(after, &to); err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]auditChange)
+	diffValues(changes, path, from, to)
+	return changes, nil
+}
+
+func remarshal(v any, out *any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
+
+func diffValues(changes map[string]auditChange, path string, from, to any) {
+	fromObject, fromIsObject := from.(map[string]any)
+	toObject, toIsObject := to.(map[string]any)
+	if (fromIsObject || toIsObject) && (fromIsObject || from == nil) && (toIsObject || to == nil) {
+		for key, value := range fromObject {
+			diffValues(changes, path+"/"+escapeJSONPointer(key), value, toObject[key])
+		}
+		for key, value := range toObject {
+			if _, ok := fromObject[key]; !ok {
+				diffValues(changes, path+"/"+escapeJSONPointer(key), nil, value)
+			}
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(from, to) {
+		changes[path] = auditChange{From: from, To: to}
+	}
+}
+
+// escapeJSONPointer escapes a reference token of a JSON Pointer, undoing
+// parseJSONPointer.
+func escapeJSONPointer(token string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(token)
+}
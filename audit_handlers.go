@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getProjectHistory godoc
+// @Summary      Get change history of a project
+// @Description  Get the audit log entries of a project, newest first, including those of a deleted project. Pages are walked with links.next. Needs the audit permission.
+// @Tags         Audit
+// @Produce      json
+// @Param        id      path      int     true   "Project ID"
+// @Param        limit   query     int     false  "Maximum number of entries to return"  default(100)  maximum(1000)
+// @Param        before  query     int     false  "Only entries older than the one with this id, taken from links.next"
+// @Param        actor   query     string  false  "Only changes made by this caller"
+// @Param        action  query     string  false  "Only changes of this kind"  Enums(project.create, project.update, project.delete, project.transition, payment.create, payment.delete, member.set, member.delete)
+// @Param        since   query     string  false  "Only changes made on or after this RFC 3339 time or date"
+// @Param        until   query     string  false  "Only changes made before this RFC 3339 time or date"
+// @Success      200  {object}  auditList
+// @Failure      400  {object}  HTTPError
+// @Failure      401  {object}  HTTPError
+// @Failure      403  {object}  HTTPError
+// @Failure      404  {object}  HTTPError
+// @Failure      429  {object}  HTTPError
+// @Failure      500  {object}  HTTPError
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /projects/{id}/history [get]
+func (h *projectHandler) getProjectHistory(c *gin.Context) {
+	id := c.Param("id")
+
+	query, err := parseAuditQuery(c.Request.URL.Query())
+	if err != nil {
+		c.Error(newAPIError(http.StatusBadRequest, codeInvalidQuery, err.Error()))
+		return
+	}
+	query.ProjectID = id
+
+	entries, ok := h.listAudit(c, query)
+	if !ok {
+		return
+	}
+	if len(entries) == 0 && query.Before == 0 {
+		// Tell a project without history from a missing one
+		if _, err := h.repo.Get(c.Request.Context(), id); errors.Is(err, errProjectNotFound) {
+			c.Error(fmt.Errorf("listing history of project %s: %w", id, err))
+			return
+		}
+	}
+
+	h.writeAuditPage(c, query, entries)
+}
+
+// getAudit godoc
+// @Summary      Get audit log
+// @Description  Get the changes made to every project, newest first. Pages are walked with links.next. Needs the audit permission.
+// @Tags         Audit
+// @Produce      json
+// @Param        limit       query     int     false  "Maximum number of entries to return"  default(100)  maximum(1000)
+// @Param        before      query     int     false  "Only entries older than the one with this id, taken from links.next"
+// @Param        project_id  query     int     false  "Only changes to the project with this id"
+// @Param        actor       query     string  false  "Only changes made by this caller"
+// @Param        action      query     string  false  "Only changes of this kind"  Enums(project.create, project.update, project.delete, project.transition, payment.create, payment.delete, member.set, member.delete)
+// @Param        since       query     string  false  "Only changes made on or after this RFC 3339 time or date"
+// @Param        until       query     string  false  "Only changes made before this RFC 3339 time or date"
+// @Success      200  {object}  auditList
+// @Failure      400  {object}  HTTPError
+// @Failure      401  {object}  HTTPError
+// @Failure      403  {object}  HTTPError
+// @Failure      429  {object}  HTTPError
+// @Failure      500  {object}  HTTPError
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /audit [get]
+func (h *projectHandler) getAudit(c *gin.Context) {
+	query, err := parseAuditQuery(c.Request.URL.Query())
+	if err != nil {
+		c.Error(newAPIError(http.StatusBadRequest, codeInvalidQuery, err.Error()))
+		return
+	}
+
+	entries, ok := h.listAudit(c, query)
+	if !ok {
+		return
+	}
+	h.writeAuditPage(c, query, entries)
+}
+
+// listAudit returns the entries described by query followed by the next
+// one, if any, for writeAuditPage to tell whether there is another page. It
+// reports false once the error has been added to c.
+func (h *projectHandler) listAudit(c *gin.Context, query auditQuery) ([]auditEntry, bool) {
+	fetch := query
+	fetch.Limit++
+
+	entries, err := h.repo.ListAudit(c.Request.Context(), fetch)
+	if err != nil {
+		c.Error(fmt.Errorf("listing audit log: %w", err))
+		return nil, false
+	}
+	return entries, true
+}
+
+// writeAuditPage responds with the page of entries fetched by listAudit for
+// query, along with the link to the next page.
+func (h *projectHandler) writeAuditPage(c *gin.Context, query auditQuery, entries []auditEntry) {
+	page := auditList{Data: entries}
+	if len(page.Data) > query.Limit {
+		page.Data = page.Data[:query.Limit]
+		last := page.Data[len(page.Data)-1]
+		page.Links.Next = pageURL(c.Request.URL, map[string]string{"before": last.ID, "limit": strconv.Itoa(query.Limit)})
+	}
+	if page.Data == nil {
+		page.Data = []auditEntry{}
+	}
+
+	c.IndentedJSON(http.StatusOK, page)
+}
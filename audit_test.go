@@ -0,0 +1,139 @@
+package main
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestDiffJSON(t *testing.T) {
+	type item struct {
+		Name  string            `json:"name"`
+		Tags  []string          `json:"tags,omitempty"`
+		Extra map[string]string `json:"extra,omitempty"`
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		before any
+		after  any
+		want   map[string]auditChange
+	}{
+		{name: "unchanged", before: item{Name: "a"}, after: item{Name: "a"}, want: map[string]auditChange{}},
+		{name: "field", before: item{Name: "a"}, after: item{Name: "b"}, want: map[string]auditChange{"/name": {From: "a", To: "b"}}},
+		{name: "array as a whole", before: item{Name: "a", Tags: []string{"x"}}, after: item{Name: "a", Tags: []string{"x", "y"}}, want: map[string]auditChange{"/tags": {From: []any{"x"}, To: []any{"x", "y"}}}},
+		{name: "added field", before: item{Name: "a"}, after: item{Name: "a", Extra: map[string]string{"k": "v"}}, want: map[string]auditChange{"/extra/k": {From: nil, To: "v"}}},
+		{name: "escaped keys", before: item{Extra: map[string]string{"a/b~c": "1"}}, after: item{Extra: map[string]string{"a/b~c": "2"}}, want: map[string]auditChange{"/extra/a~1b~0c": {From: "1", To: "2"}}},
+		{name: "created under a path", path: "/payments/3", after: map[string]any{"amount": "10.00"}, want: map[string]auditChange{"/payments/3/amount": {From: nil, To: "10.00"}}},
+		{name: "deleted under a path", path: "/members/2", before: map[string]any{"role": "member"}, want: map[string]auditChange{"/members/2/role": {From: "member", To: nil}}},
+		{name: "object replaced by a value", before: map[string]any{"a": map[string]any{"b": 1}}, after: map[string]any{"a": "c"}, want: map[string]auditChange{"/a": {From: map[string]any{"b": float64(1)}, To: "c"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := diffJSON(tt.path, tt.before, tt.after)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffJSON() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAuditQuery(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantErr bool
+	}{
+		{query: ""},
+		{query: "limit=1000&before=7&project_id=3&actor=jane&action=payment.create&since=2024-01-01&until=2024-02-01T00:00:00Z"},
+		{query: "limit=0", wantErr: true},
+		{query: "limit=1001", wantErr: true},
+		{query: "before=0", wantErr: true},
+		{query: "before=abc", wantErr: true},
+		{query: "action=project.rename", wantErr: true},
+		{query: "since=yesterday", wantErr: true},
+		{query: "until=2024-13-01", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			values, _ := url.ParseQuery(tt.query)
+			_, err := parseAuditQuery(values)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseAuditQuery() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	values, _ := url.ParseQuery("project_id=3&actor=jane&action=member.set")
+	got, err := parseAuditQuery(values)
+	if err != nil || got.Limit != defaultAuditLimit || got.ProjectID != "3" || got.Actor != "jane" || got.Action != auditMemberSet {
+		t.Errorf("parseAuditQuery() = %+v, %v", got, err)
+	}
+}
+
+func TestAuditRoutes(t *testing.T) {
+	router, repo := newAuthTestServer(t)
+	proj := seedLeadership(t, repo)
+	path := "/api/v1/projects/" + proj.ID
+	admin := bearer(t, "admin@example.com", "admin")
+	editor := bearer(t, "editor@example.com", "editor")
+
+	// Changes by the editor and the admin, after the creation by nobody
+	checkStatus(t, request(router, http.MethodPatch, path, `{"title": "Bridge 2"}`, "Authorization", editor, "Content-Type", "application/merge-patch+json"), http.StatusOK)
+	checkStatus(t, request(router, http.MethodPost, path+"/payments", paymentBody("100", "USD"), "Authorization", admin), http.StatusCreated)
+	checkStatus(t, request(router, http.MethodPatch, path, `{"title": "Bridge 3"}`, "Authorization", admin, "Content-Type", "application/merge-patch+json"), http.StatusOK)
+
+	tests := []struct {
+		name    string
+		path    string
+		token   string
+		status  int
+		actions []string
+		next    bool
+	}{
+		{name: "history", path: path + "/history", token: admin, status: http.StatusOK, actions: []string{auditProjectUpdate, auditPaymentCreate, auditProjectUpdate, auditProjectCreate}},
+		{name: "history by actor", path: path + "/history?actor=editor@example.com", token: admin, status: http.StatusOK, actions: []string{auditProjectUpdate}},
+		{name: "history by action", path: path + "/history?action=payment.create", token: admin, status: http.StatusOK, actions: []string{auditPaymentCreate}},
+		{name: "history page", path: path + "/history?limit=2", token: admin, status: http.StatusOK, actions: []string{auditProjectUpdate, auditPaymentCreate}, next: true},
+		{name: "history of a missing project", path: "/api/v1/projects/999/history", token: admin, status: http.StatusNotFound},
+		{name: "history without the audit permission", path: path + "/history", token: editor, status: http.StatusForbidden},
+		{name: "audit by project", path: "/api/v1/audit?project_id=" + proj.ID + "&action=project.create", token: admin, status: http.StatusOK, actions: []string{auditProjectCreate}},
+		{name: "audit of another project", path: "/api/v1/audit?project_id=999", token: admin, status: http.StatusOK, actions: []string{}},
+		{name: "audit with an invalid filter", path: "/api/v1/audit?action=project.rename", token: admin, status: http.StatusBadRequest},
+		{name: "audit without the audit permission", path: "/api/v1/audit", token: editor, status: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := request(router, http.MethodGet, tt.path, "", "Authorization", tt.token)
+			switch tt.status {
+			case http.StatusForbidden:
+				checkProblem(t, w, tt.status, codeForbidden)
+				return
+			case http.StatusBadRequest:
+				checkProblem(t, w, tt.status, codeInvalidQuery)
+				return
+			}
+			checkStatus(t, w, tt.status)
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			var page auditList
+			decode(t, w, &page)
+			actions := []string{}
+			for _, entry := range page.Data {
+				actions = append(actions, entry.Action)
+			}
+			if !reflect.DeepEqual(actions, tt.actions) {
+				t.Errorf("actions = %v, want %v", actions, tt.actions)
+			}
+			if (page.Links.Next != "") != tt.next {
+				t.Errorf("links.next = %q, want one: %v", page.Links.Next, tt.next)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-example-api/internal/auth"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// callerKey is the key of the gin context under which authentication stores
+// the caller of the request.
+const callerKey = "caller"
+
+// callerContextKey is the key of the request context under which
+// authentication stores the caller.
+type callerContextKey struct{}
+
+// callerFromContext returns the caller of the request of ctx, if
+// authenticated.
+func callerFromContext(ctx context.Context) (caller, bool) {
+	who, ok := ctx.Value(callerContextKey{}).(caller)
+	return who, ok
+}
+
+// caller is the authenticated client of a request: a person holding a token
+// or a service holding an API key.
+type caller struct {
+	// Name identifies the caller in logs, the subject of the token or
+	// api_key:<name>.
+	Name string
+	// Email and Roles are taken from the token of a person.
+	Email string
+	Roles []string
+	// Scopes are the permissions granted by an API key.
+	Scopes []string
+}
+
+// authentication rejects with a 401 problem the requests that carry neither
+// a valid JWT bearer token nor a valid API key in the X-API-Key header. The
+// caller is stored under callerKey and in the request context, and their
+// name under userKey, added to the request logger and recorded on the
+// request span. Bearer tokens are rejected when verifier is nil.
+func authentication(verifier *auth.Verifier, keys APIKeyRepository) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		var who caller
+		if key := c.GetHeader(apiKeyHeader); key != "" {
+			var err error
+			who, err = authenticateAPIKey(ctx, keys, key, time.Now().UTC().Truncate(time.Second))
+			if errors.Is(err, errInvalidAPIKey) {
+				unauthorized(c, "the API key is invalid, revoked or expired", err)
+				return
+			}
+			if err != nil {
+				c.Error(err)
+				c.Abort()
+				return
+			}
+		} else {
+			if verifier == nil {
+				unauthorized(c, "an API key is required", nil)
+				return
+			}
+			token, ok := bearerToken(c.GetHeader("Authorization"))
+			if !ok {
+				unauthorized(c, "a bearer token or an API key is required", nil)
+				return
+			}
+			claims, err := verifier.Verify(ctx, token)
+			if err != nil {
+				unauthorized(c, "the bearer token is invalid or expired", err)
+				return
+			}
+			who = caller{Name: claims.Subject, Email: claims.Email, Roles: claims.Roles}
+		}
+
+		c.Set(userKey, who.Name)
+		c.Set(callerKey, who)
+		trace.SpanFromContext(ctx).SetAttributes(semconv.EnduserID(who.Name))
+		logger := log.Ctx(ctx).With().Str("user", who.Name).Logger()
+		ctx = context.WithValue(ctx, callerContextKey{}, who)
+		c.Request = c.Request.WithContext(logger.WithContext(ctx))
+
+		c.Next()
+	}
+}
+
+// unauthorized aborts the request with a 401 problem. err is the reason the
+// credentials were rejected, nil when there were none.
+func unauthorized(c *gin.Context, detail string, err error) {
+	if err != nil {
+		c.Header("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+	} else {
+		c.Header("WWW-Authenticate", `Bearer realm="api"`)
+	}
+	c.Error(&apiError{Status: http.StatusUnauthorized, Code: codeUnauthorized, Detail: detail, Err: err})
+	c.Abort()
+}
+
+// bearerToken extracts the token of an Authorization header using the Bearer
+// scheme, whose name is case insensitive.
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
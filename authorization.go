@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Permissions granted by the roles of the callers, see config.Auth.Roles.
+const (
+	permissionRead   = "read"
+	permissionWrite  = "write"
+	permissionDelete = "delete"
+	permissionAudit  = "audit"
+)
+
+// errForbidden is returned by an authorizer when the caller lacks the
+// permission a request needs.
+var errForbidden = errors.New("permission denied")
+
+// authorizer decides whether a caller may use a permission. projectID is the
+// project the request is about, empty for other requests.
+type authorizer interface {
+	authorize(ctx context.Context, who caller, permission, projectID string) error
+}
+
+// rolePolicy grants callers the permissions of their roles, and API keys
+// their scopes. The leader of a project may also delete it and its payments
+// and members without the delete permission. A caller leads a project when
+// the email claim of their token is the email of its leader.
+type rolePolicy struct {
+	roles map[string][]string
+	repo  ProjectRepository
+}
+
+func newRolePolicy(roles map[string][]string, repo ProjectRepository) *rolePolicy {
+	return &rolePolicy{roles: roles, repo: repo}
+}
+
+func (p *rolePolicy) authorize(ctx context.Context, who caller, permission, projectID string) error {
+	if slices.Contains(who.Scopes, permission) {
+		return nil
+	}
+	for _, role := range who.Roles {
+		if slices.Contains(p.roles[role], permission) {
+			return nil
+		}
+	}
+
+	if permission == permissionDelete && projectID != "" {
+		leads, err := p.leads(ctx, who, projectID)
+		if err != nil {
+			return err
+		}
+		if leads {
+			return nil
+		}
+		return fmt.Errorf("%w: deleting needs the delete permission or leading the project", errForbidden)
+	}
+	return fmt.Errorf("%w: the %s permission is needed", errForbidden, permission)
+}
+
+// leads reports whether a caller leads the project with the given id.
+func (p *rolePolicy) leads(ctx context.Context, who caller, projectID string) (bool, error) {
+	if who.Email == "" {
+		return false, nil
+	}
+
+	project, err := p.repo.Get(ctx, projectID)
+	if errors.Is(err, errProjectNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	leader, err := p.repo.GetPerson(ctx, project.LeaderID)
+	if errors.Is(err, errPersonNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return strings.EqualFold(leader.Email, who.Email), nil
+}
+
+// authorizerKey is the key of the gin context under which authorization
+// stores its authorizer, see requirePermission.
+const authorizerKey = "authorizer"
+
+// requirePermission returns errForbidden unless the caller may use
+// permission beyond the one the method of the request needs. Changing the
+// leader of a project or the email of a person needs the delete permission,
+// since it would let an editor lead a project and then delete it. Every
+// permission is granted when authentication is disabled.
+func requirePermission(c *gin.Context, permission string) error {
+	who, ok := c.Get(callerKey)
+	if !ok {
+		return nil
+	}
+	a := c.MustGet(authorizerKey).(authorizer)
+	return a.authorize(c.Request.Context(), who.(caller), permission, "")
+}
+
+// checkLeaderChange returns denied, the error of requirePermission for the
+// delete permission, when updated has another leader than current.
+func checkLeaderChange(current, updated projectModel, denied error) error {
+	if denied == nil || updated.LeaderID == current.LeaderID {
+		return nil
+	}
+	return fmt.Errorf("changing the leader: %w", denied)
+}
+
+// ledBy narrows precondition to the projects still led by leaderID, so that
+// the leader checked before a write cannot change before the write.
+func ledBy(precondition projectPrecondition, leaderID string) projectPrecondition {
+	return func(current projectModel) bool {
+		return current.LeaderID == leaderID && (precondition == nil || precondition(current))
+	}
+}
+
+// authorization rejects with a 403 problem the requests the caller may not
+// make. It runs after authentication. Reading needs the read permission,
+// DELETE the delete permission and the other methods the write permission.
+// The audit log and the history of a project need the audit permission.
+func authorization(a authorizer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		who := c.MustGet(callerKey).(caller)
+
+		permission := permissionWrite
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			permission = permissionRead
+		case http.MethodDelete:
+			permission = permissionDelete
+		}
+		if route := c.FullPath(); strings.HasSuffix(route, "/audit") || strings.HasSuffix(route, "/history") {
+			permission = permissionAudit
+		}
+
+		var projectID string
+		if route := c.FullPath(); strings.Contains(route, "/projects/:id") || strings.Contains(route, "/project/:id") {
+			projectID = c.Param("id")
+		}
+
+		if err := a.authorize(c.Request.Context(), who, permission, projectID); err != nil {
+			c.Error(err)
+			c.Abort()
+			return
+		}
+
+		c.Set(authorizerKey, a)
+		c.Next()
+	}
+}
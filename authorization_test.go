@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"go-example-api/internal/auth"
+	"go-example-api/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+// testRoles are the default roles of the configuration.
+var testRoles = config.Default().Auth.Roles
+
+// seedLeadership stores Jane Doe, leading the Bridge project, and returns
+// the project.
+func seedLeadership(t *testing.T, repo ProjectRepository) projectModel {
+	t.Helper()
+	ctx := context.Background()
+	jane, err := repo.CreatePerson(ctx, personModel{Name: "Jane Doe", Email: "jane@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	budget, err := parseMoney("3000", "USD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	proj, err := repo.Create(ctx, projectModel{
+		Title:    "Bridge",
+		LeaderID: jane.ID,
+		Budget: budgetModel{
+			BudgetValue: budget,
+			DownPayment: money{Currency: "USD"},
+			Deadline:    newDateTime(time.Now().AddDate(1, 0, 0)),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return proj
+}
+
+func TestRolePolicyAuthorize(t *testing.T) {
+	repo := newMemoryProjectRepository()
+	proj := seedLeadership(t, repo)
+	policy := newRolePolicy(testRoles, repo)
+
+	tests := []struct {
+		name       string
+		email      string
+		roles      []string
+		permission string
+		projectID  string
+		allowed    bool
+	}{
+		{name: "viewer reads", roles: []string{"viewer"}, permission: permissionRead, allowed: true},
+		{name: "viewer writes", roles: []string{"viewer"}, permission: permissionWrite},
+		{name: "editor writes", roles: []string{"editor"}, permission: permissionWrite, projectID: proj.ID, allowed: true},
+		{name: "editor deletes", roles: []string{"editor"}, permission: permissionDelete, projectID: proj.ID},
+		{name: "admin deletes", roles: []string{"admin"}, permission: permissionDelete, projectID: proj.ID, allowed: true},
+		{name: "any role granting", roles: []string{"viewer", "editor"}, permission: permissionWrite, allowed: true},
+		{name: "leader deletes", email: "JANE@example.com", roles: []string{"viewer"}, permission: permissionDelete, projectID: proj.ID, allowed: true},
+		{name: "leader writes", email: "jane@example.com", roles: []string{"viewer"}, permission: permissionWrite, projectID: proj.ID},
+		{name: "leader deletes another project", email: "jane@example.com", permission: permissionDelete, projectID: "999"},
+		{name: "leader deletes without a project", email: "jane@example.com", permission: permissionDelete},
+		{name: "someone else deletes", email: "john@example.com", roles: []string{"editor"}, permission: permissionDelete, projectID: proj.ID},
+		{name: "unknown role", roles: []string{"owner"}, permission: permissionRead},
+		{name: "no role", permission: permissionRead},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			who := caller{Name: "tester", Email: tt.email, Roles: tt.roles}
+			err := policy.authorize(context.Background(), who, tt.permission, tt.projectID)
+			if tt.allowed {
+				if err != nil {
+					t.Errorf("authorize() = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.Is(err, errForbidden) {
+				t.Errorf("authorize() = %v, want errForbidden", err)
+			}
+		})
+	}
+}
+
+// newAuthTestServer returns the API routes backed by a memory repository,
+// requiring tokens signed with testSecret and authorizing them with the
+// default roles.
+func newAuthTestServer(t *testing.T) (*gin.Engine, *memoryProjectRepository) {
+	t.Helper()
+
+	verifier, err := auth.New(context.Background(), config.Auth{HS256Secret: testSecret})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := newMemoryProjectRepository()
+	router := gin.New()
+	router.Use(problemErrors())
+	registerRoutes(router, newProjectHandler(repo), authentication(verifier, repo), authorization(newRolePolicy(testRoles, repo)))
+	return router, repo
+}
+
+// bearer returns the Authorization header of a token for the caller with
+// the given email and roles.
+func bearer(t *testing.T, email string, roles ...string) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":   email,
+		"email": email,
+		"roles": roles,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	})
+	signed, err := token.SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "Bearer " + signed
+}
+
+func TestEditorCannotTakeTheLead(t *testing.T) {
+	router, repo := newAuthTestServer(t)
+	proj := seedLeadership(t, repo)
+	path := "/api/v1/projects/" + proj.ID
+	editor := bearer(t, "mallory@example.com", "editor")
+	admin := bearer(t, "admin@example.com", "admin")
+
+	// An editor may add themselves as a person, but not make them lead
+	w := request(router, http.MethodPost, "/api/v1/people", `{"name": "Mallory", "email": "mallory@example.com"}`, "Authorization", editor)
+	checkStatus(t, w, http.StatusCreated)
+	var mallory personModel
+	decode(t, w, &mallory)
+
+	// The steps share one repository and build on each other
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		token  string
+		status int
+	}{
+		{name: "delete a project led by someone else", method: http.MethodDelete, path: path, token: editor, status: http.StatusForbidden},
+		{name: "take over the email of the leader", method: http.MethodPut, path: "/api/v1/people/" + proj.LeaderID, body: `{"name": "Jane Doe", "email": "mallory@example.com"}`, token: editor, status: http.StatusForbidden},
+		{name: "remove the email of the leader", method: http.MethodPut, path: "/api/v1/people/" + proj.LeaderID, body: `{"name": "Jane Doe"}`, token: editor, status: http.StatusForbidden},
+		{name: "rename the leader", method: http.MethodPut, path: "/api/v1/people/" + proj.LeaderID, body: `{"name": "Jane Smith", "email": "Jane@example.com"}`, token: editor, status: http.StatusOK},
+		{name: "change leader_id", method: http.MethodPut, path: path, body: projectBody("Bridge", mallory.ID, "3000", "USD", futureDeadline), token: editor, status: http.StatusForbidden},
+		{name: "patch leader_id", method: http.MethodPatch, path: path, body: `{"leader_id": "` + mallory.ID + `"}`, token: editor, status: http.StatusForbidden},
+		{name: "become leader as a member", method: http.MethodPut, path: path + "/members/" + mallory.ID, body: `{"role": "leader"}`, token: editor, status: http.StatusForbidden},
+		{name: "join as a member", method: http.MethodPut, path: path + "/members/" + mallory.ID, body: `{"role": "member"}`, token: editor, status: http.StatusOK},
+		{name: "update keeping the leader", method: http.MethodPut, path: path, body: projectBody("Bridge 2", proj.LeaderID, "3000", "USD", futureDeadline), token: editor, status: http.StatusOK},
+		{name: "patch keeping the leader", method: http.MethodPatch, path: path, body: `{"title": "Bridge 3"}`, token: editor, status: http.StatusOK},
+		{name: "delete after all this", method: http.MethodDelete, path: path, token: editor, status: http.StatusForbidden},
+		{name: "admin changes the leader", method: http.MethodPatch, path: path, body: `{"leader_id": "` + mallory.ID + `"}`, token: admin, status: http.StatusOK},
+		{name: "new leader deletes", method: http.MethodDelete, path: path, token: editor, status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := []string{"Authorization", tt.token}
+			if tt.method == http.MethodPatch {
+				header = append(header, "Content-Type", "application/merge-patch+json")
+			}
+			w := request(router, tt.method, tt.path, tt.body, header...)
+			if tt.status == http.StatusForbidden {
+				checkProblem(t, w, tt.status, codeForbidden)
+				return
+			}
+			checkStatus(t, w, tt.status)
+		})
+	}
+}
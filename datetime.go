@@ -0,0 +1,80 @@
+package main
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// dateTimeLayouts are the formats accepted for a dateTime. A date without a
+// time stands for midnight UTC.
+var dateTimeLayouts = []string{time.RFC3339, time.DateOnly}
+
+// dateTime is a point in time exchanged as RFC 3339 in JSON and stored as a
+// DATETIME column in UTC. It is kept in UTC and to the second, the precision
+// of the column, so that two dateTimes of the same instant are equal.
+type dateTime struct {
+	time.Time
+}
+
+func newDateTime(t time.Time) dateTime {
+	return dateTime{t.UTC().Truncate(time.Second)}
+}
+
+// parseDateTime parses s written in one of dateTimeLayouts. Offsets other
+// than Z are converted to UTC.
+func parseDateTime(s string) (dateTime, error) {
+	for _, layout := range dateTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return newDateTime(t), nil
+		}
+	}
+	return dateTime{}, fmt.Errorf("%q is neither an RFC 3339 time nor a YYYY-MM-DD date", s)
+}
+
+func (t dateTime) String() string {
+	return t.Format(time.RFC3339)
+}
+
+func (t dateTime) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.String())
+}
+
+func (t *dateTime) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	parsed, err := parseDateTime(s)
+	if err != nil {
+		return err
+	}
+	*t = parsed
+	return nil
+}
+
+// Value writes t to the database in UTC.
+func (t dateTime) Value() (driver.Value, error) {
+	return t.Time, nil
+}
+
+// Scan reads a DATETIME column, which the driver returns as time.Time when
+// parseTime is enabled.
+func (t *dateTime) Scan(src any) error {
+	switch v := src.(type) {
+	case time.Time:
+		*t = newDateTime(v)
+	case []byte:
+		return t.Scan(string(v))
+	case string:
+		parsed, err := time.Parse(time.DateTime, v)
+		if err != nil {
+			return err
+		}
+		*t = newDateTime(parsed)
+	default:
+		return fmt.Errorf("cannot scan %T into a dateTime", src)
+	}
+	return nil
+}
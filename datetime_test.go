@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseDateTime(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "2030-12-31", want: "2030-12-31T00:00:00Z"},
+		{in: "2030-12-31T17:00:00Z", want: "2030-12-31T17:00:00Z"},
+		{in: "2030-12-31T17:00:00+07:00", want: "2030-12-31T10:00:00Z"},
+		{in: "2030-12-31T17:00:00.999-01:00", want: "2030-12-31T18:00:00Z"},
+		{in: "31/12/2030", wantErr: true},
+		{in: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseDateTime(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseDateTime(%q) = %s, want an error", tt.in, got)
+				}
+				return
+			}
+			if err != nil || got.String() != tt.want {
+				t.Errorf("parseDateTime(%q) = %s, %v, want %s", tt.in, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDateTimeJSON(t *testing.T) {
+	var got struct {
+		Deadline dateTime `json:"deadline"`
+	}
+	if err := json.Unmarshal([]byte(`{"deadline": "2030-12-31T17:00:00+07:00"}`), &got); err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"deadline":"2030-12-31T10:00:00Z"}` {
+		t.Errorf("marshalled %s, want the deadline in UTC", b)
+	}
+
+	if err := json.Unmarshal([]byte(`{"deadline": 2030}`), &got); err == nil {
+		t.Error("unmarshalled a number into a dateTime")
+	}
+}
+
+func TestDateTimeScan(t *testing.T) {
+	want := newDateTime(time.Date(2030, 12, 31, 10, 0, 0, 0, time.UTC))
+	tests := []struct {
+		name string
+		src  any
+	}{
+		{name: "time", src: time.Date(2030, 12, 31, 17, 0, 0, 500, time.FixedZone("", 7*60*60))},
+		{name: "bytes", src: []byte("2030-12-31 10:00:00")},
+		{name: "string", src: "2030-12-31 10:00:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got dateTime
+			if err := got.Scan(tt.src); err != nil || got != want {
+				t.Errorf("Scan(%v) = %s, %v, want %s", tt.src, got, err, want)
+			}
+		})
+	}
+
+	var got dateTime
+	if err := got.Scan(int64(2030)); err == nil {
+		t.Error("scanned an int64 into a dateTime")
+	}
+}
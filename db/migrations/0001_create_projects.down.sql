@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS `project_budget`;
+DROP TABLE IF EXISTS `project`;
@@ -1,11 +1,13 @@
-CREATE DATABASE IF NOT exists `company` /*!40100 DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci */ /*!80016 DEFAULT ENCRYPTION='N' */;
-CREATE TABLE IF NOT exists `company`.`project` (
+-- IF NOT EXISTS adopts databases created by hand from the former
+-- db/schema_go.sql
+CREATE TABLE IF NOT EXISTS `project` (
   `id` int NOT NULL AUTO_INCREMENT,
   `title` varchar(255) NOT NULL,
   `leader` varchar(255) NOT NULL,
   PRIMARY KEY (`id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
-CREATE TABLE IF NOT exists `company`.`project_budget` (
+
+CREATE TABLE IF NOT EXISTS `project_budget` (
   `id` int NOT NULL AUTO_INCREMENT,
   `budget_value` int NOT NULL,
   `down_payment` int NOT NULL,
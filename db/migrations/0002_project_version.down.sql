@@ -0,0 +1,3 @@
+ALTER TABLE `project_budget` DROP COLUMN `version`;
+
+ALTER TABLE `project` DROP COLUMN `version`;
@@ -0,0 +1,4 @@
+-- The version columns behind the ETags, formerly db/alter_add_version.sql
+ALTER TABLE `project` ADD COLUMN `version` int NOT NULL DEFAULT 1;
+
+ALTER TABLE `project_budget` ADD COLUMN `version` int NOT NULL DEFAULT 1;
@@ -0,0 +1,11 @@
+DROP INDEX `deadline` ON `project_budget`;
+
+ALTER TABLE `project_budget` ADD COLUMN `deadline_text` varchar(255) NULL;
+
+UPDATE `project_budget` SET `deadline_text` = DATE_FORMAT(`deadline`, '%Y-%m-%dT%H:%i:%sZ');
+
+ALTER TABLE `project_budget` MODIFY `deadline_text` varchar(255) NOT NULL;
+
+ALTER TABLE `project_budget` DROP COLUMN `deadline`;
+
+ALTER TABLE `project_budget` RENAME COLUMN `deadline_text` TO `deadline`;
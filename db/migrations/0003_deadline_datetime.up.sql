@@ -0,0 +1,24 @@
+-- Deadlines were free text. Dates become midnight UTC and RFC 3339 times are
+-- converted to UTC, dropping fractions of seconds. Any other value is left
+-- NULL, which makes the NOT NULL change below fail: fix those rows, drop the
+-- deadline_at column and delete the row of this migration from
+-- schema_migrations before migrating again.
+ALTER TABLE `project_budget` ADD COLUMN `deadline_at` datetime NULL;
+
+UPDATE `project_budget` SET `deadline_at` = CASE
+  WHEN `deadline` REGEXP '^[0-9]{4}-[0-9]{2}-[0-9]{2}$'
+    THEN STR_TO_DATE(`deadline`, '%Y-%m-%d')
+  WHEN `deadline` REGEXP '^[0-9]{4}-[0-9]{2}-[0-9]{2}[Tt][0-9]{2}:[0-9]{2}:[0-9]{2}(\\.[0-9]+)?([Zz]|[+-][0-9]{2}:[0-9]{2})$'
+    THEN CONVERT_TZ(
+      STR_TO_DATE(REPLACE(LEFT(`deadline`, 19), 't', 'T'), '%Y-%m-%dT%H:%i:%s'),
+      IF(UPPER(RIGHT(`deadline`, 1)) = 'Z', '+00:00', RIGHT(`deadline`, 6)),
+      '+00:00')
+END;
+
+ALTER TABLE `project_budget` MODIFY `deadline_at` datetime NOT NULL;
+
+ALTER TABLE `project_budget` DROP COLUMN `deadline`;
+
+ALTER TABLE `project_budget` RENAME COLUMN `deadline_at` TO `deadline`;
+
+CREATE INDEX `deadline` ON `project_budget` (`deadline`);
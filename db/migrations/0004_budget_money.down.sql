@@ -0,0 +1,8 @@
+-- Amounts go back to whole units, taking every currency to have cents, and
+-- the currency is dropped.
+UPDATE `project_budget` SET `budget_value` = `budget_value` DIV 100, `down_payment` = `down_payment` DIV 100;
+
+ALTER TABLE `project_budget`
+  DROP COLUMN `currency`,
+  MODIFY `budget_value` int NOT NULL,
+  MODIFY `down_payment` int NOT NULL;
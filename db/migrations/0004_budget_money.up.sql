@@ -0,0 +1,12 @@
+-- Amounts move to 64-bit minor units of the new currency column. Existing
+-- budgets had no currency and are taken to be whole US dollars. If they were
+-- in another currency, change the default and the factor of 100 (10 to the
+-- power of the minor unit digits of that currency) before migrating.
+ALTER TABLE `project_budget`
+  MODIFY `budget_value` bigint NOT NULL,
+  MODIFY `down_payment` bigint NOT NULL,
+  ADD COLUMN `currency` char(3) NOT NULL DEFAULT 'USD' AFTER `down_payment`;
+
+UPDATE `project_budget` SET `budget_value` = `budget_value` * 100, `down_payment` = `down_payment` * 100;
+
+ALTER TABLE `project_budget` ALTER COLUMN `currency` DROP DEFAULT;
@@ -0,0 +1,13 @@
+-- Payments towards the budget of a project, in minor units of its currency.
+CREATE TABLE `project_payment` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `project_id` int NOT NULL,
+  `amount` bigint NOT NULL,
+  `currency` char(3) NOT NULL,
+  `paid_at` datetime NOT NULL,
+  `payer` varchar(255) NOT NULL,
+  `note` varchar(1000) NOT NULL DEFAULT '',
+  PRIMARY KEY (`id`),
+  KEY `project_payment_project_id_paid_at` (`project_id`, `paid_at`),
+  CONSTRAINT `project_payment_ibfk_1` FOREIGN KEY (`project_id`) REFERENCES `project` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
@@ -0,0 +1,14 @@
+-- Projects get the name of their leader back. Members and people are lost.
+ALTER TABLE `project` ADD COLUMN `leader` varchar(255) NULL AFTER `title`;
+
+UPDATE `project` SET `leader` = (SELECT `person`.`name` FROM `person` WHERE `person`.`id` = `project`.`leader_id`);
+
+ALTER TABLE `project` MODIFY `leader` varchar(255) NOT NULL;
+
+DROP TABLE `project_member`;
+
+ALTER TABLE `project` DROP FOREIGN KEY `project_leader_id`;
+
+ALTER TABLE `project` DROP COLUMN `leader_id`;
+
+DROP TABLE `person`;
@@ -0,0 +1,33 @@
+-- Leaders were free text. Every distinct leader becomes a person, referenced
+-- by the projects they lead and recorded as their leader member.
+CREATE TABLE `person` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `name` varchar(255) NOT NULL,
+  `email` varchar(255) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `person_email` (`email`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+INSERT INTO `person` (`name`) SELECT DISTINCT `leader` FROM `project`;
+
+ALTER TABLE `project` ADD COLUMN `leader_id` int NULL AFTER `leader`;
+
+UPDATE `project` SET `leader_id` = (SELECT MIN(`person`.`id`) FROM `person` WHERE `person`.`name` = `project`.`leader`);
+
+ALTER TABLE `project`
+  MODIFY `leader_id` int NOT NULL,
+  ADD CONSTRAINT `project_leader_id` FOREIGN KEY (`leader_id`) REFERENCES `person` (`id`);
+
+ALTER TABLE `project` DROP COLUMN `leader`;
+
+CREATE TABLE `project_member` (
+  `project_id` int NOT NULL,
+  `person_id` int NOT NULL,
+  `role` enum('leader','member','viewer') NOT NULL,
+  PRIMARY KEY (`project_id`, `person_id`),
+  KEY `project_member_person_id` (`person_id`),
+  CONSTRAINT `project_member_project_id` FOREIGN KEY (`project_id`) REFERENCES `project` (`id`),
+  CONSTRAINT `project_member_person_id` FOREIGN KEY (`person_id`) REFERENCES `person` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+INSERT INTO `project_member` (`project_id`, `person_id`, `role`) SELECT `id`, `leader_id`, 'leader' FROM `project`;
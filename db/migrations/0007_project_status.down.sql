@@ -0,0 +1,6 @@
+DROP TABLE `project_transition`;
+
+ALTER TABLE `project`
+  DROP KEY `project_status`,
+  DROP COLUMN `status_changed_at`,
+  DROP COLUMN `status`;
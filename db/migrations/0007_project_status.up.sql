@@ -0,0 +1,24 @@
+-- Projects get a lifecycle status. Existing projects are taken to be active,
+-- new ones start out planned.
+ALTER TABLE `project`
+  ADD COLUMN `status` enum('planned','active','on_hold','completed','cancelled') NOT NULL DEFAULT 'active' AFTER `leader_id`,
+  ADD COLUMN `status_changed_at` datetime NULL AFTER `status`,
+  ADD KEY `project_status` (`status`);
+
+UPDATE `project` SET `status_changed_at` = UTC_TIMESTAMP();
+
+ALTER TABLE `project`
+  MODIFY `status` enum('planned','active','on_hold','completed','cancelled') NOT NULL DEFAULT 'planned',
+  MODIFY `status_changed_at` datetime NOT NULL;
+
+CREATE TABLE `project_transition` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `project_id` int NOT NULL,
+  `from_status` enum('planned','active','on_hold','completed','cancelled') NOT NULL,
+  `to_status` enum('planned','active','on_hold','completed','cancelled') NOT NULL,
+  `reason` varchar(1000) NOT NULL DEFAULT '',
+  `created_at` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `project_transition_project_id` (`project_id`),
+  CONSTRAINT `project_transition_project_id` FOREIGN KEY (`project_id`) REFERENCES `project` (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
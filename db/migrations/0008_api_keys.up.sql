@@ -0,0 +1,16 @@
+-- API keys of the services calling the API. Only the SHA-256 hash of each
+-- key is stored, along with its first characters to recognize it.
+CREATE TABLE `api_key` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `name` varchar(255) NOT NULL,
+  `prefix` varchar(16) NOT NULL,
+  `hash` char(64) NOT NULL,
+  `scopes` varchar(255) NOT NULL,
+  `created_at` datetime NOT NULL,
+  `expires_at` datetime NULL,
+  `last_used_at` datetime NULL,
+  `revoked_at` datetime NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `api_key_name` (`name`),
+  UNIQUE KEY `api_key_hash` (`hash`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
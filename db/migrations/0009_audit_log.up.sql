@@ -0,0 +1,23 @@
+-- Append-only log of the changes to projects. Entries keep the id of their
+-- project without a foreign key, so that they outlive it.
+CREATE TABLE `audit_log` (
+  `id` bigint NOT NULL AUTO_INCREMENT,
+  `created_at` datetime NOT NULL,
+  `actor` varchar(255) NOT NULL,
+  `action` varchar(32) NOT NULL,
+  `project_id` int NOT NULL,
+  `request_id` varchar(128) NOT NULL DEFAULT '',
+  `changes` json NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `audit_log_project_id` (`project_id`, `id`),
+  KEY `audit_log_actor` (`actor`, `id`),
+  KEY `audit_log_created_at` (`created_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;
+
+-- Entries cannot be changed nor removed. The triggers are kept on one line
+-- for the migration runner, which splits statements on line ends. Creating
+-- them needs SUPER while binary logging is on, unless the server runs with
+-- log_bin_trust_function_creators=1.
+CREATE TRIGGER `audit_log_no_update` BEFORE UPDATE ON `audit_log` FOR EACH ROW BEGIN SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_log is append-only'; END;
+
+CREATE TRIGGER `audit_log_no_delete` BEFORE DELETE ON `audit_log` FOR EACH ROW BEGIN SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_log is append-only'; END;
@@ -0,0 +1,13 @@
+// Package migrations holds the SQL migrations of the MySQL schema.
+//
+// Every migration is a pair of files named <version>_<name>.up.sql and
+// <version>_<name>.down.sql. Statements are separated by a semicolon at the
+// end of a line.
+package migrations
+
+import "embed"
+
+// FS contains the migration files.
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/go-sql-driver/mysql"
+	"github.com/rs/zerolog/log"
+)
+
+// Error codes sent in the code field of problem responses. Clients match on
+// them, so existing codes must never change.
+const (
+	codeBadRequest           = "bad_request"
+	codeUnauthorized         = "unauthorized"
+	codeForbidden            = "forbidden"
+	codeRateLimited          = "rate_limited"
+	codeInvalidQuery         = "invalid_query"
+	codeValidationFailed     = "validation_failed"
+	codeInvalidPatch         = "invalid_patch"
+	codePatchTestFailed      = "patch_test_failed"
+	codeUnsupportedMediaType = "unsupported_media_type"
+	codeNotFound             = "not_found"
+	codeRouteNotFound        = "route_not_found"
+	codeMethodNotAllowed     = "method_not_allowed"
+	codePreconditionFailed   = "precondition_failed"
+	codeDuplicateKey         = "duplicate_key"
+	codeForeignKeyViolation  = "foreign_key_violation"
+	codeLeaderRequired       = "leader_required"
+	codeInvalidTransition    = "invalid_transition"
+	codeProjectCompleted     = "project_completed"
+	codeDeadlock             = "deadlock"
+	codeLockTimeout          = "lock_timeout"
+	codeInternal             = "internal_error"
+)
+
+// MySQL server error numbers mapped to HTTP statuses.
+const (
+	mysqlErrDupEntry        = 1062
+	mysqlErrRowIsReferenced = 1451
+	mysqlErrNoReferencedRow = 1452
+	mysqlErrLockWaitTimeout = 1205
+	mysqlErrLockDeadlock    = 1213
+)
+
+const (
+	problemContentType = "application/problem+json"
+	// problemTypePrefix is joined with the error code to build the type
+	// URI reference of a problem.
+	problemTypePrefix = "/problems/"
+	// retryAfterSeconds is sent with errors that go away when retried.
+	retryAfterSeconds = 1
+)
+
+// HTTPError is the body of every error response, a problem details object
+// as described in RFC 7807.
+type HTTPError struct {
+	Type      string       `json:"type" example:"/problems/not_found"`
+	Title     string       `json:"title" example:"Not Found"`
+	Status    int          `json:"status" example:"404"`
+	Detail    string       `json:"detail,omitempty" example:"project not found"`
+	Instance  string       `json:"instance,omitempty" example:"/api/v1/projects/42"`
+	RequestID string       `json:"request_id,omitempty" example:"8f14e45fceea167a5a36dedd4bea2543"`
+	Code      string       `json:"code" example:"not_found"`
+	Errors    []fieldError `json:"errors,omitempty"`
+}
+
+// apiError is an error that knows how it is presented to clients. Handlers
+// return it through c.Error and the problemErrors middleware renders it.
+type apiError struct {
+	Status int
+	Code   string
+	Detail string
+	Errors []fieldError
+	// Err is the underlying cause. It is logged but never sent to clients.
+	Err error
+}
+
+func newAPIError(status int, code, detail string) *apiError {
+	return &apiError{Status: status, Code: code, Detail: detail}
+}
+
+func (e *apiError) Error() string {
+	if e.Err != nil {
+		return e.Code + ": " + e.Err.Error()
+	}
+	return e.Code + ": " + e.Detail
+}
+
+func (e *apiError) Unwrap() error {
+	return e.Err
+}
+
+// toAPIError maps the errors returned by the repositories, the validator and
+// the MySQL driver to their apiError. Anything unknown becomes a 500.
+func toAPIError(err error) *apiError {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		return &apiError{
+			Status: http.StatusUnprocessableEntity,
+			Code:   codeValidationFailed,
+			Detail: "the request body is not valid",
+			Errors: validationFieldErrors(validationErrs),
+			Err:    err,
+		}
+	}
+
+	wrap := func(status int, code, detail string) *apiError {
+		return &apiError{Status: status, Code: code, Detail: detail, Err: err}
+	}
+
+	// invalid wraps a rule a body breaks given the stored project
+	invalid := func(field, rule, message string) *apiError {
+		return &apiError{
+			Status: http.StatusUnprocessableEntity,
+			Code:   codeValidationFailed,
+			Detail: "the request body is not valid",
+			Errors: []fieldError{{Field: field, Rule: rule, Message: message}},
+			Err:    err,
+		}
+	}
+
+	switch {
+	case errors.Is(err, errForbidden):
+		return wrap(http.StatusForbidden, codeForbidden, err.Error())
+	case errors.Is(err, errProjectNotFound):
+		return wrap(http.StatusNotFound, codeNotFound, "project not found")
+	case errors.Is(err, errDeadlinePassed):
+		return invalid("budget.deadline", "future_date", "must be in the future")
+	case errors.Is(err, errPaymentNotFound):
+		return wrap(http.StatusNotFound, codeNotFound, "payment not found")
+	case errors.Is(err, errPersonNotFound):
+		return wrap(http.StatusNotFound, codeNotFound, "person not found")
+	case errors.Is(err, errMemberNotFound):
+		return wrap(http.StatusNotFound, codeNotFound, "member not found")
+	case errors.Is(err, errLeaderNotFound):
+		return invalid("leader_id", "person", "must be the id of an existing person")
+	case errors.Is(err, errLeaderRequired):
+		return wrap(http.StatusConflict, codeLeaderRequired, "a project needs a leader, make someone else leader first")
+	case errors.Is(err, errInvalidTransition):
+		return wrap(http.StatusConflict, codeInvalidTransition, err.Error())
+	case errors.Is(err, errProjectCompleted):
+		return wrap(http.StatusConflict, codeProjectCompleted, errProjectCompleted.Error())
+	case errors.Is(err, errPersonInUse):
+		return wrap(http.StatusConflict, codeForeignKeyViolation, "the person still leads or belongs to projects")
+	case errors.Is(err, errDuplicateEmail):
+		return wrap(http.StatusConflict, codeDuplicateKey, "a person with this email already exists")
+	case errors.Is(err, errPaymentCurrency):
+		return invalid("amount.currency", "same_currency", "must be in the currency of budget_value")
+	case errors.Is(err, errPaymentExceedsBalance):
+		return invalid("amount", "lte_remaining", "must not be greater than the remaining balance of the budget")
+	case errors.Is(err, errBudgetCurrencyPaid):
+		return invalid("budget.budget_value.currency", "paid_currency", "cannot change once payments are recorded")
+	case errors.Is(err, errBudgetBelowPaid):
+		return invalid("budget.budget_value", "gte_paid", "must not be less than the amount paid")
+	case errors.Is(err, errPreconditionFailed):
+		return wrap(http.StatusPreconditionFailed, codePreconditionFailed, "project has been modified")
+	case errors.Is(err, errPatchTestFailed):
+		return wrap(http.StatusConflict, codePatchTestFailed, err.Error())
+	case errors.Is(err, errInvalidPatch):
+		return wrap(http.StatusBadRequest, codeInvalidPatch, err.Error())
+	}
+
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case mysqlErrDupEntry:
+			return wrap(http.StatusConflict, codeDuplicateKey, "a resource with the same unique value already exists")
+		case mysqlErrRowIsReferenced, mysqlErrNoReferencedRow:
+			return wrap(http.StatusConflict, codeForeignKeyViolation, "the request references a missing resource or a resource that is still in use")
+		case mysqlErrLockDeadlock:
+			return wrap(http.StatusServiceUnavailable, codeDeadlock, "the request conflicted with a concurrent request, retry it")
+		case mysqlErrLockWaitTimeout:
+			return wrap(http.StatusServiceUnavailable, codeLockTimeout, "the resource is locked by another request, retry it")
+		}
+	}
+
+	return wrap(http.StatusInternalServerError, codeInternal, "")
+}
+
+// problemErrors renders the last error added to the context with c.Error as
+// a problem response, unless the handler already wrote a response.
+func problemErrors() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		writeProblem(c, c.Errors.Last().Err)
+	}
+}
+
+func writeProblem(c *gin.Context, err error) {
+	apiErr := toAPIError(err)
+
+	if apiErr.Status >= http.StatusInternalServerError {
+		log.Ctx(c.Request.Context()).Error().Msg(err.Error())
+	} else {
+		log.Ctx(c.Request.Context()).Warn().Msg(err.Error())
+	}
+
+	if apiErr.Status == http.StatusServiceUnavailable {
+		c.Header("Retry-After", strconv.Itoa(retryAfterSeconds))
+	}
+
+	c.Header("Content-Type", problemContentType)
+	c.IndentedJSON(apiErr.Status, HTTPError{
+		Type:      problemTypePrefix + apiErr.Code,
+		Title:     http.StatusText(apiErr.Status),
+		Status:    apiErr.Status,
+		Detail:    apiErr.Detail,
+		Instance:  c.Request.URL.Path,
+		RequestID: requestID(c),
+		Code:      apiErr.Code,
+		Errors:    apiErr.Errors,
+	})
+}
+
+// requestID returns the id of the request being served, if it has one.
+func requestID(c *gin.Context) string {
+	if id := c.Writer.Header().Get("X-Request-ID"); id != "" {
+		return id
+	}
+	return c.GetHeader("X-Request-ID")
+}
+
+// noRoute answers requests for unknown routes with a problem response.
+func noRoute(c *gin.Context) {
+	c.Error(newAPIError(http.StatusNotFound, codeRouteNotFound, "no route matches "+c.Request.URL.Path))
+}
+
+// noMethod answers requests with an unsupported method with a problem
+// response.
+func noMethod(c *gin.Context) {
+	c.Error(newAPIError(http.StatusMethodNotAllowed, codeMethodNotAllowed, c.Request.Method+" is not allowed on "+c.Request.URL.Path))
+}
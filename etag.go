@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+// projectETag returns the entity tag of a project. It changes whenever the
+// project or its budget is updated, and when its leader is renamed, which
+// changes the project as returned without bumping its version.
+func projectETag(proj projectModel) string {
+	leader := fnv.New32a()
+	leader.Write([]byte(proj.Leader))
+	return fmt.Sprintf(`"%d.%d.%08x"`, proj.Version, proj.Budget.Version, leader.Sum32())
+}
+
+// etagListMatches reports whether etag is in the comma separated list of an
+// If-Match or If-None-Match header. Weak tags are compared by their opaque
+// value when weak is true and never match otherwise.
+func etagListMatches(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.HasPrefix(candidate, "W/") {
+			if !weak {
+				continue
+			}
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatchPrecondition turns an If-Match header into a precondition for the
+// repository. An empty header allows the write unconditionally.
+func ifMatchPrecondition(header string) projectPrecondition {
+	if header == "" {
+		return nil
+	}
+	return func(current projectModel) bool {
+		return etagListMatches(header, projectETag(current), false)
+	}
+}
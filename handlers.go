@@ -0,0 +1,396 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// projectHandler serves the project endpoints on top of a ProjectRepository.
+type projectHandler struct {
+	repo ProjectRepository
+}
+
+func newProjectHandler(repo ProjectRepository) *projectHandler {
+	return &projectHandler{repo: repo}
+}
+
+// getProjects godoc
+// @Summary      Get projects
+// @Description  Get a page of projects. Pages can be walked with limit/offset or with the cursors returned in links.
+// @Tags         Get Projects
+// @Accept       json
+// @Produce      json
+// @Param        limit            query     int     false  "Maximum number of projects to return"  default(100)  maximum(1000)
+// @Param        offset           query     int     false  "Number of projects to skip"
+// @Param        cursor           query     string  false  "Cursor taken from links.next or links.prev"
+// @Param        sort             query     string  false  "Sort field, budget_value needs currency"  Enums(id, title, leader, budget_value, deadline)
+// @Param        order            query     string  false  "Sort direction"  Enums(asc, desc)
+// @Param        leader           query     string  false  "Only projects led by a person with this name"
+// @Param        leader_id        query     int     false  "Only projects led by the person with this id"
+// @Param        title~           query     string  false  "Only projects whose title contains this text"
+// @Param        status           query     string  false  "Only projects in one of these comma separated statuses: planned, active, on_hold, completed, cancelled"
+// @Param        currency         query     string  false  "Only projects budgeted in this ISO 4217 currency"
+// @Param        min_budget       query     string  false  "Minimum budget value as a decimal amount, needs currency"
+// @Param        max_budget       query     string  false  "Maximum budget value as a decimal amount, needs currency"
+// @Param        deadline_before  query     string  false  "Only projects with a deadline before this RFC 3339 time or date"
+// @Param        deadline_after   query     string  false  "Only projects with a deadline on or after this RFC 3339 time or date"
+// @Param        overdue          query     bool    false  "Only projects whose deadline has passed"
+// @Param        due_within       query     int     false  "Only projects whose deadline is within this number of days from now"
+// @Success      200  {object}  projectPage
+// @Failure      400  {object}  HTTPError
+// @Failure      401  {object}  HTTPError
+// @Failure      403  {object}  HTTPError
+// @Failure      429  {object}  HTTPError
+// @Failure      500  {object}  HTTPError
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /projects [get]
+func (h *projectHandler) getProjects(c *gin.Context) {
+	query, err := parseProjectListQuery(c.Request.URL.Query(), time.Now())
+	if err != nil {
+		c.Error(newAPIError(http.StatusBadRequest, codeInvalidQuery, err.Error()))
+		return
+	}
+
+	h.writeProjectPage(c, query)
+}
+
+// writeProjectPage responds with the page of projects described by query,
+// along with the links to the pages next to it.
+func (h *projectHandler) writeProjectPage(c *gin.Context, query projectListQuery) {
+	// Ask for one more project than requested to find out whether there is
+	// another page in the direction we are walking
+	fetch := query
+	fetch.Limit++
+
+	list, err := h.repo.List(c.Request.Context(), fetch)
+	if err != nil {
+		c.Error(fmt.Errorf("listing projects: %w", err))
+		return
+	}
+
+	page := projectPage{
+		Data:  list.Projects,
+		Total: list.Total,
+		Limit: query.Limit,
+	}
+	hasMore := len(page.Data) > query.Limit
+	if hasMore {
+		if query.Cursor != nil && query.Cursor.Before {
+			page.Data = page.Data[1:]
+		} else {
+			page.Data = page.Data[:query.Limit]
+		}
+	}
+	if page.Data == nil {
+		page.Data = []projectModel{}
+	}
+
+	u := c.Request.URL
+	limit := strconv.Itoa(query.Limit)
+	if c.Query("offset") != "" {
+		// Offset pagination
+		page.Offset = query.Offset
+		if hasMore {
+			page.Links.Next = pageURL(u, map[string]string{"offset": strconv.Itoa(query.Offset + query.Limit), "limit": limit})
+		}
+		if query.Offset > 0 {
+			page.Links.Prev = pageURL(u, map[string]string{"offset": strconv.Itoa(max(query.Offset-query.Limit, 0)), "limit": limit})
+		}
+	} else if len(page.Data) > 0 {
+		// Cursor pagination
+		backwards := query.Cursor != nil && query.Cursor.Before
+		first, last := page.Data[0], page.Data[len(page.Data)-1]
+		if hasMore && !backwards || query.Cursor != nil && backwards {
+			page.Links.Next = pageURL(u, map[string]string{"cursor": encodeProjectCursor(cursorFor(query.Sort, last, false)), "limit": limit})
+		}
+		if query.Cursor != nil && !backwards || hasMore && backwards {
+			page.Links.Prev = pageURL(u, map[string]string{"cursor": encodeProjectCursor(cursorFor(query.Sort, first, true)), "limit": limit})
+		}
+	}
+
+	c.IndentedJSON(http.StatusOK, page)
+}
+
+// getProjectById godoc
+// @Summary      Get project by id
+// @Description  Get project by id
+// @Tags         Get Project by id
+// @Accept       json
+// @Produce      json
+// @Param        id             path      int     true   "Project ID"
+// @Param        If-None-Match  header    string  false  "Return 304 if the project still has one of these ETags"
+// @Success      200  {object}  projectModel
+// @Header       200  {string}  ETag  "Version of the project"
+// @Success      304  "Project has not changed"
+// @Failure      401  {object}  HTTPError
+// @Failure      403  {object}  HTTPError
+// @Failure      404  {object} 	HTTPError
+// @Failure      429  {object}  HTTPError
+// @Failure      500  {object}  HTTPError
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /projects/{id} [get]
+func (h *projectHandler) getProjectById(c *gin.Context) {
+	id := c.Param("id")
+
+	proj, err := h.repo.Get(c.Request.Context(), id)
+	if err != nil {
+		c.Error(fmt.Errorf("getting project %s: %w", id, err))
+		return
+	}
+
+	etag := projectETag(proj)
+	c.Header("ETag", etag)
+	if inm := c.GetHeader("If-None-Match"); inm != "" && etagListMatches(inm, etag, true) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, proj)
+}
+
+// postProjects godoc
+// @Summary      Post project
+// @Description  Post project
+// @Tags         Post project
+// @Accept       json
+// @Produce      json
+// @Param        project  body      projectModel  true  "Add project"
+// @Success      201  {object}  projectModel
+// @Failure      400  {object}  HTTPError
+// @Failure      401  {object}  HTTPError
+// @Failure      403  {object}  HTTPError
+// @Failure      422  {object}  HTTPError
+// @Failure      429  {object}  HTTPError
+// @Failure      500  {object}  HTTPError
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /projects [post]
+func (h *projectHandler) postProjects(c *gin.Context) {
+	var newProject projectModel
+
+	//binding request to struct model
+	if err := bindProject(c, &newProject); err != nil {
+		c.Error(err)
+		return
+	}
+
+	created, err := h.repo.Create(c.Request.Context(), newProject)
+	if err != nil {
+		c.Error(fmt.Errorf("creating project: %w", err))
+		return
+	}
+
+	c.Header("ETag", projectETag(created))
+	c.IndentedJSON(http.StatusCreated, created)
+}
+
+// updateProjectById godoc
+// @Summary      Update project by id
+// @Description  Upadte project by id. Changing leader_id needs the delete permission.
+// @Tags         Update Project by id
+// @Accept       json
+// @Produce      json
+// @Param        id        path      int           true   "Project ID"
+// @Param        If-Match  header    string        false  "Only update if the project still has one of these ETags"
+// @Param        project   body      projectModel  true   "Add project"
+// @Success      200  {object}  projectModel
+// @Header       200  {string}  ETag  "Version of the updated project"
+// @Failure      400  {object}  HTTPError
+// @Failure      401  {object}  HTTPError
+// @Failure      403  {object}  HTTPError
+// @Failure      404  {object} 	HTTPError
+// @Failure      412  {object}  HTTPError
+// @Failure      422  {object}  HTTPError
+// @Failure      429  {object}  HTTPError
+// @Failure      500  {object}  HTTPError
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /projects/{id} [put]
+func (h *projectHandler) updateProject(c *gin.Context) {
+	id := c.Param("id")
+
+	var newProject projectModel
+
+	//binding request to struct model
+	if err := bindProject(c, &newProject); err != nil {
+		c.Error(err)
+		return
+	}
+
+	precondition := ifMatchPrecondition(c.GetHeader("If-Match"))
+	if denied := requirePermission(c, permissionDelete); denied != nil {
+		current, err := h.repo.Get(c.Request.Context(), id)
+		if err == nil {
+			err = checkLeaderChange(current, newProject, denied)
+		}
+		if err != nil {
+			c.Error(fmt.Errorf("updating project %s: %w", id, err))
+			return
+		}
+		precondition = ledBy(precondition, current.LeaderID)
+	}
+
+	updated, err := h.repo.Update(c.Request.Context(), id, newProject, precondition)
+	if err != nil {
+		c.Error(fmt.Errorf("updating project %s: %w", id, err))
+		return
+	}
+
+	c.Header("ETag", projectETag(updated))
+	c.IndentedJSON(http.StatusOK, updated)
+}
+
+// patchProject godoc
+// @Summary      Partially update project by id
+// @Description  Update only the given fields of a project. The body is either a JSON Merge Patch (RFC 7386) or a JSON Patch (RFC 6902), selected by the Content-Type header. Changing leader_id needs the delete permission.
+// @Tags         Update Project by id
+// @Accept       application/merge-patch+json
+// @Accept       application/json-patch+json
+// @Produce      json
+// @Param        id        path      int     true   "Project ID"
+// @Param        If-Match  header    string  false  "Only update if the project still has one of these ETags"
+// @Param        patch     body      object  true   "Merge patch document or list of patch operations"
+// @Success      200  {object}  projectModel
+// @Header       200  {string}  ETag  "Version of the updated project"
+// @Failure      400  {object}  HTTPError
+// @Failure      401  {object}  HTTPError
+// @Failure      403  {object}  HTTPError
+// @Failure      404  {object} 	HTTPError
+// @Failure      409  {object}  HTTPError
+// @Failure      412  {object}  HTTPError
+// @Failure      415  {object}  HTTPError
+// @Failure      422  {object}  HTTPError
+// @Failure      429  {object}  HTTPError
+// @Failure      500  {object}  HTTPError
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /projects/{id} [patch]
+func (h *projectHandler) patchProject(c *gin.Context) {
+	id := c.Param("id")
+
+	var applyPatch func(doc, patch []byte) ([]byte, error)
+	switch c.ContentType() {
+	case "application/merge-patch+json", "application/json":
+		applyPatch = applyMergePatch
+	case "application/json-patch+json":
+		applyPatch = applyJSONPatch
+	default:
+		c.Error(newAPIError(http.StatusUnsupportedMediaType, codeUnsupportedMediaType, "use application/merge-patch+json or application/json-patch+json"))
+		return
+	}
+
+	patch, err := c.GetRawData()
+	if err != nil {
+		c.Error(&apiError{Status: http.StatusBadRequest, Code: codeBadRequest, Detail: "the request body could not be read", Err: err})
+		return
+	}
+
+	denied := requirePermission(c, permissionDelete)
+	patched, err := h.repo.Patch(c.Request.Context(), id, ifMatchPrecondition(c.GetHeader("If-Match")), func(current projectModel) (projectModel, error) {
+		patched, err := patchProjectModel(current, patch, applyPatch)
+		if err != nil {
+			return projectModel{}, err
+		}
+		return patched, checkLeaderChange(current, patched, denied)
+	})
+	if err != nil {
+		c.Error(fmt.Errorf("patching project %s: %w", id, err))
+		return
+	}
+
+	c.Header("ETag", projectETag(patched))
+	c.IndentedJSON(http.StatusOK, patched)
+}
+
+// patchProjectModel applies patch to current and validates the result. The
+// deadline only has to be in the future when the patch changes it.
+func patchProjectModel(current projectModel, patch []byte, applyPatch func(doc, patch []byte) ([]byte, error)) (projectModel, error) {
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return projectModel{}, err
+	}
+
+	if doc, err = applyPatch(doc, patch); err != nil {
+		return projectModel{}, err
+	}
+
+	var patched projectModel
+	decoder := json.NewDecoder(bytes.NewReader(doc))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&patched); err != nil {
+		return projectModel{}, fmt.Errorf("%w: %v", errInvalidPatch, err)
+	}
+	if patched.ID != current.ID {
+		return projectModel{}, fmt.Errorf("%w: id cannot be changed", errInvalidPatch)
+	}
+
+	if err := binding.Validator.ValidateStruct(&patched); err != nil {
+		return projectModel{}, err
+	}
+	if err := checkDeadline(current, patched, time.Now()); err != nil {
+		return projectModel{}, err
+	}
+
+	return patched, nil
+}
+
+// deleteProjectById godoc
+// @Summary      Delete project by id
+// @Description  Delete project by id
+// @Tags         Delete Project by id
+// @Accept       json
+// @Produce      json
+// @Param        id        path      int     true   "Project ID"
+// @Param        If-Match  header    string  false  "Only delete if the project still has one of these ETags"
+// @Success      200  {object}  HTTPSuccess
+// @Failure      401  {object}  HTTPError
+// @Failure      403  {object}  HTTPError
+// @Failure      404  {object} 	HTTPError
+// @Failure      412  {object}  HTTPError
+// @Failure      429  {object}  HTTPError
+// @Failure      500  {object}  HTTPError
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /projects/{id} [delete]
+func (h *projectHandler) deleteProject(c *gin.Context) {
+	id := c.Param("id")
+
+	if err := h.repo.Delete(c.Request.Context(), id, ifMatchPrecondition(c.GetHeader("If-Match"))); err != nil {
+		c.Error(fmt.Errorf("deleting project %s: %w", id, err))
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{"message": "Successfully deleted!"})
+}
+
+// bindProject binds the request body to project and validates it.
+func bindProject(c *gin.Context, project *projectModel) error {
+	return bindBody(c, project, "a project")
+}
+
+// bindBody binds the request body to obj and validates it. Validation
+// failures are returned as is, anything else that stops the body from being
+// decoded becomes a bad request mentioning what the body should hold.
+func bindBody(c *gin.Context, obj any, what string) error {
+	err := c.ShouldBindJSON(obj)
+	if err == nil {
+		return nil
+	}
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		return err
+	}
+	return &apiError{Status: http.StatusBadRequest, Code: codeBadRequest, Detail: "the request body is not valid JSON for " + what + ": " + err.Error(), Err: err}
+}
@@ -0,0 +1,705 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-sql-driver/mysql"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	log.Logger = zerolog.Nop()
+	if err := registerValidators(); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// newTestServer returns the API routes backed by a memory repository.
+func newTestServer(t *testing.T) (*gin.Engine, *memoryProjectRepository) {
+	t.Helper()
+
+	repo := newMemoryProjectRepository()
+	handler := newProjectHandler(repo)
+	router := gin.New()
+	router.Use(problemErrors())
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(noRoute)
+	router.NoMethod(noMethod)
+	registerRoutes(router, handler)
+	return router, repo
+}
+
+// request sends a request to router. header holds pairs of header names and
+// values, the body is sent as JSON unless it sets Content-Type.
+func request(router http.Handler, method, path, body string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// decode decodes the JSON body of w into v.
+func decode(t *testing.T, w *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("decoding %s: %v", w.Body, err)
+	}
+}
+
+// checkStatus fails t unless w has the status wanted.
+func checkStatus(t *testing.T, w *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("status = %d, want %d: %s", w.Code, status, w.Body)
+	}
+}
+
+// projectBody returns the body creating or replacing a project.
+func projectBody(title, leaderID, budgetValue, currency, deadline string) string {
+	return fmt.Sprintf(`{"title": %q, "leader_id": %q, "budget": {"budget_value": {"amount": %q, "currency": %q}, "down_payment": {"amount": "0", "currency": %q}, "deadline": %q}}`,
+		title, leaderID, budgetValue, currency, currency, deadline)
+}
+
+// createPerson creates a person named name and returns their id.
+func createPerson(t *testing.T, router http.Handler, name string) string {
+	t.Helper()
+	w := request(router, http.MethodPost, "/api/v1/people", fmt.Sprintf(`{"name": %q}`, name))
+	checkStatus(t, w, http.StatusCreated)
+
+	var person personModel
+	decode(t, w, &person)
+	return person.ID
+}
+
+// createProject creates a project and returns it.
+func createProject(t *testing.T, router http.Handler, body string) projectModel {
+	t.Helper()
+	w := request(router, http.MethodPost, "/api/v1/projects", body)
+	checkStatus(t, w, http.StatusCreated)
+
+	var proj projectModel
+	decode(t, w, &proj)
+	return proj
+}
+
+// checkProblem fails t unless w is a problem response with the status and
+// code wanted.
+func checkProblem(t *testing.T, w *httptest.ResponseRecorder, status int, code string) HTTPError {
+	t.Helper()
+	checkStatus(t, w, status)
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, problemContentType) {
+		t.Errorf("Content-Type = %q, want %s", ct, problemContentType)
+	}
+
+	var problem HTTPError
+	decode(t, w, &problem)
+	if problem.Code != code || problem.Status != status {
+		t.Errorf("problem = %+v, want code %q and status %d", problem, code, status)
+	}
+	return problem
+}
+
+// checkFieldError fails t unless w is a validation problem whose first error
+// is about field and rule.
+func checkFieldError(t *testing.T, w *httptest.ResponseRecorder, field, rule string) {
+	t.Helper()
+	problem := checkProblem(t, w, http.StatusUnprocessableEntity, codeValidationFailed)
+	if len(problem.Errors) == 0 || problem.Errors[0].Field != field || problem.Errors[0].Rule != rule {
+		t.Errorf("errors = %+v, want %s failing %s", problem.Errors, field, rule)
+	}
+}
+
+// futureDeadline and pastDeadline are deadlines a new project may and may
+// not have.
+var (
+	futureDeadline = time.Now().AddDate(1, 0, 0).Format(time.DateOnly)
+	pastDeadline   = time.Now().AddDate(0, -1, 0).Format(time.DateOnly)
+)
+
+// makeOverdue moves the deadline of the project with the given id to
+// pastDeadline, as if it had passed since the project was created.
+func makeOverdue(t *testing.T, repo *memoryProjectRepository, id string) {
+	t.Helper()
+	deadline, err := parseDateTime(pastDeadline)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	proj, ok := repo.projects[id]
+	if !ok {
+		t.Fatalf("no project %s", id)
+	}
+	proj.Budget.Deadline = deadline
+	repo.projects[id] = proj
+}
+
+// pageTitles returns the titles of the projects of the page in w.
+func pageTitles(t *testing.T, w *httptest.ResponseRecorder) []string {
+	t.Helper()
+	var page projectPage
+	decode(t, w, &page)
+
+	titles := make([]string, 0, len(page.Data))
+	for _, proj := range page.Data {
+		titles = append(titles, proj.Title)
+	}
+	return titles
+}
+
+func TestProjectHandlers(t *testing.T) {
+	router, _ := newTestServer(t)
+	jane := createPerson(t, router, "Jane Doe")
+	john := createPerson(t, router, "John Roe")
+	var (
+		bridge = `{"title": "Bridge", "leader_id": "` + jane + `", "budget": {"budget_value": {"amount": "3000.50", "currency": "USD"}, "down_payment": {"amount": 500, "currency": "USD"}, "deadline": "2030-01-01"}}`
+		tunnel = `{"title": "Tunnel", "leader_id": "` + john + `", "budget": {"budget_value": {"amount": "4000", "currency": "JPY"}, "down_payment": {"amount": "0", "currency": "JPY"}, "deadline": "2031-01-01"}}`
+	)
+
+	// The steps share one repository and build on each other
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		want   *projectModel
+		count  int
+	}{
+		{name: "empty list", method: http.MethodGet, path: "/api/v1/projects", status: http.StatusOK, count: 0},
+		{
+			name: "create", method: http.MethodPost, path: "/api/v1/projects", body: bridge, status: http.StatusCreated,
+			want: &projectModel{ID: "1", Title: "Bridge", LeaderID: jane, Leader: "Jane Doe", Budget: budgetModel{BudgetValue: money{Amount: 300050, Currency: "USD"}, DownPayment: money{Amount: 50000, Currency: "USD"}, Deadline: newDateTime(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)), paymentSummary: summarizePayments(money{Amount: 300050, Currency: "USD"}, 0)}, Status: statusPlanned},
+		},
+		{name: "invalid JSON", method: http.MethodPost, path: "/api/v1/projects", body: `{"title": `, status: http.StatusBadRequest},
+		{
+			name: "get", method: http.MethodGet, path: "/api/v1/projects/1", status: http.StatusOK,
+			want: &projectModel{ID: "1", Title: "Bridge", LeaderID: jane, Leader: "Jane Doe", Budget: budgetModel{BudgetValue: money{Amount: 300050, Currency: "USD"}, DownPayment: money{Amount: 50000, Currency: "USD"}, Deadline: newDateTime(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)), paymentSummary: summarizePayments(money{Amount: 300050, Currency: "USD"}, 0)}, Status: statusPlanned},
+		},
+		{name: "get missing", method: http.MethodGet, path: "/api/v1/projects/2", status: http.StatusNotFound},
+		{
+			name: "update", method: http.MethodPut, path: "/api/v1/projects/1", body: tunnel, status: http.StatusOK,
+			want: &projectModel{ID: "1", Title: "Tunnel", LeaderID: john, Leader: "John Roe", Budget: budgetModel{BudgetValue: money{Amount: 4000, Currency: "JPY"}, DownPayment: money{Currency: "JPY"}, Deadline: newDateTime(time.Date(2031, 1, 1, 0, 0, 0, 0, time.UTC)), paymentSummary: summarizePayments(money{Amount: 4000, Currency: "JPY"}, 0)}, Status: statusPlanned},
+		},
+		{name: "update missing", method: http.MethodPut, path: "/api/v1/projects/2", body: tunnel, status: http.StatusNotFound},
+		{name: "create another", method: http.MethodPost, path: "/api/v1/projects", body: bridge, status: http.StatusCreated},
+		{name: "list", method: http.MethodGet, path: "/api/v1/projects", status: http.StatusOK, count: 2},
+		{name: "delete", method: http.MethodDelete, path: "/api/v1/projects/1", status: http.StatusOK},
+		{name: "get deleted", method: http.MethodGet, path: "/api/v1/projects/1", status: http.StatusNotFound},
+		{name: "delete again", method: http.MethodDelete, path: "/api/v1/projects/1", status: http.StatusNotFound},
+		{name: "list after delete", method: http.MethodGet, path: "/api/v1/projects", status: http.StatusOK, count: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := request(router, tt.method, tt.path, tt.body)
+			checkStatus(t, w, tt.status)
+
+			switch {
+			case tt.want != nil:
+				var got projectModel
+				decode(t, w, &got)
+				want := *tt.want
+				if got.StatusChangedAt.IsZero() {
+					t.Error("status_changed_at is not set")
+				}
+				want.StatusChangedAt = got.StatusChangedAt
+				if got != want {
+					t.Errorf("got %+v, want %+v", got, want)
+				}
+			case tt.method == http.MethodGet && tt.status == http.StatusOK:
+				var got projectPage
+				decode(t, w, &got)
+				if len(got.Data) != tt.count || got.Total != tt.count {
+					t.Errorf("%d projects of %d, want %d", len(got.Data), got.Total, tt.count)
+				}
+			}
+		})
+	}
+}
+
+func TestGetProjects(t *testing.T) {
+	router, _ := newTestServer(t)
+	jane := createPerson(t, router, "Jane Doe")
+	john := createPerson(t, router, "John Roe")
+	createProject(t, router, projectBody("Bridge", jane, "3000", "USD", "2030-03-01"))
+	createProject(t, router, projectBody("Airport", john, "1000", "EUR", "2030-01-01"))
+	createProject(t, router, projectBody("Canal", jane, "2000", "USD", "2030-02-01"))
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		titles []string
+	}{
+		{name: "default", query: "", status: http.StatusOK, titles: []string{"Bridge", "Airport", "Canal"}},
+		{name: "sort by title", query: "?sort=title", status: http.StatusOK, titles: []string{"Airport", "Bridge", "Canal"}},
+		{name: "descending", query: "?sort=title&order=desc", status: http.StatusOK, titles: []string{"Canal", "Bridge", "Airport"}},
+		{name: "sort by budget value", query: "?sort=budget_value&currency=USD", status: http.StatusOK, titles: []string{"Canal", "Bridge"}},
+		{name: "sort by deadline", query: "?sort=deadline&order=desc", status: http.StatusOK, titles: []string{"Bridge", "Canal", "Airport"}},
+		{name: "leader", query: "?leader=Jane+Doe", status: http.StatusOK, titles: []string{"Bridge", "Canal"}},
+		{name: "leader id", query: "?leader_id=" + john, status: http.StatusOK, titles: []string{"Airport"}},
+		{name: "sort by leader", query: "?sort=leader&order=desc", status: http.StatusOK, titles: []string{"Airport", "Canal", "Bridge"}},
+		{name: "title contains", query: "?title~=an", status: http.StatusOK, titles: []string{"Canal"}},
+		{name: "currency", query: "?currency=EUR", status: http.StatusOK, titles: []string{"Airport"}},
+		{name: "budget range", query: "?currency=USD&min_budget=1500&max_budget=2500.50", status: http.StatusOK, titles: []string{"Canal"}},
+		{name: "deadline before", query: "?deadline_before=2030-02-15", status: http.StatusOK, titles: []string{"Airport", "Canal"}},
+		{name: "deadline after", query: "?deadline_after=2030-02-01", status: http.StatusOK, titles: []string{"Bridge", "Canal"}},
+		{name: "deadline range with offset", query: "?deadline_after=2030-01-01T01:00:00%2B02:00&deadline_before=2030-02-01T00:00:01Z", status: http.StatusOK, titles: []string{"Airport", "Canal"}},
+		{name: "overdue", query: "?overdue=true", status: http.StatusOK, titles: []string{}},
+		{name: "due within", query: "?due_within=36500&sort=deadline", status: http.StatusOK, titles: []string{"Airport", "Canal", "Bridge"}},
+		{name: "offset", query: "?offset=1&limit=1", status: http.StatusOK, titles: []string{"Airport"}},
+		{name: "offset past the end", query: "?offset=5", status: http.StatusOK, titles: []string{}},
+		{name: "unknown sort", query: "?sort=colour", status: http.StatusBadRequest},
+		{name: "unknown order", query: "?order=up", status: http.StatusBadRequest},
+		{name: "limit too large", query: "?limit=5000", status: http.StatusBadRequest},
+		{name: "negative offset", query: "?offset=-1", status: http.StatusBadRequest},
+		{name: "invalid budget", query: "?currency=USD&min_budget=lots", status: http.StatusBadRequest},
+		{name: "budget too precise", query: "?currency=JPY&min_budget=1.5", status: http.StatusBadRequest},
+		{name: "budget without currency", query: "?min_budget=1500", status: http.StatusBadRequest},
+		{name: "sort by budget value without currency", query: "?sort=budget_value", status: http.StatusBadRequest},
+		{name: "unknown currency", query: "?currency=ABC", status: http.StatusBadRequest},
+		{name: "invalid deadline", query: "?deadline_before=soon", status: http.StatusBadRequest},
+		{name: "invalid overdue", query: "?overdue=maybe", status: http.StatusBadRequest},
+		{name: "negative due within", query: "?due_within=-1", status: http.StatusBadRequest},
+		{name: "overdue and due within", query: "?overdue=true&due_within=7", status: http.StatusBadRequest},
+		{name: "invalid cursor", query: "?cursor=nope", status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := request(router, http.MethodGet, "/api/v1/projects"+tt.query, "")
+			checkStatus(t, w, tt.status)
+			if tt.status != http.StatusOK {
+				return
+			}
+			if titles := pageTitles(t, w); !slices.Equal(titles, tt.titles) {
+				t.Errorf("titles = %v, want %v", titles, tt.titles)
+			}
+		})
+	}
+}
+
+func TestGetProjectsPagination(t *testing.T) {
+	router, _ := newTestServer(t)
+	jane := createPerson(t, router, "Jane Doe")
+	for _, title := range []string{"A", "B", "C", "D", "E"} {
+		createProject(t, router, projectBody(title, jane, "1000", "USD", "2030-01-01"))
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		// next holds the titles of every page walked by following
+		// links.next from the first page, prev those walked by following
+		// links.prev back from the last one
+		next [][]string
+		prev [][]string
+	}{
+		{
+			name:  "cursor",
+			query: "?limit=2",
+			next:  [][]string{{"A", "B"}, {"C", "D"}, {"E"}},
+			prev:  [][]string{{"C", "D"}, {"A", "B"}},
+		},
+		{
+			name:  "cursor sorted descending",
+			query: "?limit=2&sort=title&order=desc",
+			next:  [][]string{{"E", "D"}, {"C", "B"}, {"A"}},
+			prev:  [][]string{{"C", "B"}, {"E", "D"}},
+		},
+		{
+			name:  "offset",
+			query: "?limit=2&offset=0",
+			next:  [][]string{{"A", "B"}, {"C", "D"}, {"E"}},
+			prev:  [][]string{{"C", "D"}, {"A", "B"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var page projectPage
+			walk := func(path string) []string {
+				t.Helper()
+				w := request(router, http.MethodGet, path, "")
+				checkStatus(t, w, http.StatusOK)
+				page = projectPage{}
+				decode(t, w, &page)
+				return pageTitles(t, w)
+			}
+
+			path := "/api/v1/projects" + tt.query
+			for i, want := range tt.next {
+				if titles := walk(path); !slices.Equal(titles, want) {
+					t.Fatalf("page %d = %v, want %v", i+1, titles, want)
+				}
+				if page.Total != 5 {
+					t.Errorf("total = %d, want 5", page.Total)
+				}
+				path = page.Links.Next
+			}
+			if page.Links.Next != "" {
+				t.Errorf("last page links to next %q", page.Links.Next)
+			}
+
+			for i, want := range tt.prev {
+				if page.Links.Prev == "" {
+					t.Fatalf("no link to page %d", len(tt.next)-i-1)
+				}
+				if titles := walk(page.Links.Prev); !slices.Equal(titles, want) {
+					t.Fatalf("page %d = %v, want %v", len(tt.next)-i-1, titles, want)
+				}
+			}
+			if page.Links.Prev != "" {
+				t.Errorf("first page links to prev %q", page.Links.Prev)
+			}
+		})
+	}
+}
+
+func TestPostProjectValidation(t *testing.T) {
+	router, _ := newTestServer(t)
+	jane := createPerson(t, router, "Jane Doe")
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		field  string
+		rule   string
+	}{
+		{name: "valid", body: projectBody("Bridge", jane, "3000", "USD", futureDeadline), status: http.StatusCreated},
+		{name: "RFC 3339 deadline", body: projectBody("Bridge", jane, "3000", "USD", "2030-01-01T12:00:00Z"), status: http.StatusCreated},
+		{name: "missing title", body: projectBody("", jane, "3000", "USD", futureDeadline), status: http.StatusUnprocessableEntity, field: "title", rule: "required"},
+		{name: "long title", body: projectBody(strings.Repeat("a", 256), jane, "3000", "USD", futureDeadline), status: http.StatusUnprocessableEntity, field: "title", rule: "max"},
+		{name: "missing leader", body: projectBody("Bridge", "", "3000", "USD", futureDeadline), status: http.StatusUnprocessableEntity, field: "leader_id", rule: "required"},
+		{name: "unknown leader", body: projectBody("Bridge", "999", "3000", "USD", futureDeadline), status: http.StatusUnprocessableEntity, field: "leader_id", rule: "person"},
+		{name: "negative budget", body: projectBody("Bridge", jane, "-1", "USD", futureDeadline), status: http.StatusUnprocessableEntity, field: "budget.budget_value", rule: "gte"},
+		{
+			name:   "down payment above budget",
+			body:   `{"title": "Bridge", "leader_id": "` + jane + `", "budget": {"budget_value": {"amount": "100", "currency": "USD"}, "down_payment": {"amount": "200", "currency": "USD"}, "deadline": "` + futureDeadline + `"}}`,
+			status: http.StatusUnprocessableEntity, field: "budget.down_payment", rule: "ltefield",
+		},
+		{name: "unknown currency", body: projectBody("Bridge", jane, "3000", "ABC", futureDeadline), status: http.StatusUnprocessableEntity, field: "budget.budget_value.currency", rule: "currency"},
+		{
+			name:   "mixed currencies",
+			body:   `{"title": "Bridge", "leader_id": "` + jane + `", "budget": {"budget_value": {"amount": "100", "currency": "USD"}, "down_payment": {"amount": "50", "currency": "EUR"}, "deadline": "` + futureDeadline + `"}}`,
+			status: http.StatusUnprocessableEntity, field: "budget.down_payment.currency", rule: "same_currency",
+		},
+		{name: "amount too precise", body: projectBody("Bridge", jane, "3000.5", "JPY", futureDeadline), status: http.StatusBadRequest},
+		{name: "missing deadline", body: `{"title": "Bridge", "leader_id": "` + jane + `", "budget": {"budget_value": {"amount": "3000", "currency": "USD"}, "down_payment": {"amount": "0", "currency": "USD"}}}`, status: http.StatusUnprocessableEntity, field: "budget.deadline", rule: "required"},
+		{name: "malformed deadline", body: projectBody("Bridge", jane, "3000", "USD", "next year"), status: http.StatusBadRequest},
+		{name: "past deadline", body: projectBody("Bridge", jane, "3000", "USD", pastDeadline), status: http.StatusUnprocessableEntity, field: "budget.deadline", rule: "future_date"},
+		{name: "malformed JSON", body: `{"title": "Bridge"`, status: http.StatusBadRequest},
+		{name: "wrong type", body: `{"title": 7}`, status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := request(router, http.MethodPost, "/api/v1/projects", tt.body)
+			if tt.status == http.StatusUnprocessableEntity {
+				checkFieldError(t, w, tt.field, tt.rule)
+				return
+			}
+			checkStatus(t, w, tt.status)
+		})
+	}
+}
+
+func TestUpdateProjectDeadline(t *testing.T) {
+	tests := []struct {
+		name     string
+		overdue  bool
+		deadline string
+		status   int
+	}{
+		{name: "future deadline", deadline: futureDeadline, status: http.StatusOK},
+		{name: "deadline moved to the past", deadline: pastDeadline, status: http.StatusUnprocessableEntity},
+		{name: "overdue project keeping its deadline", overdue: true, deadline: pastDeadline, status: http.StatusOK},
+		{name: "overdue project given a new deadline", overdue: true, deadline: futureDeadline, status: http.StatusOK},
+		{name: "overdue project moved to another past deadline", overdue: true, deadline: time.Now().AddDate(0, -2, 0).Format(time.DateOnly), status: http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, repo := newTestServer(t)
+			jane := createPerson(t, router, "Jane Doe")
+			proj := createProject(t, router, projectBody("Bridge", jane, "3000", "USD", futureDeadline))
+			if tt.overdue {
+				makeOverdue(t, repo, proj.ID)
+			}
+
+			w := request(router, http.MethodPut, "/api/v1/projects/"+proj.ID, projectBody("Bridge 2", jane, "3000", "USD", tt.deadline))
+			if tt.status == http.StatusUnprocessableEntity {
+				checkFieldError(t, w, "budget.deadline", "future_date")
+				return
+			}
+			checkStatus(t, w, tt.status)
+		})
+	}
+}
+
+func TestPatchProject(t *testing.T) {
+	const (
+		mergePatch = "application/merge-patch+json"
+		jsonPatch  = "application/json-patch+json"
+	)
+
+	tests := []struct {
+		name        string
+		overdue     bool
+		contentType string
+		patch       string
+		status      int
+		field       string
+		rule        string
+		check       func(t *testing.T, proj projectModel)
+	}{
+		{
+			name: "merge patch", contentType: mergePatch, patch: `{"leader_id": "2", "budget": {"down_payment": {"amount": "500", "currency": "USD"}}}`, status: http.StatusOK,
+			check: func(t *testing.T, proj projectModel) {
+				if proj.Title != "Bridge" || proj.LeaderID != "2" || proj.Leader != "John Doe" || proj.Budget.DownPayment.Amount != 50000 || proj.Budget.BudgetValue.Amount != 300000 {
+					t.Errorf("patched project = %+v", proj)
+				}
+			},
+		},
+		{
+			name: "JSON patch", contentType: jsonPatch, patch: `[{"op": "test", "path": "/title", "value": "Bridge"}, {"op": "replace", "path": "/title", "value": "Tunnel"}]`, status: http.StatusOK,
+			check: func(t *testing.T, proj projectModel) {
+				if proj.Title != "Tunnel" || proj.Leader != "Jane Doe" {
+					t.Errorf("patched project = %+v", proj)
+				}
+			},
+		},
+		{name: "invalid result", contentType: mergePatch, patch: `{"title": ""}`, status: http.StatusUnprocessableEntity, field: "title", rule: "required"},
+		{name: "unknown leader", contentType: mergePatch, patch: `{"leader_id": "999"}`, status: http.StatusUnprocessableEntity, field: "leader_id", rule: "person"},
+		{name: "deadline moved to the past", contentType: mergePatch, patch: `{"budget": {"deadline": "` + pastDeadline + `"}}`, status: http.StatusUnprocessableEntity, field: "budget.deadline", rule: "future_date"},
+		{name: "overdue project", overdue: true, contentType: mergePatch, patch: `{"title": "Tunnel"}`, status: http.StatusOK},
+		{name: "changed id", contentType: mergePatch, patch: `{"id": "other"}`, status: http.StatusBadRequest},
+		{name: "unknown field", contentType: mergePatch, patch: `{"color": "red"}`, status: http.StatusBadRequest},
+		{name: "failed test", contentType: jsonPatch, patch: `[{"op": "test", "path": "/title", "value": "Tunnel"}]`, status: http.StatusConflict},
+		{name: "malformed patch", contentType: jsonPatch, patch: `{"title": "Tunnel"}`, status: http.StatusBadRequest},
+		{name: "unsupported format", contentType: "text/plain", patch: `title=Tunnel`, status: http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router, repo := newTestServer(t)
+			// Jane Doe and John Doe get the ids 1 and 2
+			jane := createPerson(t, router, "Jane Doe")
+			createPerson(t, router, "John Doe")
+			proj := createProject(t, router, projectBody("Bridge", jane, "3000", "USD", futureDeadline))
+			if tt.overdue {
+				makeOverdue(t, repo, proj.ID)
+			}
+
+			w := request(router, http.MethodPatch, "/api/v1/projects/"+proj.ID, tt.patch, "Content-Type", tt.contentType)
+			if tt.status == http.StatusUnprocessableEntity {
+				checkFieldError(t, w, tt.field, tt.rule)
+				return
+			}
+			checkStatus(t, w, tt.status)
+			if tt.check != nil {
+				var patched projectModel
+				decode(t, w, &patched)
+				tt.check(t, patched)
+			}
+		})
+	}
+
+	t.Run("unknown project", func(t *testing.T) {
+		router, _ := newTestServer(t)
+		w := request(router, http.MethodPatch, "/api/v1/projects/missing", `{"title": "Tunnel"}`, "Content-Type", mergePatch)
+		checkStatus(t, w, http.StatusNotFound)
+	})
+}
+
+func TestProjectPreconditions(t *testing.T) {
+	router, _ := newTestServer(t)
+	jane := createPerson(t, router, "Jane Doe")
+	proj := createProject(t, router, projectBody("Bridge", jane, "3000", "USD", futureDeadline))
+	path := "/api/v1/projects/" + proj.ID
+
+	w := request(router, http.MethodGet, path, "")
+	checkStatus(t, w, http.StatusOK)
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("GET returned no ETag")
+	}
+
+	steps := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		header []string
+		status int
+	}{
+		{name: "unchanged", method: http.MethodGet, path: path, header: []string{"If-None-Match", etag}, status: http.StatusNotModified},
+		{name: "weak tag in list", method: http.MethodGet, path: path, header: []string{"If-None-Match", `"0.0", W/` + etag}, status: http.StatusNotModified},
+		{name: "any tag", method: http.MethodGet, path: path, header: []string{"If-None-Match", "*"}, status: http.StatusNotModified},
+		{name: "other tag", method: http.MethodGet, path: path, header: []string{"If-None-Match", `"0.0"`}, status: http.StatusOK},
+		{name: "weak If-Match", method: http.MethodPatch, path: path, body: `{"title": "Tunnel"}`, header: []string{"Content-Type", "application/merge-patch+json", "If-Match", "W/" + etag}, status: http.StatusPreconditionFailed},
+		{name: "current If-Match", method: http.MethodPatch, path: path, body: `{"title": "Tunnel"}`, header: []string{"Content-Type", "application/merge-patch+json", "If-Match", etag}, status: http.StatusOK},
+		{name: "stale PUT", method: http.MethodPut, path: "/api/v1/projects/" + proj.ID, body: projectBody("Bridge", jane, "3000", "USD", futureDeadline), header: []string{"If-Match", etag}, status: http.StatusPreconditionFailed},
+		{name: "stale DELETE", method: http.MethodDelete, path: "/api/v1/projects/" + proj.ID, header: []string{"If-Match", etag}, status: http.StatusPreconditionFailed},
+		{name: "stale If-None-Match", method: http.MethodGet, path: path, header: []string{"If-None-Match", etag}, status: http.StatusOK},
+		{name: "unconditional DELETE", method: http.MethodDelete, path: "/api/v1/projects/" + proj.ID, status: http.StatusOK},
+	}
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			w := request(router, step.method, step.path, step.body, step.header...)
+			checkStatus(t, w, step.status)
+		})
+	}
+}
+
+func TestLegacyRoutes(t *testing.T) {
+	router, _ := newTestServer(t)
+	jane := createPerson(t, router, "Jane Doe")
+	proj := createProject(t, router, projectBody("Bridge", jane, "3000", "USD", futureDeadline))
+
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		body      string
+		status    int
+		successor string
+	}{
+		{name: "list", method: http.MethodGet, path: "/projects", status: http.StatusOK, successor: "/api/v1/projects"},
+		{name: "get", method: http.MethodGet, path: "/projects/" + proj.ID, status: http.StatusOK, successor: "/api/v1/projects/" + proj.ID},
+		{name: "create", method: http.MethodPost, path: "/projects", body: projectBody("Tunnel", jane, "1000", "USD", futureDeadline), status: http.StatusCreated, successor: "/api/v1/projects"},
+		{name: "update", method: http.MethodPut, path: "/project/" + proj.ID, body: projectBody("Bridge 2", jane, "3000", "USD", futureDeadline), status: http.StatusOK, successor: "/api/v1/projects/" + proj.ID},
+		{name: "patch", method: http.MethodPatch, path: "/projects/" + proj.ID, body: `{"title": "Bridge 3"}`, status: http.StatusMethodNotAllowed},
+		{name: "delete", method: http.MethodDelete, path: "/project/" + proj.ID, status: http.StatusOK, successor: "/api/v1/projects/" + proj.ID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := request(router, tt.method, tt.path, tt.body)
+			checkStatus(t, w, tt.status)
+			if tt.successor == "" {
+				return
+			}
+
+			if w.Header().Get("Deprecation") == "" || w.Header().Get("Sunset") == "" {
+				t.Errorf("headers = %v, want Deprecation and Sunset", w.Header())
+			}
+			if link, want := w.Header().Get("Link"), "<"+tt.successor+`>; rel="successor-version"`; link != want {
+				t.Errorf("Link = %q, want %q", link, want)
+			}
+		})
+	}
+
+	w := request(router, http.MethodGet, "/api/v1/projects", "")
+	if w.Header().Get("Deprecation") != "" {
+		t.Errorf("/api/v1 response is marked deprecated: %v", w.Header())
+	}
+}
+
+func TestProblemResponses(t *testing.T) {
+	router, _ := newTestServer(t)
+	jane := createPerson(t, router, "Jane Doe")
+	proj := createProject(t, router, projectBody("Bridge", jane, "3000", "USD", futureDeadline))
+	path := "/api/v1/projects/" + proj.ID
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		header []string
+		status int
+		code   string
+	}{
+		{name: "unknown project", method: http.MethodGet, path: "/api/v1/projects/missing", status: http.StatusNotFound, code: codeNotFound},
+		{name: "unknown route", method: http.MethodGet, path: "/api/v1/nothing", status: http.StatusNotFound, code: codeRouteNotFound},
+		{name: "unsupported method", method: http.MethodPost, path: path, status: http.StatusMethodNotAllowed, code: codeMethodNotAllowed},
+		{name: "malformed body", method: http.MethodPost, path: "/api/v1/projects", body: `{"title": `, status: http.StatusBadRequest, code: codeBadRequest},
+		{name: "invalid query", method: http.MethodGet, path: "/api/v1/projects?sort=color", status: http.StatusBadRequest, code: codeInvalidQuery},
+		{name: "invalid body", method: http.MethodPost, path: "/api/v1/projects", body: projectBody("", jane, "3000", "USD", futureDeadline), status: http.StatusUnprocessableEntity, code: codeValidationFailed},
+		{name: "passed deadline", method: http.MethodPost, path: "/api/v1/projects", body: projectBody("Bridge", jane, "3000", "USD", pastDeadline), status: http.StatusUnprocessableEntity, code: codeValidationFailed},
+		{name: "unsupported patch", method: http.MethodPatch, path: path, body: `title=Tunnel`, header: []string{"Content-Type", "text/plain"}, status: http.StatusUnsupportedMediaType, code: codeUnsupportedMediaType},
+		{name: "invalid patch", method: http.MethodPatch, path: path, body: `{}`, header: []string{"Content-Type", "application/json-patch+json"}, status: http.StatusBadRequest, code: codeInvalidPatch},
+		{name: "failed patch test", method: http.MethodPatch, path: path, body: `[{"op": "test", "path": "/title", "value": "Tunnel"}]`, header: []string{"Content-Type", "application/json-patch+json"}, status: http.StatusConflict, code: codePatchTestFailed},
+		{name: "stale ETag", method: http.MethodDelete, path: path, header: []string{"If-Match", `"0.0"`}, status: http.StatusPreconditionFailed, code: codePreconditionFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := request(router, tt.method, tt.path, tt.body, append(tt.header, "X-Request-ID", "req-1")...)
+			problem := checkProblem(t, w, tt.status, tt.code)
+			instance, _, _ := strings.Cut(tt.path, "?")
+			if problem.Type != problemTypePrefix+tt.code || problem.Instance != instance || problem.RequestID != "req-1" {
+				t.Errorf("problem = %+v, want type, instance and request id set", problem)
+			}
+		})
+	}
+}
+
+func TestToAPIError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{name: "duplicate key", err: &mysql.MySQLError{Number: mysqlErrDupEntry}, status: http.StatusConflict, code: codeDuplicateKey},
+		{name: "missing reference", err: &mysql.MySQLError{Number: mysqlErrNoReferencedRow}, status: http.StatusConflict, code: codeForeignKeyViolation},
+		{name: "deadlock", err: &mysql.MySQLError{Number: mysqlErrLockDeadlock}, status: http.StatusServiceUnavailable, code: codeDeadlock},
+		{name: "lock timeout", err: &mysql.MySQLError{Number: mysqlErrLockWaitTimeout}, status: http.StatusServiceUnavailable, code: codeLockTimeout},
+		{name: "wrapped", err: fmt.Errorf("updating project 1: %w", errProjectNotFound), status: http.StatusNotFound, code: codeNotFound},
+		{name: "unknown", err: errors.New("disk on fire"), status: http.StatusInternalServerError, code: codeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			apiErr := toAPIError(tt.err)
+			if apiErr.Status != tt.status || apiErr.Code != tt.code {
+				t.Errorf("toAPIError() = %d %s, want %d %s", apiErr.Status, apiErr.Code, tt.status, tt.code)
+			}
+			if !errors.Is(apiErr, tt.err) {
+				t.Errorf("toAPIError() does not wrap %v", tt.err)
+			}
+		})
+	}
+
+	t.Run("unknown errors are not sent", func(t *testing.T) {
+		router := gin.New()
+		router.Use(problemErrors())
+		router.GET("/", func(c *gin.Context) { c.Error(errors.New("disk on fire")) })
+
+		w := request(router, http.MethodGet, "/", "")
+		problem := checkProblem(t, w, http.StatusInternalServerError, codeInternal)
+		if strings.Contains(w.Body.String(), "disk on fire") || problem.Detail != "" {
+			t.Errorf("response leaks the error: %s", w.Body)
+		}
+	})
+
+	t.Run("retry after", func(t *testing.T) {
+		router := gin.New()
+		router.Use(problemErrors())
+		router.GET("/", func(c *gin.Context) { c.Error(&mysql.MySQLError{Number: mysqlErrLockDeadlock}) })
+
+		w := request(router, http.MethodGet, "/", "")
+		checkProblem(t, w, http.StatusServiceUnavailable, codeDeadlock)
+		if w.Header().Get("Retry-After") == "" {
+			t.Error("no Retry-After header")
+		}
+	})
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+
+	"go-example-api/internal/migrate"
+)
+
+// healthCheck is a dependency the API needs to serve traffic.
+type healthCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+type componentHealth struct {
+	Name      string  `json:"name" example:"database"`
+	Status    string  `json:"status" example:"up"`
+	LatencyMS float64 `json:"latency_ms" example:"1.25"`
+}
+
+type healthReport struct {
+	Status     string            `json:"status" example:"up"`
+	Components []componentHealth `json:"components"`
+}
+
+// healthHandler serves the liveness, readiness and health endpoints.
+type healthHandler struct {
+	checks  []healthCheck
+	timeout time.Duration
+}
+
+func newHealthHandler(timeout time.Duration, checks ...healthCheck) *healthHandler {
+	return &healthHandler{checks: checks, timeout: timeout}
+}
+
+func (h *healthHandler) register(router gin.IRouter) {
+	router.GET("/healthz", h.liveness)
+	router.GET("/readyz", h.readiness)
+	router.GET("/health", h.health)
+}
+
+// liveness godoc
+// @Summary      Liveness probe
+// @Description  Reports that the process is running. It does not check any dependency.
+// @Tags         Health
+// @Produce      plain
+// @Success      200  {string}  string  "ok"
+// @Router       /healthz [get]
+func (h *healthHandler) liveness(c *gin.Context) {
+	c.String(http.StatusOK, "ok")
+}
+
+// readiness godoc
+// @Summary      Readiness probe
+// @Description  Reports whether the API can serve traffic: the database answers within the timeout, every migration has been applied and the connection pool is not exhausted.
+// @Tags         Health
+// @Produce      plain
+// @Success      200  {string}  string  "ok"
+// @Failure      503  {string}  string  "not ready: database"
+// @Router       /readyz [get]
+func (h *healthHandler) readiness(c *gin.Context) {
+	report := h.run(c.Request.Context())
+	if report.Status != "up" {
+		failing := ""
+		for _, component := range report.Components {
+			if component.Status != "up" {
+				failing += " " + component.Name
+			}
+		}
+		c.String(http.StatusServiceUnavailable, "not ready:"+failing)
+		return
+	}
+	c.String(http.StatusOK, "ok")
+}
+
+// health godoc
+// @Summary      Health report
+// @Description  Reports the status and latency of every component the API depends on. Why a component is down is only logged.
+// @Tags         Health
+// @Produce      json
+// @Success      200  {object}  healthReport
+// @Failure      503  {object}  healthReport
+// @Router       /health [get]
+func (h *healthHandler) health(c *gin.Context) {
+	report := h.run(c.Request.Context())
+	status := http.StatusOK
+	if report.Status != "up" {
+		status = http.StatusServiceUnavailable
+	}
+	c.IndentedJSON(status, report)
+}
+
+// run executes every check concurrently, each bounded by h.timeout.
+func (h *healthHandler) run(ctx context.Context) healthReport {
+	report := healthReport{Status: "up", Components: make([]componentHealth, len(h.checks))}
+
+	var wg sync.WaitGroup
+	for i, check := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, h.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check.check(checkCtx)
+			component := componentHealth{
+				Name:      check.name,
+				Status:    "up",
+				LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				// The cause may reveal internals, so it is only logged
+				log.Ctx(ctx).Warn().Msg("Health check " + check.name + " failed: " + err.Error())
+				component.Status = "down"
+			}
+			report.Components[i] = component
+		}()
+	}
+	wg.Wait()
+
+	for _, component := range report.Components {
+		if component.Status != "up" {
+			report.Status = "down"
+		}
+	}
+	return report
+}
+
+// databaseChecks returns the checks for a MySQL backed API.
+func databaseChecks(db *sql.DB, migrator *migrate.Migrator) []healthCheck {
+	return []healthCheck{
+		{name: "database", check: db.PingContext},
+		{name: "migrations", check: migrator.Check},
+		{name: "connection_pool", check: func(ctx context.Context) error {
+			return checkPool(db.Stats())
+		}},
+	}
+}
+
+// checkPool fails when every connection of the pool is in use and requests
+// are queueing for one.
+func checkPool(stats sql.DBStats) error {
+	if stats.MaxOpenConnections > 0 && stats.InUse >= stats.MaxOpenConnections {
+		return fmt.Errorf("all %d connections in use, %d requests waited so far", stats.InUse, stats.WaitCount)
+	}
+	return nil
+}
+
+// probe requests url and fails unless it answers with 200. It backs the
+// healthcheck subcommand, which lets the container check itself without
+// shipping curl in the image.
+func probe(url string, timeout time.Duration) error {
+	client := http.Client{Timeout: timeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(url + " answered " + resp.Status)
+	}
+	return nil
+}
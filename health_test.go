@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHealthEndpoints(t *testing.T) {
+	up := healthCheck{name: "cache", check: func(ctx context.Context) error { return nil }}
+	down := healthCheck{name: "database", check: func(ctx context.Context) error {
+		return errors.New("dial tcp 10.0.0.7:3306: connection refused")
+	}}
+	slow := healthCheck{name: "schema", check: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+
+	tests := []struct {
+		name      string
+		checks    []healthCheck
+		status    int
+		readiness string
+		down      []string
+	}{
+		{name: "no checks", status: http.StatusOK, readiness: "ok"},
+		{name: "all up", checks: []healthCheck{up}, status: http.StatusOK, readiness: "ok"},
+		{name: "one down", checks: []healthCheck{up, down}, status: http.StatusServiceUnavailable, readiness: "not ready: database", down: []string{"database"}},
+		{name: "timed out", checks: []healthCheck{slow, down}, status: http.StatusServiceUnavailable, readiness: "not ready: schema database", down: []string{"schema", "database"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			newHealthHandler(10*time.Millisecond, tt.checks...).register(router)
+
+			w := request(router, http.MethodGet, "/healthz", "")
+			checkStatus(t, w, http.StatusOK)
+
+			w = request(router, http.MethodGet, "/readyz", "")
+			checkStatus(t, w, tt.status)
+			if w.Body.String() != tt.readiness {
+				t.Errorf("readiness = %q, want %q", w.Body, tt.readiness)
+			}
+
+			w = request(router, http.MethodGet, "/health", "")
+			checkStatus(t, w, tt.status)
+			if strings.Contains(w.Body.String(), "10.0.0.7") || strings.Contains(w.Body.String(), "deadline") {
+				t.Errorf("health report leaks the cause: %s", w.Body)
+			}
+
+			var report healthReport
+			decode(t, w, &report)
+			var downs []string
+			for _, component := range report.Components {
+				if component.Status == "down" {
+					downs = append(downs, component.Name)
+				}
+			}
+			if strings.Join(downs, ",") != strings.Join(tt.down, ",") {
+				t.Errorf("down components = %v, want %v", downs, tt.down)
+			}
+		})
+	}
+}
+
+func TestCheckPool(t *testing.T) {
+	tests := []struct {
+		name  string
+		stats sql.DBStats
+		ok    bool
+	}{
+		{name: "unlimited", stats: sql.DBStats{InUse: 50}, ok: true},
+		{name: "spare connections", stats: sql.DBStats{MaxOpenConnections: 10, InUse: 9}, ok: true},
+		{name: "exhausted", stats: sql.DBStats{MaxOpenConnections: 10, InUse: 10, WaitCount: 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkPool(tt.stats); (err == nil) != tt.ok {
+				t.Errorf("checkPool() = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
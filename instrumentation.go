@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// observeQuery starts a span and a timer for the database query called name.
+// The returned function must be called with the outcome of the query once it
+// is done. sql.ErrNoRows counts as a successful query.
+func observeQuery(ctx context.Context, name, query string) func(err error) {
+	start := time.Now()
+	_, span := tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemMySQL, semconv.DBOperationName(name), semconv.DBQueryText(query)),
+	)
+
+	return func(err error) {
+		status := "ok"
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			status = "error"
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+		dbQueryDuration.WithLabelValues(name, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// execQuery runs a statement within tx and records it under name.
+func execQuery(ctx context.Context, tx *sql.Tx, name, query string, args ...any) (sql.Result, error) {
+	done := observeQuery(ctx, name, query)
+	result, err := tx.ExecContext(ctx, query, args...)
+	done(err)
+	return result, err
+}
+
+// beginTx starts a transaction on db and records it.
+func beginTx(ctx context.Context, db *sql.DB) (*sql.Tx, error) {
+	done := observeQuery(ctx, "begin", "BEGIN")
+	tx, err := db.BeginTx(ctx, nil)
+	done(err)
+	return tx, err
+}
+
+// commit commits tx and records it.
+func commit(ctx context.Context, tx *sql.Tx) error {
+	done := observeQuery(ctx, "commit", "COMMIT")
+	err := tx.Commit()
+	done(err)
+	return err
+}
@@ -0,0 +1,95 @@
+// Package auth verifies the JWT bearer tokens sent by API clients.
+//
+// Tokens are signed either with HS256 and a secret shared with the issuer,
+// or with RS256 and a private key whose public half is published in a JSON
+// Web Key Set, read from a file or fetched from a URL.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go-example-api/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ErrInvalidToken is wrapped by every error returned for a token that is
+// malformed, badly signed, expired or issued for someone else.
+var ErrInvalidToken = errors.New("invalid token")
+
+// Claims are the claims of a verified token.
+type Claims struct {
+	jwt.RegisteredClaims
+	// Email identifies the caller among the people of the API.
+	Email string `json:"email,omitempty"`
+	// Roles grant the caller their permissions.
+	Roles []string `json:"roles,omitempty"`
+}
+
+// Verifier checks the signature and the claims of tokens.
+type Verifier struct {
+	secret  []byte
+	keys    *keySet
+	methods []string
+	options []jwt.ParserOption
+}
+
+// New returns a Verifier accepting the tokens signed with the secret or the
+// keys set in cfg. The keys of a JWKS are loaded right away so that a
+// misconfiguration stops the server from starting.
+func New(ctx context.Context, cfg config.Auth) (*Verifier, error) {
+	v := &Verifier{}
+	if cfg.HS256Secret != "" {
+		v.secret = []byte(cfg.HS256Secret)
+		v.methods = append(v.methods, jwt.SigningMethodHS256.Alg())
+	}
+	if cfg.JWKSFile != "" || cfg.JWKSURL != "" {
+		v.keys = newKeySet(cfg.JWKSFile, cfg.JWKSURL, cfg.JWKSRefresh)
+		if err := v.keys.load(ctx); err != nil {
+			return nil, err
+		}
+		v.methods = append(v.methods, jwt.SigningMethodRS256.Alg())
+	}
+	if len(v.methods) == 0 {
+		return nil, errors.New("no key to verify tokens with")
+	}
+
+	v.options = []jwt.ParserOption{
+		jwt.WithValidMethods(v.methods),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(cfg.Leeway),
+	}
+	if cfg.Issuer != "" {
+		v.options = append(v.options, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		v.options = append(v.options, jwt.WithAudience(cfg.Audience))
+	}
+	return v, nil
+}
+
+// Verify returns the claims of token once its signature and claims are
+// checked. Tokens must expire and name their subject.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		switch t.Method {
+		case jwt.SigningMethodHS256:
+			return v.secret, nil
+		case jwt.SigningMethodRS256:
+			kid, _ := t.Header["kid"].(string)
+			return v.keys.key(ctx, kid)
+		}
+		return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+	}, v.options...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: token has no subject", ErrInvalidToken)
+	}
+	return claims, nil
+}
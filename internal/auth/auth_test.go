@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go-example-api/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+// sign returns a token with claims signed with key, naming kid in its
+// header when set.
+func sign(t *testing.T, method jwt.SigningMethod, key any, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// claimsFor returns valid claims for subject, changed by the given pairs.
+func claimsFor(subject string, changes ...any) jwt.MapClaims {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"sub": subject,
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+		"iss": "https://id.example.com",
+		"aud": "projects",
+	}
+	for i := 0; i < len(changes); i += 2 {
+		name := changes[i].(string)
+		if changes[i+1] == nil {
+			delete(claims, name)
+			continue
+		}
+		claims[name] = changes[i+1]
+	}
+	return claims
+}
+
+func generateKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestVerifyHS256(t *testing.T) {
+	v, err := New(context.Background(), config.Auth{
+		HS256Secret: testSecret,
+		Issuer:      "https://id.example.com",
+		Audience:    "projects",
+		Leeway:      30 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret := []byte(testSecret)
+	rsaKey := generateKey(t)
+	past := time.Now().Add(-time.Minute).Unix()
+
+	tests := []struct {
+		name  string
+		token string
+		want  string
+	}{
+		{name: "valid", token: sign(t, jwt.SigningMethodHS256, secret, "", claimsFor("jane"))},
+		{name: "expired within the leeway", token: sign(t, jwt.SigningMethodHS256, secret, "", claimsFor("jane", "exp", time.Now().Add(-10*time.Second).Unix()))},
+		{name: "expired", token: sign(t, jwt.SigningMethodHS256, secret, "", claimsFor("jane", "exp", past)), want: "expired"},
+		{name: "without expiry", token: sign(t, jwt.SigningMethodHS256, secret, "", claimsFor("jane", "exp", nil)), want: "exp"},
+		{name: "not valid yet", token: sign(t, jwt.SigningMethodHS256, secret, "", claimsFor("jane", "nbf", time.Now().Add(time.Hour).Unix())), want: "not valid yet"},
+		{name: "without subject", token: sign(t, jwt.SigningMethodHS256, secret, "", claimsFor("jane", "sub", nil)), want: "no subject"},
+		{name: "other issuer", token: sign(t, jwt.SigningMethodHS256, secret, "", claimsFor("jane", "iss", "https://evil.example.com")), want: "iss"},
+		{name: "other audience", token: sign(t, jwt.SigningMethodHS256, secret, "", claimsFor("jane", "aud", "billing")), want: "aud"},
+		{name: "other secret", token: sign(t, jwt.SigningMethodHS256, []byte(strings.Repeat("x", 32)), "", claimsFor("jane")), want: "signature"},
+		{name: "HS512", token: sign(t, jwt.SigningMethodHS512, secret, "", claimsFor("jane")), want: "signing method"},
+		{name: "RS256 without keys", token: sign(t, jwt.SigningMethodRS256, rsaKey, "", claimsFor("jane")), want: "signing method"},
+		{name: "unsigned", token: sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", claimsFor("jane")), want: "signing method"},
+		{name: "malformed", token: "not.a.token", want: "malformed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := v.Verify(context.Background(), tt.token)
+			if tt.want == "" {
+				if err != nil || claims.Subject != "jane" {
+					t.Errorf("Verify() = %+v, %v, want jane's claims", claims, err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidToken) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Verify() error = %v, want an invalid token error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyRS256(t *testing.T) {
+	key := generateKey(t)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwksOf(map[string]*rsa.PublicKey{"k1": &key.PublicKey}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	v, err := New(context.Background(), config.Auth{JWKSFile: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := generateKey(t)
+
+	tests := []struct {
+		name  string
+		token string
+		want  string
+	}{
+		{name: "valid", token: sign(t, jwt.SigningMethodRS256, key, "k1", claimsFor("jane"))},
+		{name: "without kid from a single key set", token: sign(t, jwt.SigningMethodRS256, key, "", claimsFor("jane"))},
+		{name: "unknown kid", token: sign(t, jwt.SigningMethodRS256, key, "k2", claimsFor("jane")), want: `unknown key "k2"`},
+		{name: "other key", token: sign(t, jwt.SigningMethodRS256, other, "k1", claimsFor("jane")), want: "verification error"},
+		// The public key must not be accepted as an HS256 secret
+		{name: "HS256 without secret", token: sign(t, jwt.SigningMethodHS256, []byte(testSecret), "k1", claimsFor("jane")), want: "signing method"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := v.Verify(context.Background(), tt.token)
+			if tt.want == "" {
+				if err != nil || claims.Subject != "jane" {
+					t.Errorf("Verify() = %+v, %v, want jane's claims", claims, err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidToken) || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Verify() error = %v, want an invalid token error containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.Auth
+		want string
+	}{
+		{name: "no key", cfg: config.Auth{}, want: "no key"},
+		{name: "missing JWKS file", cfg: config.Auth{JWKSFile: filepath.Join(t.TempDir(), "missing.json")}, want: "reading JWKS file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(context.Background(), tt.cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("New() error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
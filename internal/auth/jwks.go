@@ -0,0 +1,256 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// minRefetchInterval bounds how often a token signed with an unknown
+	// key can make the key set be fetched again.
+	minRefetchInterval = time.Minute
+	// maxJWKSSize bounds the key sets read from files and URLs.
+	maxJWKSSize  = 1 << 20
+	fetchTimeout = 10 * time.Second
+)
+
+// jwk is a JSON Web Key as described in RFC 7517. Only the members of RSA
+// keys are decoded.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// keySet holds the RSA public keys of a JWKS by key id. Keys read from a file
+// are loaded once, keys fetched from a URL are fetched again every refresh
+// interval and when a token is signed with a key not seen yet.
+//
+// Fetches happen in the background, one at a time, so that verifying tokens
+// never waits on the lock while the identity provider answers.
+type keySet struct {
+	file    string
+	url     string
+	refresh time.Duration
+	client  *http.Client
+
+	mu      sync.RWMutex
+	keys    map[string]*rsa.PublicKey
+	fetched time.Time
+	// err is the error of the last fetch, nil once it succeeded.
+	err error
+	// fetching is closed when the fetch in flight completes, nil when none is.
+	fetching chan struct{}
+}
+
+func newKeySet(file, url string, refresh time.Duration) *keySet {
+	return &keySet{
+		file:    file,
+		url:     url,
+		refresh: refresh,
+		client:  &http.Client{Timeout: fetchTimeout},
+	}
+}
+
+func (s *keySet) load(ctx context.Context) error {
+	s.mu.Lock()
+	s.fetched = time.Now()
+	s.mu.Unlock()
+	return s.reload(ctx)
+}
+
+// key returns the key with the given id. An empty id is accepted when the
+// set holds a single key.
+func (s *keySet) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	s.mu.RLock()
+	key, ok := s.lookup(kid)
+	age := time.Since(s.fetched)
+	fetching := s.fetching != nil
+	s.mu.RUnlock()
+
+	if s.url != "" {
+		switch {
+		case ok:
+			// Keep verifying with the keys at hand while they are fetched again
+			if age > s.refresh {
+				s.refetch(s.refresh)
+			}
+		case kid != "" && (age > minRefetchInterval || fetching):
+			// The key may have been rotated in since the last fetch
+			select {
+			case <-s.refetch(minRefetchInterval):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			s.mu.RLock()
+			key, ok = s.lookup(kid)
+			s.mu.RUnlock()
+		}
+	}
+
+	if !ok {
+		s.mu.RLock()
+		defer s.mu.RUnlock()
+		if s.err != nil {
+			return nil, fmt.Errorf("unknown key %q: %w", kid, s.err)
+		}
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+	return key, nil
+}
+
+// lookup returns the key with the given id. The caller must hold s.mu.
+func (s *keySet) lookup(kid string) (*rsa.PublicKey, bool) {
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+// refetch starts fetching the keys of the URL again unless a fetch is in
+// flight or completed less than interval ago, failed or not. It returns a
+// channel closed once there is no fetch to wait for anymore.
+func (s *keySet) refetch(interval time.Duration) <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.fetching != nil {
+		return s.fetching
+	}
+	done := make(chan struct{})
+	if time.Since(s.fetched) <= interval {
+		close(done)
+		return done
+	}
+
+	s.fetching = done
+	s.fetched = time.Now()
+	go func() {
+		// The fetch outlives the request that started it, others may wait on it
+		s.reload(context.Background())
+		s.mu.Lock()
+		s.fetching = nil
+		s.mu.Unlock()
+		close(done)
+	}()
+	return done
+}
+
+// reload reads the set again and replaces the keys, which are kept when
+// reading fails.
+func (s *keySet) reload(ctx context.Context) error {
+	keys, err := s.read(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+	if err == nil {
+		s.keys = keys
+	}
+	return err
+}
+
+func (s *keySet) read(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	var b []byte
+	var err error
+	if s.file != "" {
+		b, err = readJWKSFile(s.file)
+	} else {
+		b, err = s.fetch(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := parseJWKS(b)
+	if err != nil {
+		return nil, fmt.Errorf("parsing JWKS: %w", err)
+	}
+	return keys, nil
+}
+
+func readJWKSFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading JWKS file: %w", err)
+	}
+	defer f.Close()
+	return io.ReadAll(io.LimitReader(f, maxJWKSSize))
+}
+
+func (s *keySet) fetch(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("fetching JWKS: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching JWKS: %s answered %s", s.url, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxJWKSSize))
+}
+
+// parseJWKS returns the RSA signing keys of a JWKS by key id. Keys of other
+// types or uses are skipped.
+func parseJWKS(b []byte) (map[string]*rsa.PublicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") || (k.Alg != "" && k.Alg != "RS256") {
+			continue
+		}
+		key, err := rsaPublicKey(k)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no RSA signing key")
+	}
+	return keys, nil
+}
+
+func rsaPublicKey(k jwk) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("exponent: %w", err)
+	}
+	exponent := new(big.Int).SetBytes(e)
+	if len(n) == 0 || !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid modulus or exponent")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+}
@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// jwksOf returns the JWKS publishing keys by key id.
+func jwksOf(keys map[string]*rsa.PublicKey) []byte {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	for kid, key := range keys {
+		set.Keys = append(set.Keys, jwk{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			Alg: "RS256",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	b, _ := json.Marshal(set)
+	return b
+}
+
+func TestParseJWKS(t *testing.T) {
+	key := generateKey(t)
+	n := base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+
+	tests := []struct {
+		name string
+		jwks string
+		kids []string
+		want string
+	}{
+		{name: "signing key", jwks: `{"keys": [{"kty": "RSA", "kid": "k1", "n": "` + n + `", "e": "AQAB"}]}`, kids: []string{"k1"}},
+		{
+			name: "other keys skipped",
+			jwks: `{"keys": [{"kty": "EC", "kid": "ec"}, {"kty": "RSA", "kid": "enc", "use": "enc", "n": "` + n + `", "e": "AQAB"},` +
+				`{"kty": "RSA", "kid": "ps", "alg": "PS256", "n": "` + n + `", "e": "AQAB"}, {"kty": "RSA", "kid": "k1", "n": "` + n + `", "e": "AQAB"}]}`,
+			kids: []string{"k1"},
+		},
+		{name: "no signing key", jwks: `{"keys": [{"kty": "EC", "kid": "ec"}]}`, want: "no RSA signing key"},
+		{name: "invalid modulus", jwks: `{"keys": [{"kty": "RSA", "kid": "k1", "n": "*", "e": "AQAB"}]}`, want: "modulus"},
+		{name: "tiny exponent", jwks: `{"keys": [{"kty": "RSA", "kid": "k1", "n": "` + n + `", "e": "AQ"}]}`, want: "invalid modulus or exponent"},
+		{name: "not JSON", jwks: `keys`, want: "invalid character"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := parseJWKS([]byte(tt.jwks))
+			if tt.want != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.want) {
+					t.Errorf("parseJWKS() error = %v, want one containing %q", err, tt.want)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(keys) != len(tt.kids) {
+				t.Errorf("parseJWKS() = %d keys, want %q", len(keys), tt.kids)
+			}
+			for _, kid := range tt.kids {
+				if got := keys[kid]; got == nil || !got.Equal(&key.PublicKey) {
+					t.Errorf("key %s = %v, want the published key", kid, got)
+				}
+			}
+		})
+	}
+}
+
+// jwksServer publishes a JWKS which can be changed, and counts its fetches.
+type jwksServer struct {
+	*httptest.Server
+	fetches atomic.Int32
+
+	mu   sync.Mutex
+	jwks []byte
+	// release, when set, holds the answers until it is closed.
+	release chan struct{}
+}
+
+func newJWKSServer(t *testing.T, keys map[string]*rsa.PublicKey) *jwksServer {
+	s := &jwksServer{jwks: jwksOf(keys)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.fetches.Add(1)
+		s.mu.Lock()
+		jwks, release := s.jwks, s.release
+		s.mu.Unlock()
+		if release != nil {
+			<-release
+		}
+		if jwks == nil {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write(jwks)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) publish(jwks []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jwks = jwks
+}
+
+// age makes the keys of set look fetched d ago.
+func age(set *keySet, d time.Duration) {
+	set.mu.Lock()
+	defer set.mu.Unlock()
+	set.fetched = time.Now().Add(-d)
+}
+
+// wait waits for the fetch of set in flight, if any.
+func wait(set *keySet) {
+	set.mu.RLock()
+	fetching := set.fetching
+	set.mu.RUnlock()
+	if fetching != nil {
+		<-fetching
+	}
+}
+
+func TestKeySetURL(t *testing.T) {
+	ctx := context.Background()
+	k1, k2 := generateKey(t), generateKey(t)
+	server := newJWKSServer(t, map[string]*rsa.PublicKey{"k1": &k1.PublicKey})
+	set := newKeySet("", server.URL, time.Hour)
+	if err := set.load(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if key, err := set.key(ctx, "k1"); err != nil || !key.Equal(&k1.PublicKey) {
+		t.Errorf("key(k1) = %v, %v, want k1", key, err)
+	}
+	if _, err := set.key(ctx, "k2"); err == nil {
+		t.Error("key(k2) found a key that is not published")
+	}
+	if got := server.fetches.Load(); got != 1 {
+		t.Errorf("fetched %d times, want once as the set was fetched less than a minute ago", got)
+	}
+
+	// A rotated key is fetched once a minute has passed
+	server.publish(jwksOf(map[string]*rsa.PublicKey{"k1": &k1.PublicKey, "k2": &k2.PublicKey}))
+	age(set, 2*time.Minute)
+	if key, err := set.key(ctx, "k2"); err != nil || !key.Equal(&k2.PublicKey) {
+		t.Errorf("key(k2) = %v, %v, want k2 once rotated in", key, err)
+	}
+	if got := server.fetches.Load(); got != 2 {
+		t.Errorf("fetched %d times, want twice", got)
+	}
+
+	// Stale keys keep verifying while they are fetched again
+	server.publish(nil)
+	age(set, 2*time.Hour)
+	if key, err := set.key(ctx, "k1"); err != nil || !key.Equal(&k1.PublicKey) {
+		t.Errorf("key(k1) = %v, %v, want k1 while the set is fetched again", key, err)
+	}
+	wait(set)
+	if got := server.fetches.Load(); got != 3 {
+		t.Errorf("fetched %d times, want 3", got)
+	}
+	if key, err := set.key(ctx, "k1"); err != nil || !key.Equal(&k1.PublicKey) {
+		t.Errorf("key(k1) = %v, %v, want k1 kept when fetching fails", key, err)
+	}
+	age(set, 2*time.Minute)
+	if _, err := set.key(ctx, "k3"); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("key(k3) error = %v, want the failed fetch", err)
+	}
+}
+
+func TestKeySetFetchesOnce(t *testing.T) {
+	k1 := generateKey(t)
+	server := newJWKSServer(t, map[string]*rsa.PublicKey{"k1": &k1.PublicKey})
+	set := newKeySet("", server.URL, time.Hour)
+	if err := set.load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	age(set, 2*time.Minute)
+
+	release := make(chan struct{})
+	server.mu.Lock()
+	server.release = release
+	server.mu.Unlock()
+
+	// Tokens with an unknown kid wait for a single fetch, and do not keep the
+	// known keys from being used meanwhile
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := set.key(context.Background(), "k2"); err == nil {
+				t.Error("key(k2) found a key that is not published")
+			}
+		}()
+	}
+	for server.fetches.Load() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := set.key(context.Background(), "k1"); err != nil {
+		t.Errorf("key(k1) = %v during the fetch", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := set.key(ctx, "k2"); err != context.Canceled {
+		t.Errorf("key(k2) error = %v, want the request to stop waiting once cancelled", err)
+	}
+
+	close(release)
+	wg.Wait()
+	if got := server.fetches.Load(); got != 2 {
+		t.Errorf("fetched %d times, want twice: on load and once for every unknown kid", got)
+	}
+}
@@ -0,0 +1,672 @@
+// Package config loads the settings of the API server.
+//
+// Every setting has a default and can be overridden, from lowest to highest
+// precedence, by a YAML or TOML file, an environment variable and a command
+// line flag.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds every setting of the API server.
+type Config struct {
+	// Storage selects the ProjectRepository, either "mysql" or "memory".
+	Storage string
+
+	Server    Server
+	Database  Database
+	Log       Log
+	Swagger   Swagger
+	Health    Health
+	Tracing   Tracing
+	Auth      Auth
+	RateLimit RateLimit
+}
+
+type Server struct {
+	Addr string
+	// ReadTimeout limits reading a whole request, body included.
+	ReadTimeout time.Duration
+	// WriteTimeout limits the time from the end of the request headers to
+	// the end of the response.
+	WriteTimeout time.Duration
+	// IdleTimeout limits how long a keep-alive connection waits for the next
+	// request.
+	IdleTimeout time.Duration
+	// ShutdownTimeout limits how long in-flight requests may take to finish
+	// once the server is asked to stop.
+	ShutdownTimeout time.Duration
+	// TrustedProxies lists the addresses or CIDR ranges of the proxies whose
+	// X-Forwarded-For header gives the address of the client.
+	TrustedProxies []string
+}
+
+type Database struct {
+	User     string
+	Password string
+	Host     string
+	Name     string
+	// MaxOpenConns limits the connections in the pool, MaxIdleConns the ones
+	// kept open while unused.
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	// AutoMigrate applies the pending migrations at startup.
+	AutoMigrate bool
+}
+
+type Log struct {
+	// Level is the minimum level logged: trace, debug, info, warn or error.
+	Level string
+	// Format is "json" or "console". It applies to the stdout and file
+	// sinks, syslog and Loki always receive JSON.
+	Format string
+	// Sinks lists where logs are written: stdout, file, syslog and loki.
+	Sinks []string
+
+	File string
+	// MaxSize is the size in megabytes at which the log file is rotated.
+	MaxSize int
+	// MaxBackups is the number of rotated files kept, MaxAge how long they
+	// are kept. Zero keeps them all.
+	MaxBackups int
+	MaxAge     time.Duration
+	// Compress gzips the rotated files.
+	Compress bool
+	// RotateInterval rotates the log file periodically regardless of its
+	// size. Zero disables it.
+	RotateInterval time.Duration
+
+	// SyslogNetwork and SyslogAddress locate the syslog daemon, the local
+	// one when both are empty.
+	SyslogNetwork string
+	SyslogAddress string
+	SyslogTag     string
+
+	// LokiURL is the base URL of the Loki server logs are pushed to.
+	LokiURL string
+}
+
+type Swagger struct {
+	Host string
+}
+
+type Health struct {
+	// Timeout limits how long each readiness check may take.
+	Timeout time.Duration
+}
+
+type Tracing struct {
+	// Exporter selects where spans are sent: "none", "stdout" or "otlp".
+	Exporter string
+	// Endpoint is the URL of the OTLP/HTTP collector, e.g.
+	// http://localhost:4318.
+	Endpoint    string
+	ServiceName string
+	// SampleRatio is the fraction of traces recorded when the caller did
+	// not decide already, between 0 and 1.
+	SampleRatio float64
+}
+
+type Auth struct {
+	// Enabled requires a valid JWT bearer token on every API route.
+	Enabled bool
+	// HS256Secret verifies the tokens signed with HS256.
+	HS256Secret string
+	// JWKSFile or JWKSURL holds the RSA public keys verifying the tokens
+	// signed with RS256. At most one of them is set.
+	JWKSFile string
+	JWKSURL  string
+	// JWKSRefresh is how often the keys of JWKSURL are fetched again.
+	JWKSRefresh time.Duration
+	// Issuer and Audience, when set, must match the iss and aud claims.
+	Issuer   string
+	Audience string
+	// Leeway allows for clock skew when checking exp, nbf and iat.
+	Leeway time.Duration
+	// Roles maps the roles found in the roles claim of tokens to the
+	// permissions they grant: read, write, delete and audit.
+	Roles map[string][]string
+}
+
+type RateLimit struct {
+	// Enabled limits how often each client may call the API.
+	Enabled bool
+	// The rate in requests per second and the burst of each group of
+	// routes: listing projects, the other reads and the changes. A zero rate
+	// lifts the limit of the group.
+	ListRate   float64
+	ListBurst  int
+	ReadRate   float64
+	ReadBurst  int
+	WriteRate  float64
+	WriteBurst int
+	// AddressRate and AddressBurst limit the requests from each address
+	// before they are authenticated, failed attempts included.
+	AddressRate  float64
+	AddressBurst int
+	// Quota caps the requests of each client within every QuotaWindow, zero
+	// lifts the cap.
+	Quota       int
+	QuotaWindow time.Duration
+}
+
+// permissions lists what the roles of Auth can grant.
+var permissions = []string{"read", "write", "delete", "audit"}
+
+// Default returns the configuration used when nothing is overridden.
+func Default() *Config {
+	return &Config{
+		Storage: "mysql",
+		Server: Server{
+			Addr:            ":8080",
+			ReadTimeout:     15 * time.Second,
+			WriteTimeout:    30 * time.Second,
+			IdleTimeout:     120 * time.Second,
+			ShutdownTimeout: 20 * time.Second,
+		},
+		Database: Database{
+			Host:            "localhost:3306",
+			Name:            "company",
+			MaxOpenConns:    25,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: 5 * time.Minute,
+		},
+		Log: Log{
+			Level:          "info",
+			Format:         "json",
+			Sinks:          []string{"stdout", "file"},
+			File:           "logs/app.log",
+			MaxSize:        100,
+			MaxBackups:     10,
+			MaxAge:         30 * 24 * time.Hour,
+			Compress:       true,
+			RotateInterval: 24 * time.Hour,
+			SyslogTag:      "go-example-api",
+			LokiURL:        "http://localhost:3100",
+		},
+		Swagger: Swagger{
+			Host: "localhost:8080",
+		},
+		Health: Health{
+			Timeout: 2 * time.Second,
+		},
+		Tracing: Tracing{
+			Exporter:    "none",
+			Endpoint:    "http://localhost:4318",
+			ServiceName: "go-example-api",
+			SampleRatio: 1,
+		},
+		Auth: Auth{
+			Enabled:     true,
+			JWKSRefresh: time.Hour,
+			Leeway:      30 * time.Second,
+			Roles: map[string][]string{
+				"viewer": {"read"},
+				"editor": {"read", "write"},
+				"admin":  {"read", "write", "delete", "audit"},
+			},
+		},
+		RateLimit: RateLimit{
+			Enabled:      true,
+			ListRate:     1,
+			ListBurst:    10,
+			ReadRate:     10,
+			ReadBurst:    50,
+			WriteRate:    2,
+			WriteBurst:   20,
+			AddressRate:  20,
+			AddressBurst: 100,
+			Quota:        10000,
+			QuotaWindow:  24 * time.Hour,
+		},
+	}
+}
+
+// settings binds every field of c to its key, environment variable and
+// description. The key is used both in configuration files and as flag name.
+func (c *Config) settings() []setting {
+	return []setting{
+		{key: "storage", env: "STORAGE", usage: "project storage, mysql or memory", value: (*stringValue)(&c.Storage)},
+		{key: "server.addr", env: "HTTP_ADDR", usage: "address the HTTP server listens on", value: (*stringValue)(&c.Server.Addr)},
+		{key: "server.read_timeout", env: "HTTP_READ_TIMEOUT", usage: "maximum duration for reading a request", value: (*durationValue)(&c.Server.ReadTimeout)},
+		{key: "server.write_timeout", env: "HTTP_WRITE_TIMEOUT", usage: "maximum duration for writing a response", value: (*durationValue)(&c.Server.WriteTimeout)},
+		{key: "server.idle_timeout", env: "HTTP_IDLE_TIMEOUT", usage: "maximum time a keep-alive connection stays idle", value: (*durationValue)(&c.Server.IdleTimeout)},
+		{key: "server.shutdown_timeout", env: "HTTP_SHUTDOWN_TIMEOUT", usage: "maximum time to drain in-flight requests on shutdown", value: (*durationValue)(&c.Server.ShutdownTimeout)},
+		{key: "server.trusted_proxies", env: "HTTP_TRUSTED_PROXIES", usage: "comma separated addresses or CIDR ranges of the proxies setting X-Forwarded-For", value: (*stringsValue)(&c.Server.TrustedProxies)},
+		{key: "database.user", env: "DBUSER", usage: "MySQL user", value: (*stringValue)(&c.Database.User)},
+		{key: "database.password", env: "DBPASS", usage: "MySQL password", secret: true, value: (*stringValue)(&c.Database.Password)},
+		{key: "database.host", env: "DBHOST", usage: "MySQL address as host:port", value: (*stringValue)(&c.Database.Host)},
+		{key: "database.name", env: "DBNAME", usage: "MySQL database name", value: (*stringValue)(&c.Database.Name)},
+		{key: "database.max_open_conns", env: "DB_MAX_OPEN_CONNS", usage: "maximum number of open MySQL connections", value: (*intValue)(&c.Database.MaxOpenConns)},
+		{key: "database.max_idle_conns", env: "DB_MAX_IDLE_CONNS", usage: "maximum number of idle MySQL connections", value: (*intValue)(&c.Database.MaxIdleConns)},
+		{key: "database.conn_max_lifetime", env: "DB_CONN_MAX_LIFETIME", usage: "maximum time a MySQL connection is reused", value: (*durationValue)(&c.Database.ConnMaxLifetime)},
+		{key: "database.auto_migrate", env: "DB_AUTO_MIGRATE", usage: "apply pending migrations at startup", value: (*boolValue)(&c.Database.AutoMigrate)},
+		{key: "log.level", env: "LOG_LEVEL", usage: "minimum log level, trace, debug, info, warn or error", value: (*stringValue)(&c.Log.Level)},
+		{key: "log.format", env: "LOG_FORMAT", usage: "log format of the stdout and file sinks, json or console", value: (*stringValue)(&c.Log.Format)},
+		{key: "log.sinks", env: "LOG_SINKS", usage: "comma separated log sinks among stdout, file, syslog and loki", value: (*stringsValue)(&c.Log.Sinks)},
+		{key: "log.file", env: "LOG_FILE", usage: "file the logs are written to", value: (*stringValue)(&c.Log.File)},
+		{key: "log.max_size", env: "LOG_MAX_SIZE", usage: "size in megabytes at which the log file is rotated", value: (*intValue)(&c.Log.MaxSize)},
+		{key: "log.max_backups", env: "LOG_MAX_BACKUPS", usage: "number of rotated log files kept, 0 keeps all", value: (*intValue)(&c.Log.MaxBackups)},
+		{key: "log.max_age", env: "LOG_MAX_AGE", usage: "how long rotated log files are kept, 0 keeps them forever", value: (*durationValue)(&c.Log.MaxAge)},
+		{key: "log.compress", env: "LOG_COMPRESS", usage: "gzip rotated log files", value: (*boolValue)(&c.Log.Compress)},
+		{key: "log.rotate_interval", env: "LOG_ROTATE_INTERVAL", usage: "rotate the log file periodically, 0 disables it", value: (*durationValue)(&c.Log.RotateInterval)},
+		{key: "log.syslog_network", env: "LOG_SYSLOG_NETWORK", usage: "network of the syslog daemon, e.g. udp, empty for the local one", value: (*stringValue)(&c.Log.SyslogNetwork)},
+		{key: "log.syslog_address", env: "LOG_SYSLOG_ADDRESS", usage: "address of the syslog daemon, empty for the local one", value: (*stringValue)(&c.Log.SyslogAddress)},
+		{key: "log.syslog_tag", env: "LOG_SYSLOG_TAG", usage: "tag of the syslog messages", value: (*stringValue)(&c.Log.SyslogTag)},
+		{key: "log.loki_url", env: "LOG_LOKI_URL", usage: "base URL of the Loki server logs are pushed to", value: (*stringValue)(&c.Log.LokiURL)},
+		{key: "swagger.host", env: "SWAGGER_HOST", usage: "host shown in the Swagger documentation", value: (*stringValue)(&c.Swagger.Host)},
+		{key: "health.timeout", env: "HEALTH_TIMEOUT", usage: "maximum duration of each readiness check", value: (*durationValue)(&c.Health.Timeout)},
+		{key: "tracing.exporter", env: "TRACING_EXPORTER", usage: "span exporter, none, stdout or otlp", value: (*stringValue)(&c.Tracing.Exporter)},
+		{key: "tracing.endpoint", env: "OTEL_EXPORTER_OTLP_ENDPOINT", usage: "URL of the OTLP/HTTP collector", value: (*stringValue)(&c.Tracing.Endpoint)},
+		{key: "tracing.service_name", env: "OTEL_SERVICE_NAME", usage: "service name attached to spans", value: (*stringValue)(&c.Tracing.ServiceName)},
+		{key: "tracing.sample_ratio", env: "TRACING_SAMPLE_RATIO", usage: "fraction of new traces that are recorded", value: (*floatValue)(&c.Tracing.SampleRatio)},
+		{key: "auth.enabled", env: "AUTH_ENABLED", usage: "require a JWT bearer token on every API route", value: (*boolValue)(&c.Auth.Enabled)},
+		{key: "auth.hs256_secret", env: "AUTH_HS256_SECRET", usage: "secret verifying HS256 tokens", secret: true, value: (*stringValue)(&c.Auth.HS256Secret)},
+		{key: "auth.jwks_file", env: "AUTH_JWKS_FILE", usage: "JWKS file with the RSA keys verifying RS256 tokens", value: (*stringValue)(&c.Auth.JWKSFile)},
+		{key: "auth.jwks_url", env: "AUTH_JWKS_URL", usage: "URL of the JWKS with the RSA keys verifying RS256 tokens", value: (*stringValue)(&c.Auth.JWKSURL)},
+		{key: "auth.jwks_refresh", env: "AUTH_JWKS_REFRESH", usage: "how often the keys of auth.jwks_url are fetched again", value: (*durationValue)(&c.Auth.JWKSRefresh)},
+		{key: "auth.issuer", env: "AUTH_ISSUER", usage: "required iss claim, empty accepts any", value: (*stringValue)(&c.Auth.Issuer)},
+		{key: "auth.audience", env: "AUTH_AUDIENCE", usage: "required aud claim, empty accepts any", value: (*stringValue)(&c.Auth.Audience)},
+		{key: "auth.leeway", env: "AUTH_LEEWAY", usage: "clock skew allowed when checking token times", value: (*durationValue)(&c.Auth.Leeway)},
+		{key: "auth.roles", env: "AUTH_ROLES", usage: "permissions of each role, e.g. viewer=read;editor=read,write", value: (*listsValue)(&c.Auth.Roles)},
+		{key: "ratelimit.enabled", env: "RATE_LIMIT_ENABLED", usage: "limit how often each client may call the API", value: (*boolValue)(&c.RateLimit.Enabled)},
+		{key: "ratelimit.list_rate", env: "RATE_LIMIT_LIST_RATE", usage: "project listings per second allowed to each client, 0 is unlimited", value: (*floatValue)(&c.RateLimit.ListRate)},
+		{key: "ratelimit.list_burst", env: "RATE_LIMIT_LIST_BURST", usage: "project listings each client may send at once", value: (*intValue)(&c.RateLimit.ListBurst)},
+		{key: "ratelimit.read_rate", env: "RATE_LIMIT_READ_RATE", usage: "other reads per second allowed to each client, 0 is unlimited", value: (*floatValue)(&c.RateLimit.ReadRate)},
+		{key: "ratelimit.read_burst", env: "RATE_LIMIT_READ_BURST", usage: "other reads each client may send at once", value: (*intValue)(&c.RateLimit.ReadBurst)},
+		{key: "ratelimit.write_rate", env: "RATE_LIMIT_WRITE_RATE", usage: "changes per second allowed to each client, 0 is unlimited", value: (*floatValue)(&c.RateLimit.WriteRate)},
+		{key: "ratelimit.write_burst", env: "RATE_LIMIT_WRITE_BURST", usage: "changes each client may send at once", value: (*intValue)(&c.RateLimit.WriteBurst)},
+		{key: "ratelimit.address_rate", env: "RATE_LIMIT_ADDRESS_RATE", usage: "requests per second allowed from each address before authentication, 0 is unlimited", value: (*floatValue)(&c.RateLimit.AddressRate)},
+		{key: "ratelimit.address_burst", env: "RATE_LIMIT_ADDRESS_BURST", usage: "requests each address may send at once before authentication", value: (*intValue)(&c.RateLimit.AddressBurst)},
+		{key: "ratelimit.quota", env: "RATE_LIMIT_QUOTA", usage: "requests allowed to each client per quota window, 0 is unlimited", value: (*intValue)(&c.RateLimit.Quota)},
+		{key: "ratelimit.quota_window", env: "RATE_LIMIT_QUOTA_WINDOW", usage: "period over which the quota of each client is counted", value: (*durationValue)(&c.RateLimit.QuotaWindow)},
+	}
+}
+
+// Load builds the configuration from the defaults, the configuration file,
+// the environment and the command line flags in args. The configuration
+// file is taken from the -config flag or the CONFIG_FILE environment
+// variable. The arguments left after the flags are returned.
+func Load(args []string) (*Config, []string, error) {
+	cfg := Default()
+	settings := cfg.settings()
+
+	fs := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "YAML or TOML configuration file (env CONFIG_FILE)")
+	flagValues := make(map[string]*string, len(settings))
+	for _, s := range settings {
+		flagValues[s.key] = fs.String(s.key, "", fmt.Sprintf("%s (env %s)", s.usage, s.env))
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			fs.SetOutput(os.Stderr)
+			fs.PrintDefaults()
+		}
+		return nil, nil, err
+	}
+
+	if *configFile != "" {
+		values, err := readFile(*configFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := apply(settings, values, "file "+*configFile); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, s := range settings {
+		if v, ok := os.LookupEnv(s.env); ok {
+			if err := s.value.Set(v); err != nil {
+				return nil, nil, fmt.Errorf("environment variable %s: %w", s.env, err)
+			}
+		}
+	}
+
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if s.key == f.Name && flagErr == nil {
+				if err := s.value.Set(*flagValues[s.key]); err != nil {
+					flagErr = fmt.Errorf("flag -%s: %w", s.key, err)
+				}
+			}
+		}
+	})
+	if flagErr != nil {
+		return nil, nil, flagErr
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	return cfg, fs.Args(), nil
+}
+
+// Validate reports every invalid setting of c at once.
+func (c *Config) Validate() error {
+	var errs []error
+
+	switch c.Storage {
+	case "mysql":
+		if c.Database.User == "" {
+			errs = append(errs, errors.New("database.user is required when storage is mysql"))
+		}
+		if c.Database.Host == "" {
+			errs = append(errs, errors.New("database.host is required when storage is mysql"))
+		}
+		if c.Database.Name == "" {
+			errs = append(errs, errors.New("database.name is required when storage is mysql"))
+		}
+		if c.Database.MaxOpenConns < 1 {
+			errs = append(errs, errors.New("database.max_open_conns must be at least 1"))
+		}
+		if c.Database.MaxIdleConns < 0 || c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+			errs = append(errs, errors.New("database.max_idle_conns must be between 0 and database.max_open_conns"))
+		}
+	case "memory":
+	default:
+		errs = append(errs, fmt.Errorf("storage must be mysql or memory, got %q", c.Storage))
+	}
+
+	if c.Server.Addr == "" {
+		errs = append(errs, errors.New("server.addr is required"))
+	}
+	for _, timeout := range []struct {
+		key string
+		d   time.Duration
+	}{
+		{"server.read_timeout", c.Server.ReadTimeout},
+		{"server.write_timeout", c.Server.WriteTimeout},
+		{"server.idle_timeout", c.Server.IdleTimeout},
+		{"server.shutdown_timeout", c.Server.ShutdownTimeout},
+		{"database.conn_max_lifetime", c.Database.ConnMaxLifetime},
+		{"health.timeout", c.Health.Timeout},
+	} {
+		if timeout.d <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive", timeout.key))
+		}
+	}
+	errs = append(errs, c.Log.validate()...)
+
+	switch c.Tracing.Exporter {
+	case "otlp":
+		if c.Tracing.Endpoint == "" {
+			errs = append(errs, errors.New("tracing.endpoint is required when tracing.exporter is otlp"))
+		}
+	case "none", "stdout":
+	default:
+		errs = append(errs, fmt.Errorf("tracing.exporter must be none, stdout or otlp, got %q", c.Tracing.Exporter))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, errors.New("tracing.sample_ratio must be between 0 and 1"))
+	}
+
+	errs = append(errs, c.Auth.validate()...)
+	// API keys are created in MySQL, memory storage has none to accept
+	if c.Auth.Enabled && !c.Auth.VerifiesTokens() && c.Storage == "memory" {
+		errs = append(errs, errors.New("auth.hs256_secret, auth.jwks_file or auth.jwks_url is required when auth.enabled is true and storage is memory, which has no API keys, set auth.enabled to false to run without authentication"))
+	}
+	errs = append(errs, c.RateLimit.validate()...)
+
+	return errors.Join(errs...)
+}
+
+// VerifiesTokens reports whether a secret or keys are set to verify bearer
+// tokens with. Without them only API keys are accepted.
+func (a *Auth) VerifiesTokens() bool {
+	return a.HS256Secret != "" || a.JWKSFile != "" || a.JWKSURL != ""
+}
+
+func (r *RateLimit) validate() []error {
+	if !r.Enabled {
+		return nil
+	}
+
+	var errs []error
+	for _, group := range []struct {
+		name  string
+		rate  float64
+		burst int
+	}{
+		{"list", r.ListRate, r.ListBurst},
+		{"read", r.ReadRate, r.ReadBurst},
+		{"write", r.WriteRate, r.WriteBurst},
+		{"address", r.AddressRate, r.AddressBurst},
+	} {
+		if group.rate < 0 {
+			errs = append(errs, fmt.Errorf("ratelimit.%s_rate must not be negative", group.name))
+		}
+		if group.rate > 0 && group.burst < 1 {
+			errs = append(errs, fmt.Errorf("ratelimit.%s_burst must be at least 1", group.name))
+		}
+	}
+	if r.Quota < 0 {
+		errs = append(errs, errors.New("ratelimit.quota must not be negative"))
+	}
+	if r.Quota > 0 && r.QuotaWindow <= 0 {
+		errs = append(errs, errors.New("ratelimit.quota_window must be positive"))
+	}
+	return errs
+}
+
+// minHS256SecretLength is the key size RFC 7518 requires for HS256.
+const minHS256SecretLength = 32
+
+func (a *Auth) validate() []error {
+	if !a.Enabled {
+		return nil
+	}
+
+	var errs []error
+	if a.HS256Secret != "" && len(a.HS256Secret) < minHS256SecretLength {
+		errs = append(errs, fmt.Errorf("auth.hs256_secret must be at least %d bytes long", minHS256SecretLength))
+	}
+	if a.JWKSFile != "" && a.JWKSURL != "" {
+		errs = append(errs, errors.New("auth.jwks_file and auth.jwks_url cannot both be set"))
+	}
+	if a.JWKSURL != "" && a.JWKSRefresh <= 0 {
+		errs = append(errs, errors.New("auth.jwks_refresh must be positive"))
+	}
+	if a.Leeway < 0 {
+		errs = append(errs, errors.New("auth.leeway must not be negative"))
+	}
+	for role, granted := range a.Roles {
+		for _, permission := range granted {
+			if !slices.Contains(permissions, permission) {
+				errs = append(errs, fmt.Errorf("auth.roles: role %s grants unknown permission %q, use %s", role, permission, strings.Join(permissions, ", ")))
+			}
+		}
+	}
+	return errs
+}
+
+func (l *Log) validate() []error {
+	var errs []error
+
+	switch l.Level {
+	case "trace", "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log.level must be trace, debug, info, warn or error, got %q", l.Level))
+	}
+	switch l.Format {
+	case "json", "console":
+	default:
+		errs = append(errs, fmt.Errorf("log.format must be json or console, got %q", l.Format))
+	}
+
+	if len(l.Sinks) == 0 {
+		errs = append(errs, errors.New("log.sinks must list at least one sink"))
+	}
+	seen := make(map[string]bool, len(l.Sinks))
+	for _, sink := range l.Sinks {
+		if seen[sink] {
+			errs = append(errs, fmt.Errorf("log.sinks lists %s twice", sink))
+		}
+		seen[sink] = true
+
+		switch sink {
+		case "file":
+			if l.File == "" {
+				errs = append(errs, errors.New("log.file is required when log.sinks contains file"))
+			}
+			if l.MaxSize < 1 {
+				errs = append(errs, errors.New("log.max_size must be at least 1"))
+			}
+			if l.MaxBackups < 0 {
+				errs = append(errs, errors.New("log.max_backups must not be negative"))
+			}
+			if l.MaxAge < 0 {
+				errs = append(errs, errors.New("log.max_age must not be negative"))
+			}
+			if l.RotateInterval < 0 {
+				errs = append(errs, errors.New("log.rotate_interval must not be negative"))
+			}
+		case "loki":
+			if l.LokiURL == "" {
+				errs = append(errs, errors.New("log.loki_url is required when log.sinks contains loki"))
+			}
+		case "stdout", "syslog":
+		default:
+			errs = append(errs, fmt.Errorf("log.sinks: unknown sink %q, use stdout, file, syslog or loki", sink))
+		}
+	}
+	return errs
+}
+
+// Redacted returns every setting as a key/value map suitable for logging.
+// Secrets are replaced by a placeholder.
+func (c *Config) Redacted() map[string]string {
+	values := make(map[string]string)
+	for _, s := range c.settings() {
+		v := s.value.String()
+		if s.secret && v != "" {
+			v = "[REDACTED]"
+		}
+		values[s.key] = v
+	}
+	return values
+}
+
+// String lists the settings of c one per line, with secrets redacted.
+func (c *Config) String() string {
+	values := c.Redacted()
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s = %s\n", k, values[k])
+	}
+	return b.String()
+}
+
+// readFile reads a YAML or TOML file, picked by its extension, into a flat
+// map keyed by the dotted path of each value.
+func readFile(path string) (map[string]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	var doc map[string]any
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(b, &doc)
+	case ".toml":
+		err = toml.Unmarshal(b, &doc)
+	default:
+		return nil, fmt.Errorf("config file %s: unsupported format %q, use .yaml, .yml or .toml", path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	flatten("", doc, values)
+	return values, nil
+}
+
+// flatten walks a decoded document and stores every leaf under its dotted
+// key. Lists are joined with commas.
+func flatten(prefix string, node any, values map[string]string) {
+	switch node := node.(type) {
+	case map[string]any:
+		for k, v := range node {
+			key := k
+			if prefix != "" {
+				key = prefix + "." + k
+			}
+			flatten(key, v, values)
+		}
+	case []any:
+		items := make([]string, len(node))
+		for i, item := range node {
+			items[i] = fmt.Sprint(item)
+		}
+		values[prefix] = strings.Join(items, ",")
+	case nil:
+		values[prefix] = ""
+	default:
+		values[prefix] = fmt.Sprint(node)
+	}
+}
+
+// apply sets the settings found in values. Unknown keys are an error so that
+// typos do not go unnoticed.
+func apply(settings []setting, values map[string]string, source string) error {
+	known := make(map[string]setting, len(settings))
+	for _, s := range settings {
+		known[s.key] = s
+	}
+
+	var errs []error
+	for k, v := range values {
+		s, ok := known[k]
+		if !ok {
+			if err := applyEntry(settings, k, v); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", source, err))
+			}
+			continue
+		}
+		if err := s.value.Set(v); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %s: %w", source, k, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// applyEntry sets an entry of a setting taking entries, e.g. the viewer
+// entry of auth.roles for the key auth.roles.viewer.
+func applyEntry(settings []setting, key, v string) error {
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		for _, s := range settings {
+			if setter, ok := s.value.(entrySetter); ok && s.key == key[:i] {
+				if err := setter.SetEntry(key[i+1:], v); err != nil {
+					return fmt.Errorf("%s: %w", key, err)
+				}
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("unknown setting %q", key)
+}
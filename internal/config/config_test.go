@@ -0,0 +1,282 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// clearEnv unsets every environment variable Load reads for the duration of
+// the test.
+func clearEnv(t *testing.T) {
+	t.Helper()
+	envs := []string{"CONFIG_FILE"}
+	for _, s := range Default().settings() {
+		envs = append(envs, s.env)
+	}
+	for _, env := range envs {
+		// Setenv restores the variable when the test ends
+		t.Setenv(env, "")
+		os.Unsetenv(env)
+	}
+}
+
+// testSecret is long enough to verify HS256 tokens, which the default
+// configuration needs to be valid.
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+// writeFile writes content to a file called name in a temporary directory
+// and returns its path.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": "storage: memory\nserver:\n  addr: \":1\"\nlog:\n  file: file.log\n  sinks: [file, loki]\n  compress: false\nswagger:\n  host: file:8080\n",
+		"config.toml": "storage = \"memory\"\n[server]\naddr = \":1\"\n[log]\nfile = \"file.log\"\nsinks = [\"file\", \"loki\"]\ncompress = false\n[swagger]\nhost = \"file:8080\"\n",
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			clearEnv(t)
+			t.Setenv("AUTH_HS256_SECRET", testSecret)
+			path := writeFile(t, name, content)
+			t.Setenv("CONFIG_FILE", path)
+			t.Setenv("HTTP_ADDR", ":2")
+			t.Setenv("LOG_FILE", "env.log")
+			t.Setenv("HTTP_SHUTDOWN_TIMEOUT", "5s")
+
+			cfg, args, err := Load([]string{"-server.addr", ":3", "serve"})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			checks := []struct{ key, got, want string }{
+				{"database.name", cfg.Database.Name, "company"},
+				{"storage", cfg.Storage, "memory"},
+				{"swagger.host", cfg.Swagger.Host, "file:8080"},
+				{"log.file", cfg.Log.File, "env.log"},
+				{"server.addr", cfg.Server.Addr, ":3"},
+				{"server.read_timeout", cfg.Server.ReadTimeout.String(), "15s"},
+				{"server.shutdown_timeout", cfg.Server.ShutdownTimeout.String(), "5s"},
+				{"log.sinks", strings.Join(cfg.Log.Sinks, ","), "file,loki"},
+				{"log.compress", strconv.FormatBool(cfg.Log.Compress), "false"},
+			}
+			for _, c := range checks {
+				if c.got != c.want {
+					t.Errorf("%s = %q, want %q", c.key, c.got, c.want)
+				}
+			}
+			if !slices.Equal(args, []string{"serve"}) {
+				t.Errorf("args = %q, want [serve]", args)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFlag(t *testing.T) {
+	clearEnv(t)
+	t.Setenv("AUTH_HS256_SECRET", testSecret)
+	t.Setenv("CONFIG_FILE", writeFile(t, "env.yaml", "storage: unknown\n"))
+	path := writeFile(t, "flag.yml", "storage: memory\n")
+
+	cfg, _, err := Load([]string{"-config", path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Storage != "memory" {
+		t.Errorf("storage = %q, want the one from the -config file", cfg.Storage)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		body string
+		args []string
+		want string
+	}{
+		{name: "unknown key", file: "config.yaml", body: "storage: memory\nserver:\n  port: 80\n", want: `unknown setting "server.port"`},
+		{name: "unsupported format", file: "config.json", body: "{}", want: "unsupported format"},
+		{name: "malformed file", file: "config.toml", body: "storage = ", want: "parsing config file"},
+		{name: "missing file", args: []string{"-config", "missing.yaml"}, want: "reading config file"},
+		{name: "invalid duration", args: []string{"-storage", "memory", "-server.read_timeout", "soon"}, want: "flag -server.read_timeout"},
+		{name: "unknown flag", args: []string{"-port", "80"}, want: "-port"},
+		{name: "invalid result", args: []string{"-storage", "memory", "-log.file", ""}, want: "log.file is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			args := tt.args
+			if tt.file != "" {
+				args = append([]string{"-config", writeFile(t, tt.file, tt.body)}, args...)
+			}
+
+			_, _, err := Load(args)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load() error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		want   []string
+	}{
+		{name: "memory", modify: func(c *Config) { c.Storage = "memory" }},
+		{name: "mysql", modify: func(c *Config) { c.Database.User = "firman" }},
+		{
+			name:   "mysql without user",
+			modify: func(c *Config) {},
+			want:   []string{"database.user is required"},
+		},
+		{
+			name: "every error at once",
+			modify: func(c *Config) {
+				c.Database.Host = ""
+				c.Database.Name = ""
+				c.Server.Addr = ""
+				c.Server.ShutdownTimeout = 0
+				c.Log.File = ""
+			},
+			want: []string{"database.user", "database.host", "database.name", "server.addr", "server.shutdown_timeout must be positive", "log.file"},
+		},
+		{
+			name: "otlp without endpoint",
+			modify: func(c *Config) {
+				c.Storage = "memory"
+				c.Tracing.Exporter = "otlp"
+				c.Tracing.Endpoint = ""
+			},
+			want: []string{"tracing.endpoint is required"},
+		},
+		{
+			name: "unknown exporter",
+			modify: func(c *Config) {
+				c.Storage = "memory"
+				c.Tracing.Exporter = "jaeger"
+				c.Tracing.SampleRatio = 1.5
+			},
+			want: []string{`tracing.exporter must be none, stdout or otlp, got "jaeger"`, "tracing.sample_ratio must be between 0 and 1"},
+		},
+		{
+			name: "invalid log settings",
+			modify: func(c *Config) {
+				c.Storage = "memory"
+				c.Log.Level = "loud"
+				c.Log.Sinks = []string{"file", "loki", "file", "kafka"}
+				c.Log.MaxSize = 0
+				c.Log.LokiURL = ""
+			},
+			want: []string{
+				`log.level must be trace, debug, info, warn or error, got "loud"`,
+				"log.max_size must be at least 1",
+				"log.loki_url is required",
+				"log.sinks lists file twice",
+				`unknown sink "kafka"`,
+			},
+		},
+		{
+			name:   "unknown storage",
+			modify: func(c *Config) { c.Storage = "postgres" },
+			want:   []string{`storage must be mysql or memory, got "postgres"`},
+		},
+		{
+			name: "auth without keys",
+			modify: func(c *Config) {
+				c.Storage = "memory"
+				c.Auth.HS256Secret = ""
+			},
+			want: []string{"auth.hs256_secret, auth.jwks_file or auth.jwks_url is required", "set auth.enabled to false"},
+		},
+		{
+			name: "auth with API keys only",
+			modify: func(c *Config) {
+				c.Database.User = "firman"
+				c.Auth.HS256Secret = ""
+			},
+		},
+		{
+			name: "auth disabled without keys",
+			modify: func(c *Config) {
+				c.Storage = "memory"
+				c.Auth.Enabled = false
+				c.Auth.HS256Secret = ""
+			},
+		},
+		{
+			name: "invalid auth settings",
+			modify: func(c *Config) {
+				c.Storage = "memory"
+				c.Auth.HS256Secret = "short"
+				c.Auth.JWKSFile = "keys.json"
+				c.Auth.JWKSURL = "https://example.com/keys.json"
+				c.Auth.JWKSRefresh = 0
+				c.Auth.Leeway = -time.Second
+			},
+			want: []string{
+				"auth.hs256_secret must be at least 32 bytes long",
+				"auth.jwks_file and auth.jwks_url cannot both be set",
+				"auth.jwks_refresh must be positive",
+				"auth.leeway must not be negative",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Default()
+			cfg.Auth.HS256Secret = testSecret
+			tt.modify(cfg)
+
+			err := cfg.Validate()
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Errorf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate() = nil, want %q", tt.want)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() = %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := Default()
+	cfg.Database.Password = "hunter2"
+
+	values := cfg.Redacted()
+	if values["database.password"] != "[REDACTED]" {
+		t.Errorf("database.password = %q, want it redacted", values["database.password"])
+	}
+	if values["database.host"] != "localhost:3306" {
+		t.Errorf("database.host = %q, want localhost:3306", values["database.host"])
+	}
+	if strings.Contains(cfg.String(), "hunter2") {
+		t.Errorf("String() leaks the password:\n%s", cfg)
+	}
+
+	cfg.Database.Password = ""
+	if values := cfg.Redacted(); values["database.password"] != "" {
+		t.Errorf("empty database.password = %q, want it left empty", values["database.password"])
+	}
+}
@@ -0,0 +1,166 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setting is a single configurable field of Config.
+type setting struct {
+	key    string
+	env    string
+	usage  string
+	secret bool
+	value  value
+}
+
+// value parses a setting from its textual form and prints it back.
+type value interface {
+	Set(string) error
+	String() string
+}
+
+type stringValue string
+
+func (v *stringValue) Set(s string) error {
+	*v = stringValue(s)
+	return nil
+}
+
+func (v *stringValue) String() string {
+	return string(*v)
+}
+
+// stringsValue is a comma separated list.
+type stringsValue []string
+
+func (v *stringsValue) Set(s string) error {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*v = items
+	return nil
+}
+
+func (v *stringsValue) String() string {
+	return strings.Join(*v, ",")
+}
+
+type boolValue bool
+
+func (v *boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	*v = boolValue(b)
+	return nil
+}
+
+func (v *boolValue) String() string {
+	return strconv.FormatBool(bool(*v))
+}
+
+type intValue int
+
+func (v *intValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+	*v = intValue(n)
+	return nil
+}
+
+func (v *intValue) String() string {
+	return strconv.Itoa(int(*v))
+}
+
+type floatValue float64
+
+func (v *floatValue) Set(s string) error {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return err
+	}
+	*v = floatValue(f)
+	return nil
+}
+
+func (v *floatValue) String() string {
+	return strconv.FormatFloat(float64(*v), 'g', -1, 64)
+}
+
+type durationValue time.Duration
+
+func (v *durationValue) Set(s string) error {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*v = durationValue(d)
+	return nil
+}
+
+func (v *durationValue) String() string {
+	return time.Duration(*v).String()
+}
+
+// listsValue maps names to lists, written as "a=x,y;b=z". In configuration
+// files every name is a key of its own holding a list, see entrySetter.
+type listsValue map[string][]string
+
+func (v *listsValue) Set(s string) error {
+	lists := make(map[string][]string)
+	for _, entry := range strings.Split(s, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		name, items, ok := strings.Cut(entry, "=")
+		if !ok {
+			return fmt.Errorf("%q is not of the form name=item,item", entry)
+		}
+		var list stringsValue
+		list.Set(items)
+		lists[strings.TrimSpace(name)] = list
+	}
+	*v = lists
+	return nil
+}
+
+// SetEntry replaces the list of a single name, keeping the others.
+func (v *listsValue) SetEntry(name, s string) error {
+	var list stringsValue
+	list.Set(s)
+	if *v == nil {
+		*v = make(map[string][]string)
+	}
+	(*v)[name] = list
+	return nil
+}
+
+func (v *listsValue) String() string {
+	names := make([]string, 0, len(*v))
+	for name := range *v {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	entries := make([]string, len(names))
+	for i, name := range names {
+		entries[i] = name + "=" + strings.Join((*v)[name], ",")
+	}
+	return strings.Join(entries, ";")
+}
+
+// entrySetter is implemented by the values whose entries are set one by one
+// from the keys below the key of the setting in configuration files.
+type entrySetter interface {
+	SetEntry(name, s string) error
+}
@@ -0,0 +1,79 @@
+package logging
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+
+	"go-example-api/internal/config"
+)
+
+// rotatingFile is a log file rotated once it reaches its maximum size and,
+// when an interval is configured, periodically.
+type rotatingFile struct {
+	*lumberjack.Logger
+
+	stop chan struct{}
+	done sync.WaitGroup
+}
+
+// openFile opens the log file of cfg. The file is opened once up front so
+// that a missing permission is reported at startup rather than on the first
+// log line.
+func openFile(cfg config.Log) (*rotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(cfg.File), 0o755); err != nil {
+		return nil, fmt.Errorf("creating log directory: %w", err)
+	}
+	f, err := os.OpenFile(cfg.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("opening log file: %w", err)
+	}
+	f.Close()
+
+	file := &rotatingFile{
+		Logger: &lumberjack.Logger{
+			Filename:   cfg.File,
+			MaxSize:    cfg.MaxSize,
+			MaxBackups: cfg.MaxBackups,
+			// lumberjack counts in days, round up so that a positive age
+			// never means keeping the files forever
+			MaxAge:   int(math.Ceil(cfg.MaxAge.Hours() / 24)),
+			Compress: cfg.Compress,
+		},
+		stop: make(chan struct{}),
+	}
+
+	if cfg.RotateInterval > 0 {
+		file.done.Add(1)
+		go file.rotateEvery(cfg.RotateInterval)
+	}
+	return file, nil
+}
+
+func (f *rotatingFile) rotateEvery(interval time.Duration) {
+	defer f.done.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := f.Rotate(); err != nil {
+				fmt.Fprintln(os.Stderr, "rotating log file:", err)
+			}
+		case <-f.stop:
+			return
+		}
+	}
+}
+
+func (f *rotatingFile) Close() error {
+	close(f.stop)
+	f.done.Wait()
+	return f.Logger.Close()
+}
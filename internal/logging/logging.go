@@ -0,0 +1,84 @@
+// Package logging builds the logger of the API server from its
+// configuration.
+//
+// Log lines can be written to any combination of sinks: stdout, a rotated
+// file, syslog and a Loki server.
+package logging
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"go-example-api/internal/config"
+)
+
+// New returns a logger writing to the sinks of cfg. It fails when a sink
+// cannot be opened, e.g. when the log file is not writable. Closing the
+// returned io.Closer flushes and closes every sink.
+func New(cfg config.Log) (zerolog.Logger, io.Closer, error) {
+	level, err := zerolog.ParseLevel(cfg.Level)
+	if err != nil {
+		return zerolog.Logger{}, nil, err
+	}
+
+	var writers []io.Writer
+	var closers closers
+	for _, sink := range cfg.Sinks {
+		var w io.Writer
+		var c io.Closer
+		switch sink {
+		case "stdout":
+			w = format(os.Stdout, cfg.Format, false)
+		case "file":
+			file, err := openFile(cfg)
+			if err != nil {
+				closers.Close()
+				return zerolog.Logger{}, nil, err
+			}
+			w, c = format(file, cfg.Format, true), file
+		case "syslog":
+			w, c, err = openSyslog(cfg)
+			if err != nil {
+				closers.Close()
+				return zerolog.Logger{}, nil, fmt.Errorf("connecting to syslog: %w", err)
+			}
+		case "loki":
+			loki := newLokiWriter(cfg.LokiURL, map[string]string{"job": lokiJob})
+			w, c = loki, loki
+		default:
+			closers.Close()
+			return zerolog.Logger{}, nil, fmt.Errorf("unknown log sink %q", sink)
+		}
+		writers = append(writers, w)
+		if c != nil {
+			closers = append(closers, c)
+		}
+	}
+
+	logger := zerolog.New(zerolog.MultiLevelWriter(writers...)).Level(level).With().Timestamp().Logger()
+	return logger, closers, nil
+}
+
+// format wraps w to write human readable lines when the format is console.
+func format(w io.Writer, format string, noColor bool) io.Writer {
+	if format != "console" {
+		return w
+	}
+	return zerolog.ConsoleWriter{Out: w, NoColor: noColor, TimeFormat: time.RFC3339}
+}
+
+// closers closes every sink, the last opened first.
+type closers []io.Closer
+
+func (cs closers) Close() error {
+	var errs []error
+	for i := len(cs) - 1; i >= 0; i-- {
+		errs = append(errs, cs[i].Close())
+	}
+	return errors.Join(errs...)
+}
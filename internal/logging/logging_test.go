@@ -0,0 +1,177 @@
+package logging
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"go-example-api/internal/config"
+)
+
+// fileConfig returns a configuration logging to a file in a temporary
+// directory.
+func fileConfig(t *testing.T) config.Log {
+	t.Helper()
+	cfg := config.Default().Log
+	cfg.Sinks = []string{"file"}
+	cfg.File = filepath.Join(t.TempDir(), "logs", "app.log")
+	cfg.RotateInterval = 0
+	return cfg
+}
+
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSpace(string(b)), "\n")
+}
+
+func TestNewFile(t *testing.T) {
+	tests := []struct {
+		format string
+		check  func(t *testing.T, line string)
+	}{
+		{format: "json", check: func(t *testing.T, line string) {
+			var fields map[string]any
+			if err := json.Unmarshal([]byte(line), &fields); err != nil {
+				t.Fatalf("line %q is not JSON: %v", line, err)
+			}
+			if fields["level"] != "warn" || fields["message"] != "disk almost full" || fields["time"] == nil {
+				t.Errorf("line = %v", fields)
+			}
+		}},
+		{format: "console", check: func(t *testing.T, line string) {
+			if !strings.Contains(line, "WRN disk almost full") || strings.Contains(line, "\x1b[") {
+				t.Errorf("line = %q, want an uncoloured console line", line)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			cfg := fileConfig(t)
+			cfg.Level = "warn"
+			cfg.Format = tt.format
+
+			logger, closer, err := New(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			logger.Info().Msg("filtered out")
+			logger.Warn().Msg("disk almost full")
+			if err := closer.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			lines := readLines(t, cfg.File)
+			if len(lines) != 1 {
+				t.Fatalf("logged %q, want only the warning", lines)
+			}
+			tt.check(t, lines[0])
+		})
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(cfg *config.Log)
+		want   string
+	}{
+		{name: "invalid level", modify: func(cfg *config.Log) { cfg.Level = "loud" }, want: "loud"},
+		{name: "unknown sink", modify: func(cfg *config.Log) { cfg.Sinks = []string{"file", "kafka"} }, want: `unknown log sink "kafka"`},
+		{name: "unwritable file", modify: func(cfg *config.Log) { cfg.File = filepath.Dir(cfg.File) }, want: "opening log file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := fileConfig(t)
+			if err := os.MkdirAll(filepath.Dir(cfg.File), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			tt.modify(&cfg)
+
+			_, _, err := New(cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("New() error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	cfg := fileConfig(t)
+	file, err := openFile(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Write([]byte("before\n"))
+	if err := file.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	file.Write([]byte("after\n"))
+	if err := file.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if lines := readLines(t, cfg.File); len(lines) != 1 || lines[0] != "after" {
+		t.Errorf("current file = %q, want only the line written after rotating", lines)
+	}
+	matches, err := filepath.Glob(filepath.Join(filepath.Dir(cfg.File), "app-*.log*"))
+	if err != nil || len(matches) != 1 {
+		t.Errorf("rotated files = %q, %v, want one", matches, err)
+	}
+}
+
+func TestLokiWriter(t *testing.T) {
+	var mu sync.Mutex
+	var pushed []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/loki/api/v1/push" {
+			t.Errorf("pushed to %s", r.URL.Path)
+		}
+		var body struct {
+			Streams []struct {
+				Stream map[string]string `json:"stream"`
+				Values [][2]string       `json:"values"`
+			} `json:"streams"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("decoding push: %v", err)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		for _, stream := range body.Streams {
+			if stream.Stream["job"] != lokiJob {
+				t.Errorf("stream labels = %v", stream.Stream)
+			}
+			for _, value := range stream.Values {
+				pushed = append(pushed, value[1])
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	w := newLokiWriter(server.URL+"/", map[string]string{"job": lokiJob})
+	w.Write([]byte(`{"message":"one"}` + "\n"))
+	w.Write([]byte(`{"message":"two"}` + "\n"))
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Join(pushed, ",") != `{"message":"one"},{"message":"two"}` {
+		t.Errorf("pushed %q, want both lines without their newline", pushed)
+	}
+	if _, err := w.Write([]byte("late\n")); err == nil {
+		t.Error("Write after Close succeeded")
+	}
+}
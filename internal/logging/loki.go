@@ -0,0 +1,140 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// lokiJob is the job label of the pushed streams.
+	lokiJob = "go-example-api"
+	// lokiBatchSize and lokiBatchInterval bound how many lines are pushed at
+	// once and how long a line waits before being pushed.
+	lokiBatchSize     = 500
+	lokiBatchInterval = time.Second
+	// lokiBufferSize is the number of lines waiting to be pushed after which
+	// new lines are dropped rather than slowing requests down.
+	lokiBufferSize = 10000
+)
+
+type lokiEntry struct {
+	time time.Time
+	line string
+}
+
+// lokiWriter pushes log lines to the push API of a Loki server in batches,
+// from a background goroutine.
+type lokiWriter struct {
+	url    string
+	labels map[string]string
+	client *http.Client
+
+	mu      sync.RWMutex
+	closed  bool
+	entries chan lokiEntry
+	done    chan struct{}
+}
+
+func newLokiWriter(baseURL string, labels map[string]string) *lokiWriter {
+	w := &lokiWriter{
+		url:     strings.TrimSuffix(baseURL, "/") + "/loki/api/v1/push",
+		labels:  labels,
+		client:  &http.Client{Timeout: 5 * time.Second},
+		entries: make(chan lokiEntry, lokiBufferSize),
+		done:    make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+func (w *lokiWriter) Write(p []byte) (int, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+
+	// zerolog reuses p once Write returns, hence the copy
+	select {
+	case w.entries <- lokiEntry{time: time.Now(), line: string(bytes.TrimSuffix(p, []byte("\n")))}:
+	default:
+	}
+	return len(p), nil
+}
+
+// Close pushes the pending lines and stops the background goroutine.
+func (w *lokiWriter) Close() error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.entries)
+	}
+	w.mu.Unlock()
+
+	<-w.done
+	return nil
+}
+
+func (w *lokiWriter) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(lokiBatchInterval)
+	defer ticker.Stop()
+
+	var batch []lokiEntry
+	for {
+		select {
+		case entry, ok := <-w.entries:
+			if !ok {
+				w.push(batch)
+				return
+			}
+			batch = append(batch, entry)
+			if len(batch) < lokiBatchSize {
+				continue
+			}
+		case <-ticker.C:
+		}
+		w.push(batch)
+		batch = batch[:0]
+	}
+}
+
+// push sends batch to Loki. Failures are reported on stderr since the
+// logger itself may be the one failing.
+func (w *lokiWriter) push(batch []lokiEntry) {
+	if len(batch) == 0 {
+		return
+	}
+
+	values := make([][2]string, len(batch))
+	for i, entry := range batch {
+		values[i] = [2]string{strconv.FormatInt(entry.time.UnixNano(), 10), entry.line}
+	}
+	body, err := json.Marshal(map[string]any{
+		"streams": []any{
+			map[string]any{"stream": w.labels, "values": values},
+		},
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "encoding logs for Loki:", err)
+		return
+	}
+
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "pushing logs to Loki:", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		fmt.Fprintln(os.Stderr, "pushing logs to Loki:", resp.Status)
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !windows && !plan9
+
+package logging
+
+import (
+	"io"
+	"log/syslog"
+
+	"github.com/rs/zerolog"
+
+	"go-example-api/internal/config"
+)
+
+// openSyslog connects to the syslog daemon of cfg. Every line is sent with
+// the syslog severity matching its level.
+func openSyslog(cfg config.Log) (io.Writer, io.Closer, error) {
+	w, err := syslog.Dial(cfg.SyslogNetwork, cfg.SyslogAddress, syslog.LOG_INFO|syslog.LOG_DAEMON, cfg.SyslogTag)
+	if err != nil {
+		return nil, nil, err
+	}
+	return zerolog.SyslogLevelWriter(w), w, nil
+}
@@ -0,0 +1,14 @@
+//go:build windows || plan9
+
+package logging
+
+import (
+	"errors"
+	"io"
+
+	"go-example-api/internal/config"
+)
+
+func openSyslog(config.Log) (io.Writer, io.Closer, error) {
+	return nil, nil, errors.New("syslog is not supported on this platform")
+}
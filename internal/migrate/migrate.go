@@ -0,0 +1,383 @@
+// Package migrate applies versioned SQL migrations to a MySQL database.
+//
+// Applied migrations are recorded in the schema_migrations table. A named
+// lock is held while migrating so that replicas starting at the same time do
+// not apply the same migration twice.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migration is a change of the schema along with the statements undoing it.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// Status is a migration along with whether it has been applied.
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt string
+	// Dirty is set when the migration failed halfway and the schema needs
+	// to be fixed by hand.
+	Dirty bool
+}
+
+// Step is a migration applied or rolled back by Goto.
+type Step struct {
+	Migration
+	Up bool
+}
+
+func (s Step) String() string {
+	if s.Up {
+		return "applied " + s.Migration.String()
+	}
+	return "rolled back " + s.Migration.String()
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Load reads the migrations of fsys, sorted by version. Every version must
+// have both an up and a down file.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, _ := strconv.Atoi(match[1])
+		b, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(b)
+		} else {
+			m.Down = string(b)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Version == 0 {
+			return nil, fmt.Errorf("migration %s: versions start at 1", m)
+		}
+		if strings.TrimSpace(m.Up) == "" || strings.TrimSpace(m.Down) == "" {
+			return nil, fmt.Errorf("migration %s needs both an up and a down file", m)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrator applies migrations to a database.
+type Migrator struct {
+	db          *sql.DB
+	migrations  []Migration
+	lockTimeout time.Duration
+}
+
+// New returns a Migrator applying the migrations found in fsys to db.
+func New(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations, lockTimeout: time.Minute}, nil
+}
+
+// Latest returns the version of the newest migration.
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Status lists every migration and whether it has been applied.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := createTable(ctx, conn); err != nil {
+		return nil, err
+	}
+	return m.status(ctx, conn)
+}
+
+// Check fails unless every migration has been applied cleanly. It does not
+// create the schema_migrations table, so it is safe to use for readiness.
+func (m *Migrator) Check(ctx context.Context) error {
+	applied := make(map[int]bool)
+	rows, err := m.db.QueryContext(ctx, "SELECT version, dirty FROM schema_migrations")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version int
+		var dirty bool
+		if err := rows.Scan(&version, &dirty); err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("migration %d is dirty", version)
+		}
+		applied[version] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var pending []string
+	for _, migration := range m.migrations {
+		if !applied[migration.Version] {
+			pending = append(pending, migration.String())
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("pending migrations: %s", strings.Join(pending, ", "))
+	}
+	return nil
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up(ctx context.Context) ([]Step, error) {
+	return m.Goto(ctx, m.Latest())
+}
+
+// Down rolls back the newest applied migration.
+func (m *Migrator) Down(ctx context.Context) ([]Step, error) {
+	var steps []Step
+	err := m.locked(ctx, func(conn *sql.Conn, statuses []Status) error {
+		for i := len(statuses) - 1; i >= 0; i-- {
+			if statuses[i].Applied {
+				step, err := m.rollBack(ctx, conn, statuses[i].Migration)
+				if err != nil {
+					return err
+				}
+				steps = append(steps, step)
+				return nil
+			}
+		}
+		return nil
+	})
+	return steps, err
+}
+
+// Goto applies the pending migrations up to version and rolls back the
+// applied ones above it. Version 0 rolls back every migration.
+func (m *Migrator) Goto(ctx context.Context, version int) ([]Step, error) {
+	if version != 0 && !m.exists(version) {
+		return nil, fmt.Errorf("no migration with version %d", version)
+	}
+
+	var steps []Step
+	err := m.locked(ctx, func(conn *sql.Conn, statuses []Status) error {
+		for _, s := range statuses {
+			if s.Version <= version && !s.Applied {
+				step, err := m.apply(ctx, conn, s.Migration)
+				if err != nil {
+					return err
+				}
+				steps = append(steps, step)
+			}
+		}
+		for i := len(statuses) - 1; i >= 0; i-- {
+			if s := statuses[i]; s.Version > version && s.Applied {
+				step, err := m.rollBack(ctx, conn, s.Migration)
+				if err != nil {
+					return err
+				}
+				steps = append(steps, step)
+			}
+		}
+		return nil
+	})
+	return steps, err
+}
+
+func (m *Migrator) exists(version int) bool {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// locked runs fn on a single connection holding the migration lock. It
+// refuses to run while a migration is dirty.
+func (m *Migrator) locked(ctx context.Context, fn func(*sql.Conn, []Status) error) error {
+	// Named locks belong to a connection, so everything runs on the same one
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var acquired sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(CONCAT(DATABASE(), '.schema_migrations'), ?)", int(m.lockTimeout.Seconds())).Scan(&acquired); err != nil {
+		return fmt.Errorf("acquiring migration lock: %w", err)
+	}
+	if acquired.Int64 != 1 {
+		return fmt.Errorf("another process held the migration lock for more than %s", m.lockTimeout)
+	}
+	defer conn.ExecContext(context.WithoutCancel(ctx), "SELECT RELEASE_LOCK(CONCAT(DATABASE(), '.schema_migrations'))")
+
+	if err := createTable(ctx, conn); err != nil {
+		return err
+	}
+	statuses, err := m.status(ctx, conn)
+	if err != nil {
+		return err
+	}
+	for _, s := range statuses {
+		if s.Dirty {
+			return fmt.Errorf("migration %s failed halfway, fix the schema by hand then delete its row from schema_migrations", s.Migration)
+		}
+	}
+	return fn(conn, statuses)
+}
+
+func createTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+  version bigint NOT NULL,
+  name varchar(255) NOT NULL,
+  dirty tinyint(1) NOT NULL DEFAULT 0,
+  applied_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (version)
+)`)
+	return err
+}
+
+func (m *Migrator) status(ctx context.Context, conn *sql.Conn) ([]Status, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, dirty, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type applied struct {
+		dirty bool
+		at    string
+	}
+	recorded := make(map[int]applied)
+	for rows.Next() {
+		var version int
+		var a applied
+		if err := rows.Scan(&version, &a.dirty, &a.at); err != nil {
+			return nil, err
+		}
+		recorded[version] = a
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, len(m.migrations))
+	for i, migration := range m.migrations {
+		a, ok := recorded[migration.Version]
+		statuses[i] = Status{Migration: migration, Applied: ok, AppliedAt: a.at, Dirty: a.dirty}
+	}
+	return statuses, nil
+}
+
+// apply runs the up statements of migration. MySQL commits DDL statements
+// implicitly, so the migration is recorded as dirty first and only marked
+// clean once every statement succeeded.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration) (Step, error) {
+	if _, err := conn.ExecContext(ctx, "INSERT INTO schema_migrations (version, name, dirty) VALUES (?, ?, 1)", migration.Version, migration.Name); err != nil {
+		return Step{}, err
+	}
+	if err := execScript(ctx, conn, migration.Up); err != nil {
+		return Step{}, fmt.Errorf("applying migration %s: %w", migration, err)
+	}
+	if _, err := conn.ExecContext(ctx, "UPDATE schema_migrations SET dirty = 0 WHERE version = ?", migration.Version); err != nil {
+		return Step{}, err
+	}
+	return Step{Migration: migration, Up: true}, nil
+}
+
+// rollBack runs the down statements of migration, marking it dirty until
+// they all succeeded.
+func (m *Migrator) rollBack(ctx context.Context, conn *sql.Conn, migration Migration) (Step, error) {
+	if _, err := conn.ExecContext(ctx, "UPDATE schema_migrations SET dirty = 1 WHERE version = ?", migration.Version); err != nil {
+		return Step{}, err
+	}
+	if err := execScript(ctx, conn, migration.Down); err != nil {
+		return Step{}, fmt.Errorf("rolling back migration %s: %w", migration, err)
+	}
+	if _, err := conn.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = ?", migration.Version); err != nil {
+		return Step{}, err
+	}
+	return Step{Migration: migration, Up: false}, nil
+}
+
+// execScript runs the statements of script one by one.
+func execScript(ctx context.Context, conn *sql.Conn, script string) error {
+	for _, statement := range splitStatements(script) {
+		if _, err := conn.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitStatements splits script on the semicolons ending a line and drops
+// the comment lines.
+func splitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSuffix(strings.TrimSpace(current.String()), ";"))
+			current.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
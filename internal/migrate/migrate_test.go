@@ -0,0 +1,275 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+
+	"go-example-api/db/migrations"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{name: "empty", script: "\n  \n", want: nil},
+		{
+			name:   "one per line",
+			script: "CREATE TABLE a (id int);\nDROP TABLE b;\n",
+			want:   []string{"CREATE TABLE a (id int)", "DROP TABLE b"},
+		},
+		{
+			name:   "multi-line statement",
+			script: "CREATE TABLE a (\n  id int,\n  name varchar(10)\n);\n",
+			want:   []string{"CREATE TABLE a (\n  id int,\n  name varchar(10)\n)"},
+		},
+		{
+			name:   "comments and blank lines",
+			script: "-- create a\nCREATE TABLE a (id int);\n\n  -- then b\nCREATE TABLE b (id int);",
+			want:   []string{"CREATE TABLE a (id int)", "CREATE TABLE b (id int)"},
+		},
+		{
+			name:   "semicolon inside a line",
+			script: "UPDATE a SET note = 'x;y' WHERE id = 1;\n",
+			want:   []string{"UPDATE a SET note = 'x;y' WHERE id = 1"},
+		},
+		{
+			name:   "missing final semicolon",
+			script: "DROP TABLE a;\nDROP TABLE b",
+			want:   []string{"DROP TABLE a", "DROP TABLE b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitStatements(tt.script); !slices.Equal(got, tt.want) {
+				t.Errorf("splitStatements() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	file := func(s string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(s)} }
+
+	t.Run("sorted by version", func(t *testing.T) {
+		migrations, err := Load(fstest.MapFS{
+			"0010_tenth.up.sql":    file("CREATE TABLE c (id int);"),
+			"0010_tenth.down.sql":  file("DROP TABLE c;"),
+			"0002_second.up.sql":   file("CREATE TABLE b (id int);"),
+			"0002_second.down.sql": file("DROP TABLE b;"),
+			"README.md":            file("not a migration"),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, m := range migrations {
+			names = append(names, m.String())
+		}
+		if !slices.Equal(names, []string{"0002_second", "0010_tenth"}) {
+			t.Errorf("migrations = %q", names)
+		}
+	})
+
+	errorTests := []struct {
+		name  string
+		files fstest.MapFS
+		want  string
+	}{
+		{
+			name:  "missing down",
+			files: fstest.MapFS{"0001_first.up.sql": file("CREATE TABLE a (id int);")},
+			want:  "needs both an up and a down file",
+		},
+		{
+			name:  "conflicting names",
+			files: fstest.MapFS{"0001_first.up.sql": file("SELECT 1;"), "0001_other.down.sql": file("SELECT 1;")},
+			want:  "named both",
+		},
+		{
+			name:  "version zero",
+			files: fstest.MapFS{"0000_zero.up.sql": file("SELECT 1;"), "0000_zero.down.sql": file("SELECT 1;")},
+			want:  "versions start at 1",
+		},
+	}
+	for _, tt := range errorTests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(tt.files)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load() error = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+
+	t.Run("embedded migrations", func(t *testing.T) {
+		migrations, err := Load(migrations.FS)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, m := range migrations {
+			if m.Version != i+1 {
+				t.Errorf("migration %s, want version %d: versions must not have gaps", m, i+1)
+			}
+		}
+	})
+}
+
+// testDB connects to the MySQL database of MIGRATE_TEST_DSN, skipping the
+// test when it is not set. The tables of the test are dropped before and
+// after it.
+func testDB(t *testing.T, tables ...string) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("MIGRATE_TEST_DSN")
+	if dsn == "" {
+		t.Skip("MIGRATE_TEST_DSN is not set")
+	}
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dropTables := func() error {
+		for _, table := range append(tables, "schema_migrations") {
+			if _, err := db.Exec("DROP TABLE IF EXISTS " + table); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	t.Cleanup(func() {
+		if err := dropTables(); err != nil {
+			t.Error(err)
+		}
+		db.Close()
+	})
+
+	if err := dropTables(); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+var testMigrations = fstest.MapFS{
+	"0001_create_a.up.sql":   {Data: []byte("CREATE TABLE migrate_test_a (id int NOT NULL, PRIMARY KEY (id));\n")},
+	"0001_create_a.down.sql": {Data: []byte("DROP TABLE migrate_test_a;\n")},
+	"0002_create_b.up.sql":   {Data: []byte("-- two statements\nCREATE TABLE migrate_test_b (id int NOT NULL, PRIMARY KEY (id));\nINSERT INTO migrate_test_b (id) VALUES (1);\n")},
+	"0002_create_b.down.sql": {Data: []byte("DROP TABLE migrate_test_b;\n")},
+}
+
+func TestMigrator(t *testing.T) {
+	db := testDB(t, "migrate_test_a", "migrate_test_b")
+	ctx := context.Background()
+	m, err := New(db, testMigrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Check(ctx); err == nil {
+		t.Error("Check() passed before migrating")
+	}
+
+	steps, err := m.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 2 || !steps[0].Up || steps[1].String() != "applied 0002_create_b" {
+		t.Errorf("Up() = %v, want both migrations applied", steps)
+	}
+	if err := m.Check(ctx); err != nil {
+		t.Errorf("Check() = %v after migrating", err)
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM migrate_test_b").Scan(&count); err != nil || count != 1 {
+		t.Errorf("migrate_test_b has %d rows, %v, want the one inserted", count, err)
+	}
+
+	if steps, err := m.Up(ctx); err != nil || len(steps) != 0 {
+		t.Errorf("second Up() = %v, %v, want nothing to do", steps, err)
+	}
+
+	steps, err = m.Down(ctx)
+	if err != nil || len(steps) != 1 || steps[0].String() != "rolled back 0002_create_b" {
+		t.Errorf("Down() = %v, %v, want 0002 rolled back", steps, err)
+	}
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !statuses[0].Applied || statuses[1].Applied {
+		t.Errorf("statuses = %+v, want only 0001 applied", statuses)
+	}
+
+	if _, err := m.Goto(ctx, 3); err == nil {
+		t.Error("Goto(3) succeeded without migration 3")
+	}
+	if steps, err := m.Goto(ctx, 0); err != nil || len(steps) != 1 {
+		t.Errorf("Goto(0) = %v, %v, want 0001 rolled back", steps, err)
+	}
+	if _, err := db.Exec("SELECT 1 FROM migrate_test_a"); err == nil {
+		t.Error("migrate_test_a still exists after rolling everything back")
+	}
+}
+
+func TestMigratorDirty(t *testing.T) {
+	db := testDB(t, "migrate_test_a")
+	ctx := context.Background()
+	files := fstest.MapFS{
+		"0001_broken.up.sql":   {Data: []byte("CREATE TABLE migrate_test_a (id int NOT NULL, PRIMARY KEY (id));\nINSERT INTO missing_table VALUES (1);\n")},
+		"0001_broken.down.sql": {Data: []byte("DROP TABLE migrate_test_a;\n")},
+	}
+	m, err := New(db, files)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Up(ctx); err == nil || !strings.Contains(err.Error(), "applying migration 0001_broken") {
+		t.Fatalf("Up() error = %v, want the failing migration", err)
+	}
+	if err := m.Check(ctx); err == nil || !strings.Contains(err.Error(), "dirty") {
+		t.Errorf("Check() = %v, want the dirty migration reported", err)
+	}
+	if _, err := m.Up(ctx); err == nil || !strings.Contains(err.Error(), "failed halfway") {
+		t.Errorf("Up() on a dirty schema = %v, want a refusal", err)
+	}
+}
+
+func TestMigratorLock(t *testing.T) {
+	db := testDB(t, "migrate_test_a", "migrate_test_b")
+	ctx := context.Background()
+	m, err := New(db, testMigrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.lockTimeout = time.Second
+
+	// Another replica holds the lock on its own connection
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	var acquired int
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(CONCAT(DATABASE(), '.schema_migrations'), 0)").Scan(&acquired); err != nil || acquired != 1 {
+		t.Fatalf("taking the lock: %d, %v", acquired, err)
+	}
+
+	if _, err := m.Up(ctx); err == nil || !strings.Contains(err.Error(), "held the migration lock") {
+		t.Errorf("Up() while locked = %v, want a lock timeout", err)
+	}
+
+	if _, err := conn.ExecContext(ctx, "SELECT RELEASE_LOCK(CONCAT(DATABASE(), '.schema_migrations'))"); err != nil {
+		t.Fatal(err)
+	}
+	if steps, err := m.Up(ctx); err != nil || len(steps) != 2 {
+		t.Errorf("Up() after the lock was released = %v, %v", steps, err)
+	}
+}
@@ -0,0 +1,183 @@
+// Package ratelimit limits how often clients may call the API with token
+// buckets, and how much with quotas.
+//
+// Every client has a bucket holding up to Burst tokens, refilled at Rate
+// tokens per second. Each request takes a token and is rejected when the
+// bucket is empty, so clients may send Burst requests at once and Rate
+// requests per second in the long run.
+//
+// A quota caps the requests of a client within fixed windows of time, such
+// as a day, whatever their pace.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit is the size and refill rate of a bucket.
+type Limit struct {
+	// Rate is the number of tokens added per second.
+	Rate float64
+	// Burst is the number of tokens a full bucket holds.
+	Burst int
+}
+
+// Quota is the number of requests allowed in each window. Windows are
+// aligned on multiples of Window since the zero time, so daily ones start at
+// midnight UTC.
+type Quota struct {
+	Requests int
+	Window   time.Duration
+}
+
+// Result is the outcome of taking a token or spending a quota.
+type Result struct {
+	Allowed bool
+	// Remaining is the number of whole tokens left in the bucket, or of
+	// requests left in the window.
+	Remaining int
+	// Reset is the time until the bucket is full again, or until the window
+	// ends.
+	Reset time.Duration
+	// RetryAfter is the time until a token is available, zero when the
+	// request is allowed.
+	RetryAfter time.Duration
+}
+
+// Store keeps the buckets of the clients. The memory store suits a single
+// instance, replicas sharing their limits need a store backed by a shared
+// database such as Redis.
+type Store interface {
+	// Take takes a token from the bucket of key, created full when missing.
+	Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error)
+	// Spend counts a request against the quota of key in the window of now,
+	// unless the quota is spent already.
+	Spend(ctx context.Context, key string, quota Quota, now time.Time) (Result, error)
+}
+
+// bucket is the state of a bucket as of last.
+type bucket struct {
+	tokens float64
+	last   time.Time
+	burst  int
+	rate   float64
+}
+
+// fill returns the tokens of b at now.
+func (b *bucket) fill(now time.Time) float64 {
+	return math.Min(float64(b.burst), b.tokens+now.Sub(b.last).Seconds()*b.rate)
+}
+
+// take takes a token from b at now.
+func (b *bucket) take(now time.Time) Result {
+	b.tokens = b.fill(now)
+	b.last = now
+
+	result := Result{Allowed: b.tokens >= 1}
+	if result.Allowed {
+		b.tokens--
+	} else {
+		result.RetryAfter = seconds((1 - b.tokens) / b.rate)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = seconds((float64(b.burst) - b.tokens) / b.rate)
+	return result
+}
+
+// window is the count of requests of a quota within the window ending at
+// end.
+type window struct {
+	count int
+	end   time.Time
+}
+
+// spend counts a request against quota in w at now.
+func (w *window) spend(quota Quota, now time.Time) Result {
+	if !now.Before(w.end) {
+		w.count = 0
+		w.end = now.Truncate(quota.Window).Add(quota.Window)
+	}
+
+	result := Result{Allowed: w.count < quota.Requests, Reset: w.end.Sub(now)}
+	if result.Allowed {
+		w.count++
+	} else {
+		result.RetryAfter = result.Reset
+	}
+	result.Remaining = quota.Requests - w.count
+	return result
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
+
+// sweepInterval is how often the memory store drops the buckets that have
+// filled up again and the windows that have ended, which are the same as
+// missing ones.
+const sweepInterval = time.Minute
+
+// MemoryStore keeps the buckets and quota windows in memory.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	windows   map[string]*window
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket), windows: make(map[string]*window)}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		s.sweep(now)
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now}
+		s.buckets[key] = b
+	}
+	// Pick up changes of the limit
+	b.burst, b.rate = limit.Burst, limit.Rate
+	return b.take(now), nil
+}
+
+func (s *MemoryStore) Spend(ctx context.Context, key string, quota Quota, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		s.sweep(now)
+	}
+
+	w, ok := s.windows[key]
+	if !ok {
+		w = &window{}
+		s.windows[key] = w
+	}
+	return w.spend(quota, now), nil
+}
+
+// sweep drops the full buckets and the ended windows. The caller must hold
+// s.mu.
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, b := range s.buckets {
+		if b.fill(now) >= float64(b.burst) {
+			delete(s.buckets, key)
+		}
+	}
+	for key, w := range s.windows {
+		if !now.Before(w.end) {
+			delete(s.windows, key)
+		}
+	}
+	s.lastSweep = now
+}
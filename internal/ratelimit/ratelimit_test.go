@@ -0,0 +1,106 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTake(t *testing.T) {
+	store := NewMemoryStore()
+	limit := Limit{Rate: 2, Burst: 3}
+	start := time.Date(2030, 6, 15, 12, 0, 0, 0, time.UTC)
+
+	// The steps share one bucket and build on each other
+	steps := []struct {
+		name  string
+		after time.Duration
+		limit Limit
+		want  Result
+	}{
+		{name: "full bucket", limit: limit, want: Result{Allowed: true, Remaining: 2, Reset: 500 * time.Millisecond}},
+		{name: "second", limit: limit, want: Result{Allowed: true, Remaining: 1, Reset: time.Second}},
+		{name: "last token", limit: limit, want: Result{Allowed: true, Remaining: 0, Reset: 1500 * time.Millisecond}},
+		{name: "empty bucket", limit: limit, want: Result{Remaining: 0, Reset: 1500 * time.Millisecond, RetryAfter: 500 * time.Millisecond}},
+		{name: "half a token later", after: 250 * time.Millisecond, limit: limit, want: Result{Remaining: 0, Reset: 1250 * time.Millisecond, RetryAfter: 250 * time.Millisecond}},
+		{name: "refilled token", after: 500 * time.Millisecond, limit: limit, want: Result{Allowed: true, Remaining: 0, Reset: 1500 * time.Millisecond}},
+		{name: "full again", after: 2 * time.Second, limit: limit, want: Result{Allowed: true, Remaining: 2, Reset: 500 * time.Millisecond}},
+		{name: "smaller burst", after: 2 * time.Second, limit: Limit{Rate: 1, Burst: 1}, want: Result{Allowed: true, Remaining: 0, Reset: time.Second}},
+	}
+	for _, step := range steps {
+		got, err := store.Take(context.Background(), "user:jane", step.limit, start.Add(step.after))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != step.want {
+			t.Errorf("%s: Take() = %+v, want %+v", step.name, got, step.want)
+		}
+	}
+
+	// Other keys have buckets of their own
+	if got, _ := store.Take(context.Background(), "user:john", limit, start); !got.Allowed || got.Remaining != 2 {
+		t.Errorf("Take() for another key = %+v, want a full bucket", got)
+	}
+}
+
+func TestSpend(t *testing.T) {
+	store := NewMemoryStore()
+	quota := Quota{Requests: 2, Window: time.Hour}
+	at := func(clock string) time.Time {
+		parsed, _ := time.Parse(time.DateTime, "2030-06-15 "+clock)
+		return parsed
+	}
+
+	steps := []struct {
+		name string
+		at   time.Time
+		want Result
+	}{
+		{name: "first", at: at("10:15:00"), want: Result{Allowed: true, Remaining: 1, Reset: 45 * time.Minute}},
+		{name: "last", at: at("10:30:00"), want: Result{Allowed: true, Remaining: 0, Reset: 30 * time.Minute}},
+		{name: "spent", at: at("10:59:59"), want: Result{Remaining: 0, Reset: time.Second, RetryAfter: time.Second}},
+		{name: "next window", at: at("11:00:00"), want: Result{Allowed: true, Remaining: 1, Reset: time.Hour}},
+		{name: "window skipped", at: at("13:20:00"), want: Result{Allowed: true, Remaining: 1, Reset: 40 * time.Minute}},
+	}
+	for _, step := range steps {
+		got, err := store.Spend(context.Background(), "user:jane", quota, step.at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != step.want {
+			t.Errorf("%s: Spend() = %+v, want %+v", step.name, got, step.want)
+		}
+	}
+
+	// Daily windows start at midnight UTC
+	daily := Quota{Requests: 10, Window: 24 * time.Hour}
+	if got, _ := store.Spend(context.Background(), "user:john", daily, at("18:00:00")); got.Reset != 6*time.Hour {
+		t.Errorf("Spend() reset = %v, want 6h until midnight", got.Reset)
+	}
+}
+
+func TestSweep(t *testing.T) {
+	store := NewMemoryStore()
+	start := time.Date(2030, 6, 15, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	store.Take(ctx, "idle", Limit{Rate: 1, Burst: 5}, start)
+	store.Take(ctx, "slow", Limit{Rate: 0.001, Burst: 5}, start)
+	store.Spend(ctx, "ended", Quota{Requests: 5, Window: time.Minute}, start)
+	store.Spend(ctx, "daily", Quota{Requests: 5, Window: 24 * time.Hour}, start)
+
+	// Full buckets and ended windows behave as missing ones, so they go
+	store.Take(ctx, "other", Limit{Rate: 1, Burst: 5}, start.Add(sweepInterval))
+	if _, ok := store.buckets["idle"]; ok {
+		t.Error("the full bucket was kept")
+	}
+	if _, ok := store.buckets["slow"]; !ok {
+		t.Error("the bucket still filling up was dropped")
+	}
+	if _, ok := store.windows["ended"]; ok {
+		t.Error("the ended window was kept")
+	}
+	if _, ok := store.windows["daily"]; !ok {
+		t.Error("the current window was dropped")
+	}
+}
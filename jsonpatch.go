@@ -0,0 +1,302 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+var (
+	// errInvalidPatch is returned when a patch document is malformed or
+	// cannot be applied to the target document.
+	errInvalidPatch = errors.New("invalid patch")
+	// errPatchTestFailed is returned when a JSON Patch "test" operation does
+	// not match the target document.
+	errPatchTestFailed = errors.New("patch test operation failed")
+)
+
+// applyMergePatch applies a JSON Merge Patch (RFC 7386) to doc.
+func applyMergePatch(doc, patch []byte) ([]byte, error) {
+	var target, p any
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidPatch, err)
+	}
+	return json.Marshal(mergePatch(target, p))
+}
+
+func mergePatch(target, patch any) any {
+	patchObj, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]any)
+	if !ok {
+		targetObj = map[string]any{}
+	}
+	for name, value := range patchObj {
+		if value == nil {
+			delete(targetObj, name)
+		} else {
+			targetObj[name] = mergePatch(targetObj[name], value)
+		}
+	}
+	return targetObj
+}
+
+type jsonPatchOperation struct {
+	Op    string           `json:"op"`
+	Path  string           `json:"path"`
+	From  string           `json:"from"`
+	Value *json.RawMessage `json:"value"`
+}
+
+// applyJSONPatch applies a JSON Patch (RFC 6902) to doc. Operations are
+// applied in order and the whole patch fails if any of them fails.
+func applyJSONPatch(doc, patch []byte) ([]byte, error) {
+	var ops []jsonPatchOperation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidPatch, err)
+	}
+
+	var target any
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+
+	for i, op := range ops {
+		var err error
+		if target, err = applyJSONPatchOperation(target, op); err != nil {
+			return nil, fmt.Errorf("operation %d (%s %s): %w", i, op.Op, op.Path, err)
+		}
+	}
+
+	return json.Marshal(target)
+}
+
+func applyJSONPatchOperation(doc any, op jsonPatchOperation) (any, error) {
+	var value any
+	if op.Value != nil {
+		if err := json.Unmarshal(*op.Value, &value); err != nil {
+			return nil, fmt.Errorf("%w: %v", errInvalidPatch, err)
+		}
+	}
+
+	switch op.Op {
+	case "add":
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w: missing value", errInvalidPatch)
+		}
+		return jsonPointerAdd(doc, op.Path, value)
+	case "remove":
+		doc, _, err := jsonPointerRemove(doc, op.Path)
+		return doc, err
+	case "replace":
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w: missing value", errInvalidPatch)
+		}
+		doc, _, err := jsonPointerRemove(doc, op.Path)
+		if err != nil {
+			return nil, err
+		}
+		return jsonPointerAdd(doc, op.Path, value)
+	case "move":
+		if op.Path == op.From || strings.HasPrefix(op.Path, op.From+"/") {
+			return nil, fmt.Errorf("%w: cannot move a value into itself", errInvalidPatch)
+		}
+		doc, moved, err := jsonPointerRemove(doc, op.From)
+		if err != nil {
+			return nil, err
+		}
+		return jsonPointerAdd(doc, op.Path, moved)
+	case "copy":
+		copied, err := jsonPointerGet(doc, op.From)
+		if err != nil {
+			return nil, err
+		}
+		// Copy the value so that later operations do not change both places
+		b, _ := json.Marshal(copied)
+		var clone any
+		json.Unmarshal(b, &clone)
+		return jsonPointerAdd(doc, op.Path, clone)
+	case "test":
+		if op.Value == nil {
+			return nil, fmt.Errorf("%w: missing value", errInvalidPatch)
+		}
+		current, err := jsonPointerGet(doc, op.Path)
+		if err != nil {
+			return nil, err
+		}
+		if !reflect.DeepEqual(current, value) {
+			return nil, errPatchTestFailed
+		}
+		return doc, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown operation %q", errInvalidPatch, op.Op)
+	}
+}
+
+// parseJSONPointer splits a JSON Pointer (RFC 6901) into its unescaped
+// reference tokens.
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("%w: path %q must start with /", errInvalidPatch, pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+func jsonPointerGet(doc any, pointer string) (any, error) {
+	tokens, err := parseJSONPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	for _, token := range tokens {
+		switch node := doc.(type) {
+		case map[string]any:
+			value, ok := node[token]
+			if !ok {
+				return nil, fmt.Errorf("%w: path %q does not exist", errInvalidPatch, pointer)
+			}
+			doc = value
+		case []any:
+			i, err := arrayIndex(token, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, fmt.Errorf("%w: path %q does not exist", errInvalidPatch, pointer)
+		}
+	}
+	return doc, nil
+}
+
+// jsonPointerAdd adds value at pointer and returns the updated document.
+func jsonPointerAdd(doc any, pointer string, value any) (any, error) {
+	tokens, err := parseJSONPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	parentPointer := pointer[:strings.LastIndex(pointer, "/")]
+	parent, err := jsonPointerGet(doc, parentPointer)
+	if err != nil {
+		return nil, err
+	}
+
+	last := tokens[len(tokens)-1]
+	switch node := parent.(type) {
+	case map[string]any:
+		node[last] = value
+		return doc, nil
+	case []any:
+		i := len(node)
+		if last != "-" {
+			if i, err = arrayIndex(last, len(node)); err != nil {
+				return nil, err
+			}
+		}
+		node = append(node, nil)
+		copy(node[i+1:], node[i:])
+		node[i] = value
+		return jsonPointerSet(doc, parentPointer, node)
+	default:
+		return nil, fmt.Errorf("%w: path %q does not exist", errInvalidPatch, pointer)
+	}
+}
+
+// jsonPointerSet replaces the existing value at pointer and returns the
+// updated document.
+func jsonPointerSet(doc any, pointer string, value any) (any, error) {
+	tokens, err := parseJSONPointer(pointer)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	parent, err := jsonPointerGet(doc, pointer[:strings.LastIndex(pointer, "/")])
+	if err != nil {
+		return nil, err
+	}
+
+	last := tokens[len(tokens)-1]
+	switch node := parent.(type) {
+	case map[string]any:
+		node[last] = value
+	case []any:
+		i, err := arrayIndex(last, len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		node[i] = value
+	default:
+		return nil, fmt.Errorf("%w: path %q does not exist", errInvalidPatch, pointer)
+	}
+	return doc, nil
+}
+
+// jsonPointerRemove removes the value at pointer and returns the updated
+// document along with the removed value.
+func jsonPointerRemove(doc any, pointer string) (any, any, error) {
+	tokens, err := parseJSONPointer(pointer)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, doc, nil
+	}
+
+	parentPointer := pointer[:strings.LastIndex(pointer, "/")]
+	parent, err := jsonPointerGet(doc, parentPointer)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	last := tokens[len(tokens)-1]
+	switch node := parent.(type) {
+	case map[string]any:
+		removed, ok := node[last]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: path %q does not exist", errInvalidPatch, pointer)
+		}
+		delete(node, last)
+		return doc, removed, nil
+	case []any:
+		i, err := arrayIndex(last, len(node)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		removed := node[i]
+		node = append(node[:i:i], node[i+1:]...)
+		doc, err := jsonPointerSet(doc, parentPointer, node)
+		return doc, removed, err
+	default:
+		return nil, nil, fmt.Errorf("%w: path %q does not exist", errInvalidPatch, pointer)
+	}
+}
+
+func arrayIndex(token string, maxIndex int) (int, error) {
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || i > maxIndex || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("%w: invalid array index %q", errInvalidPatch, token)
+	}
+	return i, nil
+}
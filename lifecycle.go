@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Statuses of a project. New projects are planned, completed and cancelled
+// projects are final.
+const (
+	statusPlanned   = "planned"
+	statusActive    = "active"
+	statusOnHold    = "on_hold"
+	statusCompleted = "completed"
+	statusCancelled = "cancelled"
+)
+
+// statusTransitions lists the statuses each status can move to.
+var statusTransitions = map[string][]string{
+	statusPlanned:   {statusActive, statusCancelled},
+	statusActive:    {statusOnHold, statusCompleted, statusCancelled},
+	statusOnHold:    {statusActive, statusCancelled},
+	statusCompleted: nil,
+	statusCancelled: nil,
+}
+
+// transitionModel records a change of the status of a project.
+type transitionModel struct {
+	ID     string   `json:"id"`
+	From   string   `json:"from" enums:"planned,active,on_hold,completed,cancelled" example:"active"`
+	To     string   `json:"to" enums:"planned,active,on_hold,completed,cancelled" example:"on_hold"`
+	Reason string   `json:"reason,omitempty" example:"Waiting for the building permit"`
+	At     dateTime `json:"at" swaggertype:"string" format:"date-time" example:"2024-03-01T09:30:00Z"`
+}
+
+type transitionList struct {
+	Data []transitionModel `json:"data"`
+}
+
+// transitionRequest is the body moving a project to another status. Putting
+// a project on hold or cancelling it needs a reason, see
+// validateTransitionRequest.
+type transitionRequest struct {
+	Status string `json:"status" binding:"required,oneof=planned active on_hold completed cancelled" enums:"planned,active,on_hold,completed,cancelled" example:"on_hold"`
+	Reason string `json:"reason" binding:"max=1000" example:"Waiting for the building permit"`
+}
+
+var (
+	// errInvalidTransition is returned when the state machine does not allow
+	// a project to move to the requested status.
+	errInvalidTransition = errors.New("invalid transition")
+	// errProjectCompleted is returned when changing the budget or the
+	// payments of a completed project.
+	errProjectCompleted = errors.New("the budget and payments of a completed project cannot change")
+)
+
+// checkTransition returns errInvalidTransition unless a project can move from
+// status from to status to.
+func checkTransition(from, to string) error {
+	allowed := statusTransitions[from]
+	for _, status := range allowed {
+		if status == to {
+			return nil
+		}
+	}
+	if len(allowed) == 0 {
+		return fmt.Errorf("%w: %s projects cannot change status", errInvalidTransition, from)
+	}
+	return fmt.Errorf("%w: %s projects can only move to %s", errInvalidTransition, from, strings.Join(allowed, ", "))
+}
+
+// checkCompletedBudget returns errProjectCompleted when the budget of a
+// completed project would change from the budget of current to the budget of
+// updated.
+func checkCompletedBudget(current, updated projectModel) error {
+	if current.Status != statusCompleted {
+		return nil
+	}
+	if updated.Budget.BudgetValue != current.Budget.BudgetValue ||
+		updated.Budget.DownPayment != current.Budget.DownPayment ||
+		updated.Budget.Deadline != current.Budget.Deadline {
+		return errProjectCompleted
+	}
+	return nil
+}
+
+// checkPaymentsOpen returns errProjectCompleted when payments of project
+// cannot be recorded or deleted anymore, which would change the amount paid
+// towards its final budget.
+func checkPaymentsOpen(project projectModel) error {
+	if project.Status == statusCompleted {
+		return errProjectCompleted
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	requestIDHeader = "X-Request-ID"
+	// maxRequestIDLength bounds the request IDs accepted from clients so
+	// that they cannot bloat every log line.
+	maxRequestIDLength = 128
+	// userKey is the key of the gin context under which authentication
+	// stores the name of the caller.
+	userKey = "user"
+)
+
+// requestIDContextKey is the key of the request context under which
+// requestLogging stores the request ID.
+type requestIDContextKey struct{}
+
+// requestIDFromContext returns the ID of the request of ctx, empty outside
+// of requests.
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// requestLogging assigns every request an ID, reusing the X-Request-ID header
+// of the caller when it is valid, and echoes it in the response. The request
+// context carries a logger annotated with the request ID, method, route and
+// path parameters, available through log.Ctx, and the request ID itself,
+// available through requestIDFromContext. Once the request is served
+// one access line is logged.
+func requestLogging() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestID := c.GetHeader(requestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+		c.Header(requestIDHeader, requestID)
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		fields := log.Logger.With().
+			Str("request_id", requestID).
+			Str("method", c.Request.Method).
+			Str("route", route)
+		for _, param := range c.Params {
+			fields = fields.Str(param.Key, param.Value)
+		}
+		if spanContext := trace.SpanContextFromContext(c.Request.Context()); spanContext.IsValid() {
+			fields = fields.Str("trace_id", spanContext.TraceID().String()).Str("span_id", spanContext.SpanID().String())
+		}
+		logger := fields.Logger()
+		ctx := context.WithValue(c.Request.Context(), requestIDContextKey{}, requestID)
+		c.Request = c.Request.WithContext(logger.WithContext(ctx))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		var event *zerolog.Event
+		switch {
+		case status >= http.StatusInternalServerError:
+			event = logger.Error()
+		case status >= http.StatusBadRequest:
+			event = logger.Warn()
+		default:
+			event = logger.Info()
+		}
+		if user := c.GetString(userKey); user != "" {
+			event = event.Str("user", user)
+		}
+		event.
+			Str("path", c.Request.URL.Path).
+			Int("status", status).
+			Float64("latency_ms", float64(time.Since(start).Microseconds())/1000).
+			Int("bytes", max(c.Writer.Size(), 0)).
+			Str("client_ip", c.ClientIP()).
+			Msg("Request served")
+	}
+}
+
+// validRequestID accepts IDs of printable ASCII characters without spaces.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func TestRequestLogging(t *testing.T) {
+	var buf bytes.Buffer
+	previous := log.Logger
+	log.Logger = zerolog.New(&buf)
+	t.Cleanup(func() { log.Logger = previous })
+
+	router := gin.New()
+	router.Use(requestLogging())
+	router.GET("/api/v1/things/:id", func(c *gin.Context) {
+		log.Ctx(c.Request.Context()).Info().Msg("Looking up thing")
+		c.String(http.StatusNotFound, "none")
+	})
+
+	tests := []struct {
+		name   string
+		header string
+		reused bool
+	}{
+		{name: "generated", header: ""},
+		{name: "reused", header: "req-1", reused: true},
+		{name: "invalid", header: "req 1"},
+		{name: "too long", header: strings.Repeat("a", maxRequestIDLength+1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			var header []string
+			if tt.header != "" {
+				header = []string{requestIDHeader, tt.header}
+			}
+			w := request(router, http.MethodGet, "/api/v1/things/7", "", header...)
+
+			id := w.Header().Get(requestIDHeader)
+			if tt.reused && id != tt.header || !tt.reused && (id == tt.header || len(id) != 32) {
+				t.Errorf("%s = %q for the header %q", requestIDHeader, id, tt.header)
+			}
+
+			var lines []map[string]any
+			for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+				var fields map[string]any
+				if err := json.Unmarshal([]byte(line), &fields); err != nil {
+					t.Fatalf("log line %q is not JSON: %v", line, err)
+				}
+				lines = append(lines, fields)
+			}
+			if len(lines) != 2 {
+				t.Fatalf("logged %d lines, want the handler line and the access line", len(lines))
+			}
+			for _, fields := range lines {
+				if fields["request_id"] != id || fields["route"] != "/api/v1/things/:id" || fields["id"] != "7" {
+					t.Errorf("log line %v lacks the request fields", fields)
+				}
+			}
+			if access := lines[1]; access["level"] != "warn" || access["status"] != float64(http.StatusNotFound) || access["bytes"] != float64(4) {
+				t.Errorf("access line = %v, want a warning for the 404", access)
+			}
+		})
+	}
+}
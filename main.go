@@ -1,11 +1,27 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"flag"
 	"fmt"
+	"go-example-api/db/migrations"
 	"go-example-api/docs"
+	"go-example-api/internal/auth"
+	"go-example-api/internal/config"
+	"go-example-api/internal/logging"
+	"go-example-api/internal/migrate"
+	"go-example-api/internal/ratelimit"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-sql-driver/mysql"
@@ -15,387 +31,508 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-type HTTPError struct {
-	Message string `json:"message" example:"status bad request"`
-}
-
 type HTTPSuccess struct {
 	Message string `json:"message" example:"success"`
 }
 
 type budgetModel struct {
-	BudgetValue int64  `json:"budget_value"`
-	DownPayment int64  `json:"down_payment"`
-	Deadline    string `json:"deadline"`
+	// BudgetValue and DownPayment share a currency, see validateBudget
+	BudgetValue money `json:"budget_value"`
+	DownPayment money `json:"down_payment"`
+	// Deadline must be in the future when the project is created or the
+	// deadline changed
+	Deadline dateTime `json:"deadline" binding:"required" swaggertype:"string" format:"date-time" example:"2030-12-31T00:00:00Z"`
+	// paymentSummary is computed from the payments of the project
+	paymentSummary `binding:"-"`
+	// Version is bumped on every change to the budget
+	Version int64 `json:"-"`
 }
 
 type projectModel struct {
-	ID     string      `json:"id"`
-	Title  string      `json:"title"`
-	Leader string      `json:"leader"`
-	Budget budgetModel `json:"budget"`
+	ID    string `json:"id"`
+	Title string `json:"title" binding:"required,max=255"`
+	// LeaderID is the id of the person leading the project, Leader their
+	// name. Leader is ignored in request bodies.
+	LeaderID string      `json:"leader_id" binding:"required" example:"7"`
+	Leader   string      `json:"leader" readonly:"true" example:"Jane Doe"`
+	Budget   budgetModel `json:"budget"`
+	// Status changes through transitions only, it is ignored in request
+	// bodies along with StatusChangedAt
+	Status          string   `json:"status" readonly:"true" enums:"planned,active,on_hold,completed,cancelled" example:"active"`
+	StatusChangedAt dateTime `json:"status_changed_at" readonly:"true" swaggertype:"string" format:"date-time" example:"2024-03-01T09:30:00Z"`
+	// Version is bumped on every change to the title, leader or status
+	Version int64 `json:"-"`
 }
 
-var db *sql.DB
-
 // @contact.name   API Support
 // @contact.url    http://www.swagger.io/support
 // @contact.email  support@swagger.io
 
 // @license.name  Apache 2.0
 // @license.url   http://www.apache.org/licenses/LICENSE-2.0.html
+
+// @securityDefinitions.apikey  BearerAuth
+// @in                          header
+// @name                        Authorization
+// @description                 JWT sent as "Bearer <token>", signed with HS256 or RS256
+
+// @securityDefinitions.apikey  ApiKeyAuth
+// @in                          header
+// @name                        X-API-Key
+// @description                 API key of a service, see the apikey command
 func main() {
+	os.Exit(run())
+}
+
+// Exit codes of the server process.
+const (
+	exitOK = 0
+	// exitError is used when the server fails to start or stops because of
+	// an error.
+	exitError = 1
+	// exitConfig is used when the configuration is invalid.
+	exitConfig = 2
+	// exitShutdownTimeout is used when in-flight requests did not finish
+	// within the shutdown timeout and had to be cut off.
+	exitShutdownTimeout = 3
+)
+
+// run starts the server and blocks until it stops, returning the exit code
+// of the process.
+func run() int {
+	cfg, args, err := config.Load(os.Args[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		fmt.Fprintln(os.Stderr, "invalid configuration:", err)
+		return exitConfig
+	}
+
+	if len(args) > 0 {
+		return runCommand(cfg, args)
+	}
 
 	// programmatically set swagger info
 	docs.SwaggerInfo.Title = "Swagger Example API"
 	docs.SwaggerInfo.Description = "This is a sample server Petstore server."
 	docs.SwaggerInfo.Version = "1.0"
-	docs.SwaggerInfo.Host = "localhost:8080"
+	docs.SwaggerInfo.Host = cfg.Swagger.Host
+	docs.SwaggerInfo.BasePath = "/api/v1"
 	docs.SwaggerInfo.Schemes = []string{"http", "https"}
 
-	runLogFile, _ := os.OpenFile(
-        "logs/app.log",
-        os.O_APPEND|os.O_CREATE|os.O_WRONLY,
-        0664,
-    )
-
-	multi := zerolog.MultiLevelWriter(os.Stdout, runLogFile)
-	log.Logger = zerolog.New(multi).With().Timestamp().Logger()
-
-	fmt.Println(os.Getenv("DBUSER"))
-	fmt.Println(os.Getenv("DBPASS"))
-
-	// Capture connection properties.
-	cfg := mysql.Config{
-		User:   os.Getenv("DBUSER"),
-		Passwd: os.Getenv("DBPASS"),
-		Net:    "tcp",
-		// db:3306
-		//localhost:3306
-		Addr:   os.Getenv("DBHOST"),
-		DBName: "company",
+	logger, logSinks, err := logging.New(cfg.Log)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "cannot set up logging:", err)
+		return exitError
 	}
+	// Flush the logs once everything else has shut down
+	defer logSinks.Close()
+	log.Logger = logger
+	// Code running outside of a request logs through the global logger
+	zerolog.DefaultContextLogger = &log.Logger
 
-	// Get a database handle.
-	var err error
+	log.Info().Interface("config", cfg.Redacted()).Msg("Loaded configuration")
 
-	db, err = sql.Open("mysql", cfg.FormatDSN())
+	shutdownTracing, err := setupTracing(context.Background(), cfg.Tracing)
 	if err != nil {
-		log.Fatal().Msg(err.Error())
+		log.Error().Msg("Error setting up tracing: " + err.Error())
+		return exitError
 	}
+	// Flush the spans of the last requests before exiting
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			log.Error().Msg("Error flushing spans: " + err.Error())
+		}
+	}()
 
-	pingErr := db.Ping()
-	if pingErr != nil {
-		log.Fatal().Msg(pingErr.Error())
-	}
-
-	router := gin.Default()
-	router.GET("/projects", getProjects)
-	router.GET("/projects/:id", getProjectById)
-	router.POST("/projects", postProjects)
-	router.PUT("/project/:id", updateProject)
-	router.DELETE("/project/:id", deleteProject)
-
-	// use ginSwagger middleware to serve the API docs
-	router.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
-
-	router.Run(":8080")
-}
-
-// getProjects godoc
-// @Summary      Get projects
-// @Description  Get projects
-// @Tags         Get Projects
-// @Accept       json
-// @Produce      json
-// @Success      200  {array}  projectModel
-// @Failure      404  {object} 	HTTPError
-// @Failure      500  {object}  HTTPError
-// @Router       /projects [get]
-func getProjects(c *gin.Context) {
-
-	var projects []projectModel
-
-	rows, err := db.Query("SELECT p.id, p.title, p.leader, pb.budget_value, pb.down_payment, pb.deadline FROM project p JOIN project_budget pb ON p.id = pb.project_id;")
-	if err != nil {
+	if err := registerValidators(); err != nil {
 		log.Error().Msg(err.Error())
-		return
+		return exitError
 	}
 
-	defer rows.Close()
-
-	for rows.Next() {
-		var proj projectModel
-		var projBudget budgetModel
-		if err := rows.Scan(&proj.ID, &proj.Title, &proj.Leader, &projBudget.BudgetValue, &projBudget.DownPayment, &projBudget.Deadline); err != nil {
+	var repo ProjectRepository
+	var checks []healthCheck
+	if cfg.Storage == "memory" {
+		// Keep everything in memory, useful for local demos without MySQL
+		repo = newMemoryProjectRepository()
+	} else {
+		db, err := openDatabase(cfg.Database)
+		if err != nil {
 			log.Error().Msg(err.Error())
-			c.IndentedJSON(http.StatusInternalServerError, gin.H{"message":"internal server error"})
-			return
+			return exitError
 		}
-		proj.Budget = projBudget
-		projects = append(projects, proj)
-	}
-
-	c.IndentedJSON(http.StatusOK, projects)
-}
-
-// getProjectById godoc
-// @Summary      Get project by id
-// @Description  Get project by id
-// @Tags         Get Project by id
-// @Accept       json
-// @Produce      json
-// @Param        id   path      int  true  "Project ID"
-// @Success      200  {object}  projectModel
-// @Failure      404  {object} 	HTTPError
-// @Failure      500  {object}  HTTPError
-// @Router       /projects/{id} [get]
-func getProjectById(c *gin.Context) {
-	id := c.Param("id")
-
-	var proj projectModel
-	var projBudget budgetModel
-
-	row := db.QueryRow("SELECT p.id, p.title, p.leader, pb.budget_value, pb.down_payment, pb.deadline FROM project p JOIN project_budget pb ON p.id = pb.project_id WHERE p.id = ?", id)
-	if err := row.Scan(&proj.ID, &proj.Title, &proj.Leader, &projBudget.BudgetValue, &projBudget.DownPayment, &projBudget.Deadline); err != nil {
-		if err == sql.ErrNoRows {
+		// Close the pool after the server has drained its requests
+		defer func() {
+			if err := db.Close(); err != nil {
+				log.Error().Msg("Error closing database: " + err.Error())
+			}
+		}()
+
+		migrator, err := migrate.New(db, migrations.FS)
+		if err != nil {
 			log.Error().Msg(err.Error())
-			c.IndentedJSON(http.StatusNotFound, gin.H{"message": "project not found"})
-			return
+			return exitError
+		}
+		if cfg.Database.AutoMigrate {
+			steps, err := migrator.Up(context.Background())
+			for _, step := range steps {
+				log.Info().Msg("Migrations: " + step.String())
+			}
+			if err != nil {
+				log.Error().Msg("Error migrating database: " + err.Error())
+				return exitError
+			}
 		}
-		log.Error().Msg("Error scanning data: " + err.Error())
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "internal server error"})
-		return
-	}
-	proj.Budget = projBudget
-	c.IndentedJSON(http.StatusOK, proj)
-}
 
-// postProjects godoc
-// @Summary      Post project
-// @Description  Post project
-// @Tags         Post project
-// @Accept       json
-// @Produce      json
-//	@Param		 project	body		projectModel	true	"Add project"
-// @Success      200  {object}  projectModel
-// @Failure      500  {object}  HTTPError
-// @Router       /projects [post]
-func postProjects(c *gin.Context) {
-	var newProject projectModel
-
-	//binding request to struct model
-	if err := c.BindJSON(&newProject); err != nil {
-		log.Error().Msg("Error binding json to struct: " + err.Error())
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "bad request"})
-		return
+		repo = newMySQLProjectRepository(db)
+		checks = databaseChecks(db, migrator)
+		registerDBStats(db, cfg.Database.Name)
 	}
 
-	// Start a transaction
-	tx, err := db.Begin()
-	if err != nil {
-		log.Error().Msg("Error starting transaction: " + err.Error())
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
-		return
+	// Addresses are rate limited first so that failed authentications are
+	// too, then clients are authenticated so that rate limiting tells them
+	// apart by name, then rate limited before anything else is checked
+	var apiMiddleware, authorize []gin.HandlerFunc
+	limits := ratelimit.NewMemoryStore()
+	if cfg.RateLimit.Enabled {
+		apiMiddleware = append(apiMiddleware, addressRateLimiting(limits, ratelimit.Limit{Rate: cfg.RateLimit.AddressRate, Burst: cfg.RateLimit.AddressBurst}))
 	}
-
-	// Insert query for the project table within the transaction
-	projectQuery := "INSERT INTO project (title, leader) VALUES (?, ?)"
-	projectResult, err := tx.Exec(projectQuery, newProject.Title, newProject.Leader)
-	if err != nil {
-		log.Error().Msg("Error inserting project to database: " + err.Error())
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
-		// Rollback the transaction if there's an error
-		tx.Rollback()
-		return
+	if cfg.Auth.Enabled {
+		var verifier *auth.Verifier
+		if cfg.Auth.VerifiesTokens() {
+			verifier, err = auth.New(context.Background(), cfg.Auth)
+			if err != nil {
+				log.Error().Msg("Error setting up authentication: " + err.Error())
+				return exitError
+			}
+		} else {
+			log.Warn().Msg("No secret or keys to verify bearer tokens with, only API keys are accepted")
+		}
+		apiMiddleware = append(apiMiddleware, authentication(verifier, repo))
+		authorize = append(authorize, authorization(newRolePolicy(cfg.Auth.Roles, repo)))
+	} else {
+		log.Warn().Msg("Authentication is disabled, anyone can call the API")
 	}
-
-	projectID, err := projectResult.LastInsertId()
-
-	if err != nil {
-		log.Error().Msg("Error getting last inserted id: " + err.Error())
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
-		return
+	if cfg.RateLimit.Enabled {
+		quota := ratelimit.Quota{Requests: cfg.RateLimit.Quota, Window: cfg.RateLimit.QuotaWindow}
+		apiMiddleware = append(apiMiddleware, rateLimiting(limits, rateLimits(cfg.RateLimit), quota))
 	}
+	apiMiddleware = append(apiMiddleware, authorize...)
 
-	// Insert query for the project_budget table within the transaction
-	budgetQuery := "INSERT INTO project_budget (budget_value, down_payment, deadline, project_id) VALUES (?, ?, ?, ?)"
-	_, err = tx.Exec(budgetQuery, newProject.Budget.BudgetValue, newProject.Budget.DownPayment, newProject.Budget.Deadline, projectID)
-	if err != nil {
-		log.Error().Msg("Error inserting into project_budget table: " + err.Error())
-
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+	handler := newProjectHandler(repo)
 
-		tx.Rollback()
-		return
+	// gin.Default would add gin's text access logger, requestLogging
+	// replaces it
+	router := gin.New()
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Error().Msg("Error setting trusted proxies: " + err.Error())
+		return exitError
 	}
+	router.Use(requestTracing(), requestLogging(), requestMetrics(), gin.Recovery(), problemErrors())
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(noRoute)
+	router.NoMethod(noMethod)
+	registerRoutes(router, handler, apiMiddleware...)
+	newHealthHandler(cfg.Health.Timeout, checks...).register(router)
+	router.GET("/metrics", metricsHandler())
 
-	// Commit the transaction if all insertions were successful
-	err = tx.Commit()
-	if err != nil {
-		log.Error().Msg("Error committing transaction: " + err.Error())
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
-		return
-	}
+	// use ginSwagger middleware to serve the API docs
+	router.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	c.IndentedJSON(http.StatusCreated, newProject)
+	srv := &http.Server{
+		Addr:         cfg.Server.Addr,
+		Handler:      router,
+		ReadTimeout:  cfg.Server.ReadTimeout,
+		WriteTimeout: cfg.Server.WriteTimeout,
+		IdleTimeout:  cfg.Server.IdleTimeout,
+	}
 
+	return serve(srv, cfg.Server.ShutdownTimeout)
 }
 
+// openDatabase opens the MySQL connection pool and makes sure the database
+// answers.
+func openDatabase(cfg config.Database) (*sql.DB, error) {
+	// Capture connection properties, starting from the driver defaults.
+	dbCfg := mysql.NewConfig()
+	dbCfg.User = cfg.User
+	dbCfg.Passwd = cfg.Password
+	dbCfg.Net = "tcp"
+	// db:3306
+	//localhost:3306
+	dbCfg.Addr = cfg.Host
+	dbCfg.DBName = cfg.Name
+	// Scan DATETIME columns into time.Time, read and written in UTC
+	dbCfg.ParseTime = true
+	dbCfg.Loc = time.UTC
 
-// updateProjectById godoc
-// @Summary      Update project by id
-// @Description  Upadte project by id
-// @Tags         Update Project by id
-// @Accept       json
-// @Produce      json
-// @Param        id   path      int  true  "Project ID"
-// @Param		 project	body		projectModel	true	"Add project"
-// @Success      200  {object}  projectModel
-// @Failure      404  {object} 	HTTPError
-// @Failure      500  {object}  HTTPError
-// @Router       /project/{id} [put]
-func updateProject(c *gin.Context) {
-
-	id := c.Param("id")
-
-	var newProject projectModel
-
-	//binding request to struct model
-	if err := c.BindJSON(&newProject); err != nil {
-		log.Error().Msg("Error binding json to struct: " + err.Error())
-		c.IndentedJSON(http.StatusBadRequest, gin.H{"message": "bad request"})
-		return
-	}
-
-	// Start a transaction
-	tx, err := db.Begin()
+	// Get a database handle.
+	db, err := sql.Open("mysql", dbCfg.FormatDSN())
 	if err != nil {
-		log.Error().Msg("Error starting transaction: " + err.Error())
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
-		return
+		return nil, err
 	}
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
 
-	// Update query for the project table within the transaction
-	projectQuery := "UPDATE project SET title = ?, leader = ? WHERE id = ?"
-	updateProjectResult, err := tx.Exec(projectQuery, newProject.Title, newProject.Leader, id)
-	if err != nil {
-		log.Error().Msg("Error updating project table:" + err.Error())
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
-		// Rollback the transaction if there's an error
-		tx.Rollback()
-		return
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
 	}
+	return db, nil
+}
 
-	if rowAffected, _ := updateProjectResult.RowsAffected(); rowAffected == 0 {
-		log.Error().Msg("not found")
-		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "not found"})
-		// Rollback the transaction if there's an error
-		tx.Rollback()
-		return
+// runCommand runs the subcommand named by args[0] instead of the server.
+func runCommand(cfg *config.Config, args []string) int {
+	switch args[0] {
+	case "healthcheck":
+		// Probe the readiness endpoint of a server running with the same
+		// configuration, used as the container health check
+		host, port, err := net.SplitHostPort(cfg.Server.Addr)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitConfig
+		}
+		if host == "" || host == "0.0.0.0" || host == "::" {
+			host = "127.0.0.1"
+		}
+		if err := probe("http://"+net.JoinHostPort(host, port)+"/readyz", cfg.Health.Timeout+time.Second); err != nil {
+			fmt.Fprintln(os.Stderr, "not ready:", err)
+			return exitError
+		}
+		return exitOK
+	case "migrate":
+		return runMigrate(cfg, args[1:])
+	case "apikey":
+		return runAPIKey(cfg, args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", args[0])
+		return exitConfig
 	}
+}
 
-	// Update query for the project_budget table within the transaction
-	budgetQuery := "UPDATE project_budget SET budget_value = ?, down_payment = ?, deadline = ? WHERE project_id = ?"
-	updateBudgetResult, err := tx.Exec(budgetQuery, newProject.Budget.BudgetValue, newProject.Budget.BudgetValue, newProject.Budget.Deadline, id)
-	if err != nil {
-		log.Error().Msg("Error updating project_budget table: " + err.Error())
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
-		// Rollback the transaction if there's an error
-		tx.Rollback()
-		return
+// serve runs srv until it fails or the process receives SIGINT or SIGTERM.
+// On a signal it stops accepting connections and waits up to
+// shutdownTimeout for in-flight requests to finish.
+func serve(srv *http.Server, shutdownTimeout time.Duration) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Info().Msg("Listening on " + srv.Addr)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		log.Error().Msg("Server stopped: " + err.Error())
+		return exitError
+	case <-ctx.Done():
 	}
+	// A second signal kills the process right away
+	stop()
 
-	if rowAffected, _ := updateBudgetResult.RowsAffected(); rowAffected == 0 {
-		log.Error().Msg("No rows affected")
-		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "not found"})
-		// Rollback the transaction if there's an error
-		tx.Rollback()
-		return
-	}
+	log.Info().Msg("Shutting down, draining in-flight requests")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
 
-	// Commit the transaction if all updates were successful
-	err = tx.Commit()
-	if err != nil {
-		log.Error().Msg("Error committing transaction: " + err.Error())
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
-		return
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Error().Msg("Requests did not finish in time: " + err.Error())
+		srv.Close()
+		return exitShutdownTimeout
 	}
 
-	c.IndentedJSON(http.StatusOK, newProject)
-
+	log.Info().Msg("Server stopped")
+	return exitOK
 }
 
+// runMigrate runs the migrate subcommand:
+//
+//	migrate up         applies every pending migration
+//	migrate down       rolls back the newest applied migration
+//	migrate status     lists the migrations and whether they are applied
+//	migrate goto <n>   migrates up or down to version n, 0 rolls back all
+func runMigrate(cfg *config.Config, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: migrate up|down|status|goto <version>")
+		return exitConfig
+	}
+	if cfg.Storage != "mysql" {
+		fmt.Fprintln(os.Stderr, "migrations need storage mysql")
+		return exitConfig
+	}
 
-// deleteProjectById godoc
-// @Summary      Delete project by id
-// @Description  Delete project by id
-// @Tags         Delete Project by id
-// @Accept       json
-// @Produce      json
-// @Param        id   path      int  true  "Project ID"
-// @Success      200  {object}  HTTPSuccess
-// @Failure      404  {object} 	HTTPError
-// @Failure      500  {object}  HTTPError
-// @Router       /project/{id} [delete]
-func deleteProject(c *gin.Context) {
-
-	id := c.Param("id")
-
-	// Start a transaction
-	tx, err := db.Begin()
+	db, err := openDatabase(cfg.Database)
 	if err != nil {
-		log.Error().Msg("Error starting transaction: " + err.Error())
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
-		return
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
 	}
+	defer db.Close()
 
-	// Delete query for the project_budget table within the transaction
-	budgetQuery := "DELETE FROM project_budget WHERE project_id = ?"
-	deleteBudgetResult, err := tx.Exec(budgetQuery, id)
+	migrator, err := migrate.New(db, migrations.FS)
 	if err != nil {
-		log.Error().Msg("Error deleting from project_budget table: " + err.Error())
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
-		// Rollback the transaction if there's an error
-		tx.Rollback()
-		return
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
 	}
 
-	if rowAffected, _ := deleteBudgetResult.RowsAffected(); rowAffected == 0 {
-		log.Error().Msg("no rows affected")
-		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "not found"})
-		// Rollback the transaction if there's an error
-		tx.Rollback()
-		return
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var steps []migrate.Step
+	switch {
+	case args[0] == "up" && len(args) == 1:
+		steps, err = migrator.Up(ctx)
+	case args[0] == "down" && len(args) == 1:
+		steps, err = migrator.Down(ctx)
+	case args[0] == "goto" && len(args) == 2:
+		version, convErr := strconv.Atoi(args[1])
+		if convErr != nil {
+			fmt.Fprintf(os.Stderr, "invalid version %q\n", args[1])
+			return exitConfig
+		}
+		steps, err = migrator.Goto(ctx, version)
+	case args[0] == "status" && len(args) == 1:
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		for _, s := range statuses {
+			state := "pending"
+			switch {
+			case s.Dirty:
+				state = "dirty since " + s.AppliedAt
+			case s.Applied:
+				state = "applied at " + s.AppliedAt
+			}
+			fmt.Printf("%s  %s\n", s.Migration, state)
+		}
+		return exitOK
+	default:
+		fmt.Fprintln(os.Stderr, "usage: migrate up|down|status|goto <version>")
+		return exitConfig
 	}
 
-	// Delete query for the project table within the transaction
-	projectQuery := "DELETE FROM project WHERE id = ?"
-	deleteProjectResult, err := tx.Exec(projectQuery, id)
+	for _, step := range steps {
+		fmt.Println(step)
+	}
 	if err != nil {
-		log.Error().Msg("Error deleting from project table: " + err.Error())
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
-		// Rollback the transaction if there's an error
-		tx.Rollback()
-		return
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
 	}
+	if len(steps) == 0 {
+		fmt.Println("nothing to migrate")
+	}
+	return exitOK
+}
 
-	if rowAffected, _ := deleteProjectResult.RowsAffected(); rowAffected == 0 {
-		log.Error().Msg("No rows affected")
-		c.IndentedJSON(http.StatusNotFound, gin.H{"message": "not found"})
-		// Rollback the transaction if there's an error
-		tx.Rollback()
-		return
+const apiKeyUsage = "usage: apikey create -name <name> -scopes <read,write,delete,audit> [-expires <duration>] | list | revoke <id>"
+
+// runAPIKey runs the apikey subcommand:
+//
+//	apikey create -name n -scopes s [-expires d]   creates a key and prints it
+//	apikey list                                   lists the keys
+//	apikey revoke <id>                            revokes a key
+func runAPIKey(cfg *config.Config, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, apiKeyUsage)
+		return exitConfig
+	}
+	if cfg.Storage != "mysql" {
+		fmt.Fprintln(os.Stderr, "API keys need storage mysql")
+		return exitConfig
 	}
 
-	// Commit the transaction if all deletions were successful
-	err = tx.Commit()
+	db, err := openDatabase(cfg.Database)
 	if err != nil {
-		log.Error().Msg("Error committing transaction:" + err.Error())
-		c.IndentedJSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
-		return
+		fmt.Fprintln(os.Stderr, err)
+		return exitError
 	}
+	defer db.Close()
+	repo := newMySQLProjectRepository(db)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	switch {
+	case args[0] == "create":
+		fs := flag.NewFlagSet("apikey create", flag.ContinueOnError)
+		name := fs.String("name", "", "name of the service using the key")
+		scopes := fs.String("scopes", "", "comma separated permissions among read, write, delete and audit")
+		expires := fs.Duration("expires", 0, "lifetime of the key, 0 never expires")
+		if err := fs.Parse(args[1:]); err != nil || fs.NArg() > 0 {
+			fmt.Fprintln(os.Stderr, apiKeyUsage)
+			return exitConfig
+		}
+		if *name == "" {
+			fmt.Fprintln(os.Stderr, "-name is required")
+			return exitConfig
+		}
+		granted, err := parseScopes(*scopes)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "-scopes:", err)
+			return exitConfig
+		}
 
-	c.IndentedJSON(http.StatusOK, gin.H{"message": "Successfully deleted!"})
+		key, hash, err := newAPIKey()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		apiKey := apiKeyModel{Name: *name, Prefix: key[:apiKeyShownLength], Scopes: granted, CreatedAt: now}
+		if *expires > 0 {
+			apiKey.ExpiresAt = now.Add(*expires)
+		}
+		apiKey, err = repo.CreateAPIKey(ctx, apiKey, hash)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		fmt.Printf("Created API key %s for %s. Store it now, it cannot be shown again:\n%s\n", apiKey.ID, apiKey.Name, key)
+		return exitOK
+	case args[0] == "list" && len(args) == 1:
+		keys, err := repo.ListAPIKeys(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNAME\tKEY\tSCOPES\tCREATED\tEXPIRES\tLAST USED\tSTATE")
+		for _, key := range keys {
+			state := "active"
+			switch {
+			case !key.RevokedAt.IsZero():
+				state = "revoked at " + formatKeyTime(key.RevokedAt)
+			case !key.ExpiresAt.IsZero() && !now.Before(key.ExpiresAt):
+				state = "expired"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s...\t%s\t%s\t%s\t%s\t%s\n", key.ID, key.Name, key.Prefix, strings.Join(key.Scopes, ","),
+				formatKeyTime(key.CreatedAt), formatKeyTime(key.ExpiresAt), formatKeyTime(key.LastUsedAt), state)
+		}
+		w.Flush()
+		return exitOK
+	case args[0] == "revoke" && len(args) == 2:
+		if err := repo.RevokeAPIKey(ctx, args[1], now); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitError
+		}
+		fmt.Printf("Revoked API key %s\n", args[1])
+		return exitOK
+	default:
+		fmt.Fprintln(os.Stderr, apiKeyUsage)
+		return exitConfig
+	}
+}
 
+// formatKeyTime prints the times of API keys, a dash when unset.
+func formatKeyTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
 }
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getMembers godoc
+// @Summary      Get members of a project
+// @Description  Get the people taking part in a project, the leader first
+// @Tags         Members
+// @Produce      json
+// @Param        id   path      int  true  "Project ID"
+// @Success      200  {object}  memberList
+// @Failure      401  {object}  HTTPError
+// @Failure      403  {object}  HTTPError
+// @Failure      404  {object}  HTTPError
+// @Failure      429  {object}  HTTPError
+// @Failure      500  {object}  HTTPError
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /projects/{id}/members [get]
+func (h *projectHandler) getMembers(c *gin.Context) {
+	id := c.Param("id")
+
+	members, err := h.repo.ListMembers(c.Request.Context(), id)
+	if err != nil {
+		c.Error(fmt.Errorf("listing members of project %s: %w", id, err))
+		return
+	}
+	if members == nil {
+		members = []memberModel{}
+	}
+
+	c.IndentedJSON(http.StatusOK, memberList{Data: members})
+}
+
+// putMember godoc
+// @Summary      Set member of a project
+// @Description  Add a person to a project or change their role. Making someone leader changes the leader of the project, the former leader staying on as a member, and needs the delete permission.
+// @Tags         Members
+// @Accept       json
+// @Produce      json
+// @Param        id        path      int                true  "Project ID"
+// @Param        personId  path      int                true  "Person ID"
+// @Param        role      body      memberRoleRequest  true  "Role of the person"
+// @Success      200  {object}  memberModel
+// @Failure      400  {object}  HTTPError
+// @Failure      401  {object}  HTTPError
+// @Failure      403  {object}  HTTPError
+// @Failure      404  {object}  HTTPError
+// @Failure      409  {object}  HTTPError
+// @Failure      422  {object}  HTTPError
+// @Failure      429  {object}  HTTPError
+// @Failure      500  {object}  HTTPError
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /projects/{id}/members/{personId} [put]
+func (h *projectHandler) putMember(c *gin.Context) {
+	id, personID := c.Param("id"), c.Param("personId")
+
+	var request memberRoleRequest
+	if err := bindBody(c, &request, "a member"); err != nil {
+		c.Error(err)
+		return
+	}
+	if request.Role == roleLeader {
+		if err := requirePermission(c, permissionDelete); err != nil {
+			c.Error(fmt.Errorf("making %s leader of project %s: %w", personID, id, err))
+			return
+		}
+	}
+
+	member, err := h.repo.SetMember(c.Request.Context(), id, personID, request.Role)
+	if err != nil {
+		c.Error(fmt.Errorf("setting member %s of project %s: %w", personID, id, err))
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, member)
+}
+
+// deleteMember godoc
+// @Summary      Remove member from a project
+// @Description  Remove a person from a project. The leader cannot be removed, make someone else leader first.
+// @Tags         Members
+// @Produce      json
+// @Param        id        path      int  true  "Project ID"
+// @Param        personId  path      int  true  "Person ID"
+// @Success      200  {object}  HTTPSuccess
+// @Failure      401  {object}  HTTPError
+// @Failure      403  {object}  HTTPError
+// @Failure      404  {object}  HTTPError
+// @Failure      409  {object}  HTTPError
+// @Failure      429  {object}  HTTPError
+// @Failure      500  {object}  HTTPError
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /projects/{id}/members/{personId} [delete]
+func (h *projectHandler) deleteMember(c *gin.Context) {
+	id, personID := c.Param("id"), c.Param("personId")
+
+	if err := h.repo.DeleteMember(c.Request.Context(), id, personID); err != nil {
+		c.Error(fmt.Errorf("removing member %s from project %s: %w", personID, id, err))
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{"message": "Successfully deleted!"})
+}
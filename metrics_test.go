@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRequestMetrics(t *testing.T) {
+	router := gin.New()
+	router.Use(requestMetrics())
+	router.GET("/api/v1/things/:id", func(c *gin.Context) { c.String(http.StatusTeapot, "tea") })
+	router.GET("/metrics", metricsHandler())
+
+	request(router, http.MethodGet, "/api/v1/things/1", "")
+	request(router, http.MethodGet, "/api/v1/things/2", "")
+
+	w := request(router, http.MethodGet, "/metrics", "")
+	checkStatus(t, w, http.StatusOK)
+	for _, want := range []string{
+		`http_requests_total{method="GET",route="/api/v1/things/:id",status="418"} 2`,
+		`http_request_duration_seconds_count{method="GET",route="/api/v1/things/:id"} 2`,
+		`http_response_size_bytes_sum{method="GET",route="/api/v1/things/:id"} 6`,
+		// The request for /metrics itself is still being served
+		`http_requests_in_flight 1`,
+	} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("metrics do not contain %s", want)
+		}
+	}
+}
+
+func TestObserveQuery(t *testing.T) {
+	observeQuery(context.Background(), "test_ok", "SELECT 1")(nil)
+	observeQuery(context.Background(), "test_no_rows", "SELECT 1")(sql.ErrNoRows)
+	observeQuery(context.Background(), "test_failed", "SELECT 1")(errors.New("connection reset"))
+
+	router := gin.New()
+	router.GET("/metrics", metricsHandler())
+	w := request(router, http.MethodGet, "/metrics", "")
+	checkStatus(t, w, http.StatusOK)
+
+	for _, want := range []string{
+		`db_query_duration_seconds_count{query="test_ok",status="ok"} 1`,
+		`db_query_duration_seconds_count{query="test_no_rows",status="ok"} 1`,
+		`db_query_duration_seconds_count{query="test_failed",status="error"} 1`,
+	} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("metrics do not contain %s", want)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// money is an amount in the minor units of an ISO 4217 currency, e.g. cents
+// for USD. In JSON the amount is a decimal string in major units, so that it
+// survives clients parsing numbers as floats:
+//
+//	{"amount": "1500.25", "currency": "USD"}
+type money struct {
+	Amount   int64  `json:"amount" swaggertype:"string" example:"1500.25"`
+	Currency string `json:"currency" binding:"currency" example:"USD"`
+}
+
+// currencyDigits holds the number of minor unit digits of the currencies
+// whose minor unit is not a hundredth.
+var currencyDigits = map[string]int{
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0, "PYG": 0,
+	"RWF": 0, "UGX": 0, "UYI": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"CLF": 4, "UYW": 4,
+}
+
+// currencies lists the active ISO 4217 currency codes.
+var currencies = func() map[string]bool {
+	codes := strings.Fields(`
+		AED AFN ALL AMD ANG AOA ARS AUD AWG AZN BAM BBD BDT BGN BHD BIF BMD BND BOB BOV BRL
+		BSD BTN BWP BYN BZD CAD CDF CHE CHF CHW CLF CLP CNY COP COU CRC CUP CVE CZK DJF DKK
+		DOP DZD EGP ERN ETB EUR FJD FKP GBP GEL GHS GIP GMD GNF GTQ GYD HKD HNL HTG HUF IDR
+		ILS INR IQD IRR ISK JMD JOD JPY KES KGS KHR KMF KPW KRW KWD KYD KZT LAK LBP LKR LRD
+		LSL LYD MAD MDL MGA MKD MMK MNT MOP MRU MUR MVR MWK MXN MXV MYR MZN NAD NGN NIO NOK
+		NPR NZD OMR PAB PEN PGK PHP PKR PLN PYG QAR RON RSD RUB RWF SAR SBD SCR SDG SEK SGD
+		SHP SLE SOS SRD SSP STN SVC SYP SZL THB TJS TMT TND TOP TRY TTD TWD TZS UAH UGX USD
+		USN UYI UYU UYW UZS VED VES VND VUV WST XAF XCD XOF XPF YER ZAR ZMW ZWL`)
+	set := make(map[string]bool, len(codes))
+	for _, code := range codes {
+		set[code] = true
+	}
+	return set
+}()
+
+// minorDigits returns the number of digits of the minor unit of currency.
+func minorDigits(currency string) (int, error) {
+	if !currencies[currency] {
+		return 0, fmt.Errorf("unknown currency %q, use an ISO 4217 code", currency)
+	}
+	if digits, ok := currencyDigits[currency]; ok {
+		return digits, nil
+	}
+	return 2, nil
+}
+
+// parseMoney parses a decimal amount in the major units of currency. It
+// rejects amounts more precise than the minor unit of the currency.
+func parseMoney(amount, currency string) (money, error) {
+	digits, err := minorDigits(currency)
+	if err != nil {
+		return money{}, err
+	}
+
+	s := amount
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	whole, fraction, hasFraction := strings.Cut(s, ".")
+	if whole == "" || (hasFraction && fraction == "") || !isDigits(whole) || !isDigits(fraction) {
+		return money{}, fmt.Errorf("amount %q is not a decimal number", amount)
+	}
+	if len(fraction) > digits {
+		return money{}, fmt.Errorf("amount %q has more than %d decimals, the minor unit of %s", amount, digits, currency)
+	}
+	fraction += strings.Repeat("0", digits-len(fraction))
+
+	minor, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil {
+		return money{}, fmt.Errorf("amount %q is too large", amount)
+	}
+	if negative {
+		minor = -minor
+	}
+	return money{Amount: minor, Currency: currency}, nil
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats m as its amount in major units followed by its currency,
+// e.g. "1500.25 USD".
+func (m money) String() string {
+	return m.decimal() + " " + m.Currency
+}
+
+// decimal formats the amount of m in major units.
+func (m money) decimal() string {
+	digits, err := minorDigits(m.Currency)
+	if err != nil {
+		return strconv.FormatInt(m.Amount, 10)
+	}
+
+	sign := ""
+	abs := uint64(m.Amount)
+	if m.Amount < 0 {
+		sign = "-"
+		abs = uint64(-m.Amount)
+	}
+	s := strconv.FormatUint(abs, 10)
+	if digits == 0 {
+		return sign + s
+	}
+	if len(s) <= digits {
+		s = strings.Repeat("0", digits-len(s)+1) + s
+	}
+	return sign + s[:len(s)-digits] + "." + s[len(s)-digits:]
+}
+
+type moneyJSON struct {
+	Amount   json.RawMessage `json:"amount"`
+	Currency string          `json:"currency"`
+}
+
+func (m money) MarshalJSON() ([]byte, error) {
+	amount, _ := json.Marshal(m.decimal())
+	return json.Marshal(moneyJSON{Amount: amount, Currency: m.Currency})
+}
+
+// UnmarshalJSON accepts the amount as a decimal string or a JSON number. An
+// unknown currency is kept as is for validation to report it.
+func (m *money) UnmarshalJSON(b []byte) error {
+	var v moneyJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if v.Amount == nil {
+		return errors.New("money needs an amount")
+	}
+	if _, err := minorDigits(v.Currency); err != nil {
+		*m = money{Currency: v.Currency}
+		return nil
+	}
+
+	amount := string(bytes.TrimSpace(v.Amount))
+	if strings.HasPrefix(amount, `"`) {
+		if err := json.Unmarshal(v.Amount, &amount); err != nil {
+			return err
+		}
+	} else if strings.ContainsAny(amount, "eE") {
+		return fmt.Errorf("amount %s must not use an exponent", amount)
+	}
+
+	parsed, err := parseMoney(amount, v.Currency)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		want     int64
+		wantErr  bool
+	}{
+		{amount: "1500.25", currency: "USD", want: 150025},
+		{amount: "1500.2", currency: "USD", want: 150020},
+		{amount: "1500", currency: "USD", want: 150000},
+		{amount: "0.05", currency: "EUR", want: 5},
+		{amount: "-12.5", currency: "EUR", want: -1250},
+		{amount: "1500", currency: "JPY", want: 1500},
+		{amount: "1.234", currency: "KWD", want: 1234},
+		{amount: "1.2345", currency: "CLF", want: 12345},
+		// Amounts are never rounded to the minor unit
+		{amount: "1500.255", currency: "USD", wantErr: true},
+		{amount: "1500.5", currency: "JPY", wantErr: true},
+		{amount: "1.2345", currency: "KWD", wantErr: true},
+		{amount: "1500", currency: "ABC", wantErr: true},
+		{amount: "1500", currency: "usd", wantErr: true},
+		{amount: "", currency: "USD", wantErr: true},
+		{amount: "1500.", currency: "USD", wantErr: true},
+		{amount: ".5", currency: "USD", wantErr: true},
+		{amount: "1,500", currency: "USD", wantErr: true},
+		{amount: "+1500", currency: "USD", wantErr: true},
+		{amount: "1e3", currency: "USD", wantErr: true},
+		{amount: "99999999999999999999", currency: "USD", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.amount+" "+tt.currency, func(t *testing.T) {
+			got, err := parseMoney(tt.amount, tt.currency)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parseMoney() = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil || got != (money{Amount: tt.want, Currency: tt.currency}) {
+				t.Errorf("parseMoney() = %+v, %v, want %d", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		money money
+		want  string
+	}{
+		{money: money{Amount: 150025, Currency: "USD"}, want: "1500.25 USD"},
+		{money: money{Amount: 5, Currency: "EUR"}, want: "0.05 EUR"},
+		{money: money{Amount: -1250, Currency: "EUR"}, want: "-12.50 EUR"},
+		{money: money{Amount: 1500, Currency: "JPY"}, want: "1500 JPY"},
+		{money: money{Amount: 1234, Currency: "KWD"}, want: "1.234 KWD"},
+	}
+	for _, tt := range tests {
+		if got := tt.money.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    money
+		out     string
+		wantErr bool
+	}{
+		{in: `{"amount": "1500.25", "currency": "USD"}`, want: money{Amount: 150025, Currency: "USD"}, out: `{"amount":"1500.25","currency":"USD"}`},
+		{in: `{"amount": 1500.25, "currency": "USD"}`, want: money{Amount: 150025, Currency: "USD"}, out: `{"amount":"1500.25","currency":"USD"}`},
+		{in: `{"amount": 7, "currency": "JPY"}`, want: money{Amount: 7, Currency: "JPY"}, out: `{"amount":"7","currency":"JPY"}`},
+		// Unknown currencies are left for validation to report
+		{in: `{"amount": "1", "currency": "ABC"}`, want: money{Currency: "ABC"}, out: `{"amount":"0","currency":"ABC"}`},
+		{in: `{"amount": 1e3, "currency": "USD"}`, wantErr: true},
+		{in: `{"amount": "1.001", "currency": "USD"}`, wantErr: true},
+		{in: `{"currency": "USD"}`, wantErr: true},
+		{in: `"1500 USD"`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			var got money
+			err := json.Unmarshal([]byte(tt.in), &got)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("unmarshalled %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("unmarshalled %+v, %v, want %+v", got, err, tt.want)
+			}
+			if b, err := json.Marshal(got); err != nil || string(b) != tt.out {
+				t.Errorf("marshalled %s, %v, want %s", b, err, tt.out)
+			}
+		})
+	}
+}
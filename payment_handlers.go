@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getPayments godoc
+// @Summary      Get payments of a project
+// @Description  Get the payments recorded towards the budget of a project, oldest first
+// @Tags         Payments
+// @Produce      json
+// @Param        id   path      int  true  "Project ID"
+// @Success      200  {object}  paymentList
+// @Failure      401  {object}  HTTPError
+// @Failure      403  {object}  HTTPError
+// @Failure      404  {object}  HTTPError
+// @Failure      429  {object}  HTTPError
+// @Failure      500  {object}  HTTPError
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /projects/{id}/payments [get]
+func (h *projectHandler) getPayments(c *gin.Context) {
+	id := c.Param("id")
+
+	payments, err := h.repo.ListPayments(c.Request.Context(), id)
+	if err != nil {
+		c.Error(fmt.Errorf("listing payments of project %s: %w", id, err))
+		return
+	}
+	if payments == nil {
+		payments = []paymentModel{}
+	}
+
+	c.IndentedJSON(http.StatusOK, paymentList{Data: payments})
+}
+
+// getPaymentById godoc
+// @Summary      Get payment by id
+// @Description  Get a payment of a project by id
+// @Tags         Payments
+// @Produce      json
+// @Param        id         path      int  true  "Project ID"
+// @Param        paymentId  path      int  true  "Payment ID"
+// @Success      200  {object}  paymentModel
+// @Failure      401  {object}  HTTPError
+// @Failure      403  {object}  HTTPError
+// @Failure      404  {object}  HTTPError
+// @Failure      429  {object}  HTTPError
+// @Failure      500  {object}  HTTPError
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /projects/{id}/payments/{paymentId} [get]
+func (h *projectHandler) getPaymentById(c *gin.Context) {
+	id, paymentID := c.Param("id"), c.Param("paymentId")
+
+	payment, err := h.repo.GetPayment(c.Request.Context(), id, paymentID)
+	if err != nil {
+		c.Error(fmt.Errorf("getting payment %s of project %s: %w", paymentID, id, err))
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, payment)
+}
+
+// postPayment godoc
+// @Summary      Record payment
+// @Description  Record a payment towards the budget of a project. It must be in the currency of the budget and must not exceed the remaining balance. Completed projects take no more payments.
+// @Tags         Payments
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int           true  "Project ID"
+// @Param        payment  body      paymentModel  true  "Add payment"
+// @Success      201  {object}  paymentModel
+// @Failure      400  {object}  HTTPError
+// @Failure      401  {object}  HTTPError
+// @Failure      403  {object}  HTTPError
+// @Failure      404  {object}  HTTPError
+// @Failure      409  {object}  HTTPError
+// @Failure      422  {object}  HTTPError
+// @Failure      429  {object}  HTTPError
+// @Failure      500  {object}  HTTPError
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /projects/{id}/payments [post]
+func (h *projectHandler) postPayment(c *gin.Context) {
+	id := c.Param("id")
+
+	var newPayment paymentModel
+	if err := bindBody(c, &newPayment, "a payment"); err != nil {
+		c.Error(err)
+		return
+	}
+
+	created, err := h.repo.CreatePayment(c.Request.Context(), id, newPayment)
+	if err != nil {
+		c.Error(fmt.Errorf("recording payment for project %s: %w", id, err))
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, created)
+}
+
+// deletePayment godoc
+// @Summary      Delete payment by id
+// @Description  Delete a payment recorded by mistake, unless the project is completed
+// @Tags         Payments
+// @Produce      json
+// @Param        id         path      int  true  "Project ID"
+// @Param        paymentId  path      int  true  "Payment ID"
+// @Success      200  {object}  HTTPSuccess
+// @Failure      401  {object}  HTTPError
+// @Failure      403  {object}  HTTPError
+// @Failure      404  {object}  HTTPError
+// @Failure      409  {object}  HTTPError
+// @Failure      429  {object}  HTTPError
+// @Failure      500  {object}  HTTPError
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /projects/{id}/payments/{paymentId} [delete]
+func (h *projectHandler) deletePayment(c *gin.Context) {
+	id, paymentID := c.Param("id"), c.Param("paymentId")
+
+	if err := h.repo.DeletePayment(c.Request.Context(), id, paymentID); err != nil {
+		c.Error(fmt.Errorf("deleting payment %s of project %s: %w", paymentID, id, err))
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{"message": "Successfully deleted!"})
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"math"
+)
+
+// paymentModel is an installment paid towards the budget of a project.
+type paymentModel struct {
+	ID     string   `json:"id"`
+	Amount money    `json:"amount"`
+	PaidAt dateTime `json:"paid_at" binding:"required,past_date" swaggertype:"string" format:"date-time" example:"2024-03-01T00:00:00Z"`
+	Payer  string   `json:"payer" binding:"required,max=255" example:"Acme Corp"`
+	Note   string   `json:"note" binding:"max=1000" example:"Second installment"`
+}
+
+type paymentList struct {
+	Data []paymentModel `json:"data"`
+}
+
+// paymentSummary is computed from the payments ledger of a project. It is
+// part of the budget responses and ignored in request bodies.
+type paymentSummary struct {
+	Paid        money   `json:"paid" readonly:"true"`
+	Remaining   money   `json:"remaining" readonly:"true"`
+	PercentPaid float64 `json:"percent_paid" readonly:"true" example:"25.5"`
+}
+
+// summarizePayments returns the summary of a budget of budgetValue of which
+// paid minor units have been paid. The percentage is rounded to hundredths.
+func summarizePayments(budgetValue money, paid int64) paymentSummary {
+	summary := paymentSummary{
+		Paid:      money{Amount: paid, Currency: budgetValue.Currency},
+		Remaining: money{Amount: budgetValue.Amount - paid, Currency: budgetValue.Currency},
+	}
+	if budgetValue.Amount > 0 {
+		summary.PercentPaid = math.Round(float64(paid)*10000/float64(budgetValue.Amount)) / 100
+	}
+	return summary
+}
+
+var (
+	// errPaymentNotFound is returned by a PaymentRepository when the project
+	// has no payment with the requested id.
+	errPaymentNotFound = errors.New("payment not found")
+	// errPaymentCurrency is returned when a payment is not in the currency of
+	// the budget it is paid towards.
+	errPaymentCurrency = errors.New("payment is not in the currency of the budget")
+	// errPaymentExceedsBalance is returned when a payment is larger than the
+	// remaining balance of the budget.
+	errPaymentExceedsBalance = errors.New("payment exceeds the remaining balance")
+	// errBudgetBelowPaid is returned when a budget would be lowered below the
+	// amount already paid.
+	errBudgetBelowPaid = errors.New("budget value is below the amount paid")
+	// errBudgetCurrencyPaid is returned when the currency of a budget would
+	// change although payments have been recorded in it.
+	errBudgetCurrencyPaid = errors.New("budget currency cannot change once payments are recorded")
+)
+
+// checkPayment checks that payment can be paid towards the budget of
+// project.
+func checkPayment(project projectModel, payment paymentModel) error {
+	if payment.Amount.Currency != project.Budget.BudgetValue.Currency {
+		return errPaymentCurrency
+	}
+	if payment.Amount.Amount > project.Budget.Remaining.Amount {
+		return errPaymentExceedsBalance
+	}
+	return nil
+}
+
+// checkPaidBudget checks that the budget of current can become the budget of
+// updated given the payments already recorded.
+func checkPaidBudget(current, updated projectModel) error {
+	paid := current.Budget.Paid.Amount
+	if paid == 0 {
+		return nil
+	}
+	if updated.Budget.BudgetValue.Currency != current.Budget.BudgetValue.Currency {
+		return errBudgetCurrencyPaid
+	}
+	if updated.Budget.BudgetValue.Amount < paid {
+		return errBudgetBelowPaid
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSummarizePayments(t *testing.T) {
+	tests := []struct {
+		name    string
+		budget  money
+		paid    int64
+		percent float64
+	}{
+		{name: "nothing paid", budget: money{Amount: 300000, Currency: "USD"}, paid: 0, percent: 0},
+		{name: "rounded percentage", budget: money{Amount: 300000, Currency: "USD"}, paid: 100000, percent: 33.33},
+		{name: "fully paid", budget: money{Amount: 300000, Currency: "USD"}, paid: 300000, percent: 100},
+		{name: "zero budget", budget: money{Amount: 0, Currency: "JPY"}, paid: 0, percent: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := summarizePayments(tt.budget, tt.paid)
+			want := paymentSummary{
+				Paid:        money{Amount: tt.paid, Currency: tt.budget.Currency},
+				Remaining:   money{Amount: tt.budget.Amount - tt.paid, Currency: tt.budget.Currency},
+				PercentPaid: tt.percent,
+			}
+			if got != want {
+				t.Errorf("summarizePayments() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+// paymentBody returns the JSON body of a payment paid yesterday.
+func paymentBody(amount, currency string) string {
+	return `{"amount": {"amount": "` + amount + `", "currency": "` + currency + `"}, "paid_at": "` +
+		time.Now().AddDate(0, 0, -1).Format(time.DateOnly) + `", "payer": "Acme Corp"}`
+}
+
+func TestPaymentHandlers(t *testing.T) {
+	router, _ := newTestServer(t)
+	jane := createPerson(t, router, "Jane Doe")
+	proj := createProject(t, router, projectBody("Bridge", jane, "3000", "USD", futureDeadline))
+	payments := "/api/v1/projects/" + proj.ID + "/payments"
+
+	// budgetOf returns the budget of the project, with its payment summary
+	budgetOf := func(t *testing.T) budgetModel {
+		t.Helper()
+		w := request(router, http.MethodGet, "/api/v1/projects/"+proj.ID, "")
+		checkStatus(t, w, http.StatusOK)
+		var got projectModel
+		decode(t, w, &got)
+		return got.Budget
+	}
+
+	w := request(router, http.MethodPost, payments, paymentBody("1000.50", "USD"))
+	checkStatus(t, w, http.StatusCreated)
+	var first paymentModel
+	decode(t, w, &first)
+	if first.ID == "" || first.Amount != (money{Amount: 100050, Currency: "USD"}) || first.Payer != "Acme Corp" {
+		t.Errorf("created payment = %+v", first)
+	}
+	checkStatus(t, request(router, http.MethodPost, payments, paymentBody("999.50", "USD")), http.StatusCreated)
+
+	budget := budgetOf(t)
+	if budget.Paid.Amount != 200000 || budget.Remaining.Amount != 100000 || budget.PercentPaid != 66.67 {
+		t.Errorf("summary = %+v after paying 2000.00 of 3000.00", budget.paymentSummary)
+	}
+
+	w = request(router, http.MethodGet, payments, "")
+	checkStatus(t, w, http.StatusOK)
+	var list paymentList
+	decode(t, w, &list)
+	if len(list.Data) != 2 || list.Data[0].ID != first.ID {
+		t.Errorf("payments = %+v, want both, oldest first", list.Data)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		field  string
+		rule   string
+	}{
+		{name: "get", method: http.MethodGet, path: payments + "/" + first.ID, status: http.StatusOK},
+		{name: "get missing", method: http.MethodGet, path: payments + "/999", status: http.StatusNotFound},
+		{name: "list of missing project", method: http.MethodGet, path: "/api/v1/projects/999/payments", status: http.StatusNotFound},
+		{name: "pay missing project", method: http.MethodPost, path: "/api/v1/projects/999/payments", body: paymentBody("1", "USD"), status: http.StatusNotFound},
+		{name: "exceeding the balance", method: http.MethodPost, path: payments, body: paymentBody("1000.01", "USD"), status: http.StatusUnprocessableEntity, field: "amount", rule: "lte_remaining"},
+		{name: "other currency", method: http.MethodPost, path: payments, body: paymentBody("10", "EUR"), status: http.StatusUnprocessableEntity, field: "amount.currency", rule: "same_currency"},
+		{name: "zero amount", method: http.MethodPost, path: payments, body: paymentBody("0", "USD"), status: http.StatusUnprocessableEntity, field: "amount", rule: "gt"},
+		{
+			name: "paid in the future", method: http.MethodPost, path: payments,
+			body:   `{"amount": {"amount": "1", "currency": "USD"}, "paid_at": "` + futureDeadline + `", "payer": "Acme Corp"}`,
+			status: http.StatusUnprocessableEntity, field: "paid_at", rule: "past_date",
+		},
+		{
+			name: "budget below the amount paid", method: http.MethodPut, path: "/api/v1/projects/" + proj.ID,
+			body:   projectBody("Bridge", jane, "1999.99", "USD", futureDeadline),
+			status: http.StatusUnprocessableEntity, field: "budget.budget_value", rule: "gte_paid",
+		},
+		{
+			name: "budget currency changed", method: http.MethodPut, path: "/api/v1/projects/" + proj.ID,
+			body:   projectBody("Bridge", jane, "3000", "EUR", futureDeadline),
+			status: http.StatusUnprocessableEntity, field: "budget.budget_value.currency", rule: "paid_currency",
+		},
+		{
+			name: "budget lowered to the amount paid", method: http.MethodPut, path: "/api/v1/projects/" + proj.ID,
+			body:   projectBody("Bridge", jane, "2000", "USD", futureDeadline),
+			status: http.StatusOK,
+		},
+		{name: "delete", method: http.MethodDelete, path: payments + "/" + first.ID, status: http.StatusOK},
+		{name: "delete again", method: http.MethodDelete, path: payments + "/" + first.ID, status: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := request(router, tt.method, tt.path, tt.body)
+			if tt.field != "" {
+				checkFieldError(t, w, tt.field, tt.rule)
+				return
+			}
+			checkStatus(t, w, tt.status)
+		})
+	}
+
+	budget = budgetOf(t)
+	if budget.Paid.Amount != 99950 || budget.Remaining.Amount != 100050 || budget.PercentPaid != 49.98 {
+		t.Errorf("summary = %+v after deleting the first payment", budget.paymentSummary)
+	}
+}
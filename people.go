@@ -0,0 +1,74 @@
+package main
+
+import (
+	"errors"
+	"sort"
+)
+
+// personModel is someone who can lead or take part in projects.
+type personModel struct {
+	ID    string `json:"id"`
+	Name  string `json:"name" binding:"required,max=255" example:"Jane Doe"`
+	Email string `json:"email,omitempty" binding:"omitempty,email,max=255" example:"jane@example.com"`
+}
+
+type personList struct {
+	Data []personModel `json:"data"`
+}
+
+// Roles of the members of a project. A project has exactly one leader, the
+// person referenced by projectModel.LeaderID.
+const (
+	roleLeader = "leader"
+	roleMember = "member"
+	roleViewer = "viewer"
+)
+
+// memberModel is a person taking part in a project.
+type memberModel struct {
+	PersonID string `json:"person_id" example:"7"`
+	Name     string `json:"name" example:"Jane Doe"`
+	Role     string `json:"role" enums:"leader,member,viewer" example:"member"`
+}
+
+type memberList struct {
+	Data []memberModel `json:"data"`
+}
+
+// memberRoleRequest is the body setting the role of a member.
+type memberRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=leader member viewer" enums:"leader,member,viewer" example:"member"`
+}
+
+var (
+	// errPersonNotFound is returned by a PersonRepository when no person
+	// matches the requested id.
+	errPersonNotFound = errors.New("person not found")
+	// errMemberNotFound is returned by a MemberRepository when the person is
+	// not a member of the project.
+	errMemberNotFound = errors.New("member not found")
+	// errLeaderNotFound is returned when the leader of a project is not an
+	// existing person.
+	errLeaderNotFound = errors.New("leader is not an existing person")
+	// errPersonInUse is returned when deleting a person who still leads or
+	// belongs to projects.
+	errPersonInUse = errors.New("person still leads or belongs to projects")
+	// errDuplicateEmail is returned when another person has the same email.
+	errDuplicateEmail = errors.New("a person with this email already exists")
+	// errLeaderRequired is returned when removing the leader of a project or
+	// giving them another role, which would leave the project leaderless.
+	errLeaderRequired = errors.New("a project needs a leader")
+)
+
+// roleOrder lists the roles from the most to the least involved.
+var roleOrder = map[string]int{roleLeader: 0, roleMember: 1, roleViewer: 2}
+
+// sortMembers orders members by role, then by name.
+func sortMembers(members []memberModel) {
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].Role != members[j].Role {
+			return roleOrder[members[i].Role] < roleOrder[members[j].Role]
+		}
+		return members[i].Name < members[j].Name
+	})
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getPeople godoc
+// @Summary      Get people
+// @Description  Get every person who can lead or take part in projects, ordered by name
+// @Tags         People
+// @Produce      json
+// @Success      200  {object}  personList
+// @Failure      401  {object}  HTTPError
+// @Failure      403  {object}  HTTPError
+// @Failure      429  {object}  HTTPError
+// @Failure      500  {object}  HTTPError
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /people [get]
+func (h *projectHandler) getPeople(c *gin.Context) {
+	people, err := h.repo.ListPeople(c.Request.Context())
+	if err != nil {
+		c.Error(fmt.Errorf("listing people: %w", err))
+		return
+	}
+	if people == nil {
+		people = []personModel{}
+	}
+
+	c.IndentedJSON(http.StatusOK, personList{Data: people})
+}
+
+// getPersonById godoc
+// @Summary      Get person by id
+// @Description  Get person by id
+// @Tags         People
+// @Produce      json
+// @Param        id   path      int  true  "Person ID"
+// @Success      200  {object}  personModel
+// @Failure      401  {object}  HTTPError
+// @Failure      403  {object}  HTTPError
+// @Failure      404  {object}  HTTPError
+// @Failure      429  {object}  HTTPError
+// @Failure      500  {object}  HTTPError
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /people/{id} [get]
+func (h *projectHandler) getPersonById(c *gin.Context) {
+	id := c.Param("id")
+
+	person, err := h.repo.GetPerson(c.Request.Context(), id)
+	if err != nil {
+		c.Error(fmt.Errorf("getting person %s: %w", id, err))
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, person)
+}
+
+// postPeople godoc
+// @Summary      Post person
+// @Description  Add a person who can lead or take part in projects
+// @Tags         People
+// @Accept       json
+// @Produce      json
+// @Param        person  body      personModel  true  "Add person"
+// @Success      201  {object}  personModel
+// @Failure      400  {object}  HTTPError
+// @Failure      401  {object}  HTTPError
+// @Failure      403  {object}  HTTPError
+// @Failure      409  {object}  HTTPError
+// @Failure      422  {object}  HTTPError
+// @Failure      429  {object}  HTTPError
+// @Failure      500  {object}  HTTPError
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /people [post]
+func (h *projectHandler) postPeople(c *gin.Context) {
+	var newPerson personModel
+	if err := bindBody(c, &newPerson, "a person"); err != nil {
+		c.Error(err)
+		return
+	}
+
+	created, err := h.repo.CreatePerson(c.Request.Context(), newPerson)
+	if err != nil {
+		c.Error(fmt.Errorf("creating person: %w", err))
+		return
+	}
+
+	c.IndentedJSON(http.StatusCreated, created)
+}
+
+// updatePerson godoc
+// @Summary      Update person by id
+// @Description  Update person by id. Changing the email needs the delete permission, as it decides which projects the person leads.
+// @Tags         People
+// @Accept       json
+// @Produce      json
+// @Param        id      path      int          true  "Person ID"
+// @Param        person  body      personModel  true  "Updated person"
+// @Success      200  {object}  personModel
+// @Failure      400  {object}  HTTPError
+// @Failure      401  {object}  HTTPError
+// @Failure      403  {object}  HTTPError
+// @Failure      404  {object}  HTTPError
+// @Failure      409  {object}  HTTPError
+// @Failure      422  {object}  HTTPError
+// @Failure      429  {object}  HTTPError
+// @Failure      500  {object}  HTTPError
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /people/{id} [put]
+func (h *projectHandler) updatePerson(c *gin.Context) {
+	id := c.Param("id")
+
+	var person personModel
+	if err := bindBody(c, &person, "a person"); err != nil {
+		c.Error(err)
+		return
+	}
+	// The email decides which projects its owner leads
+	if denied := requirePermission(c, permissionDelete); denied != nil {
+		current, err := h.repo.GetPerson(c.Request.Context(), id)
+		if err == nil && !strings.EqualFold(current.Email, person.Email) {
+			err = fmt.Errorf("changing the email: %w", denied)
+		}
+		if err != nil {
+			c.Error(fmt.Errorf("updating person %s: %w", id, err))
+			return
+		}
+	}
+
+	updated, err := h.repo.UpdatePerson(c.Request.Context(), id, person)
+	if err != nil {
+		c.Error(fmt.Errorf("updating person %s: %w", id, err))
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, updated)
+}
+
+// deletePerson godoc
+// @Summary      Delete person by id
+// @Description  Delete a person who no longer leads or belongs to any project
+// @Tags         People
+// @Produce      json
+// @Param        id   path      int  true  "Person ID"
+// @Success      200  {object}  HTTPSuccess
+// @Failure      401  {object}  HTTPError
+// @Failure      403  {object}  HTTPError
+// @Failure      404  {object}  HTTPError
+// @Failure      409  {object}  HTTPError
+// @Failure      429  {object}  HTTPError
+// @Failure      500  {object}  HTTPError
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /people/{id} [delete]
+func (h *projectHandler) deletePerson(c *gin.Context) {
+	id := c.Param("id")
+
+	if err := h.repo.DeletePerson(c.Request.Context(), id); err != nil {
+		c.Error(fmt.Errorf("deleting person %s: %w", id, err))
+		return
+	}
+
+	c.IndentedJSON(http.StatusOK, gin.H{"message": "Successfully deleted!"})
+}
+
+// getPersonProjects godoc
+// @Summary      Get projects of a person
+// @Description  Get a page of the projects a person leads or belongs to. It takes the paging, sorting and filter parameters of GET /projects.
+// @Tags         People
+// @Produce      json
+// @Param        id      path      int     true   "Person ID"
+// @Param        role    query     string  false  "Only projects where the person has this role"  Enums(leader, member, viewer)
+// @Param        limit   query     int     false  "Maximum number of projects to return"  default(100)  maximum(1000)
+// @Param        cursor  query     string  false  "Cursor taken from links.next or links.prev"
+// @Success      200  {object}  projectPage
+// @Failure      400  {object}  HTTPError
+// @Failure      401  {object}  HTTPError
+// @Failure      403  {object}  HTTPError
+// @Failure      404  {object}  HTTPError
+// @Failure      429  {object}  HTTPError
+// @Failure      500  {object}  HTTPError
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /people/{id}/projects [get]
+func (h *projectHandler) getPersonProjects(c *gin.Context) {
+	id := c.Param("id")
+
+	query, err := parseProjectListQuery(c.Request.URL.Query(), time.Now())
+	if err != nil {
+		c.Error(newAPIError(http.StatusBadRequest, codeInvalidQuery, err.Error()))
+		return
+	}
+	query.MemberID = id
+	query.MemberRole = c.Query("role")
+	if _, ok := roleOrder[query.MemberRole]; query.MemberRole != "" && !ok {
+		c.Error(newAPIError(http.StatusBadRequest, codeInvalidQuery, "role must be one of leader, member, viewer"))
+		return
+	}
+
+	if _, err := h.repo.GetPerson(c.Request.Context(), id); err != nil {
+		c.Error(fmt.Errorf("getting person %s: %w", id, err))
+		return
+	}
+
+	h.writeProjectPage(c, query)
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestPeopleHandlers(t *testing.T) {
+	router, _ := newTestServer(t)
+
+	// The steps share one repository and build on each other
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		code   string
+	}{
+		{name: "create", method: http.MethodPost, path: "/api/v1/people", body: `{"name": "Jane Doe", "email": "jane@example.com"}`, status: http.StatusCreated},
+		{name: "create without email", method: http.MethodPost, path: "/api/v1/people", body: `{"name": "John Roe"}`, status: http.StatusCreated},
+		{name: "duplicate email", method: http.MethodPost, path: "/api/v1/people", body: `{"name": "Janet", "email": "JANE@example.com"}`, status: http.StatusConflict, code: codeDuplicateKey},
+		{name: "invalid email", method: http.MethodPost, path: "/api/v1/people", body: `{"name": "Janet", "email": "janet"}`, status: http.StatusUnprocessableEntity, code: codeValidationFailed},
+		{name: "missing name", method: http.MethodPost, path: "/api/v1/people", body: `{"email": "janet@example.com"}`, status: http.StatusUnprocessableEntity, code: codeValidationFailed},
+		{name: "get", method: http.MethodGet, path: "/api/v1/people/1", status: http.StatusOK},
+		{name: "get missing", method: http.MethodGet, path: "/api/v1/people/999", status: http.StatusNotFound, code: codeNotFound},
+		{name: "update", method: http.MethodPut, path: "/api/v1/people/2", body: `{"name": "John Roe", "email": "john@example.com"}`, status: http.StatusOK},
+		{name: "update to a taken email", method: http.MethodPut, path: "/api/v1/people/2", body: `{"name": "John Roe", "email": "jane@example.com"}`, status: http.StatusConflict, code: codeDuplicateKey},
+		{name: "update keeping the email", method: http.MethodPut, path: "/api/v1/people/1", body: `{"name": "Jane Smith", "email": "jane@example.com"}`, status: http.StatusOK},
+		{name: "update missing", method: http.MethodPut, path: "/api/v1/people/999", body: `{"name": "Nobody"}`, status: http.StatusNotFound, code: codeNotFound},
+		{name: "delete", method: http.MethodDelete, path: "/api/v1/people/2", status: http.StatusOK},
+		{name: "delete again", method: http.MethodDelete, path: "/api/v1/people/2", status: http.StatusNotFound, code: codeNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := request(router, tt.method, tt.path, tt.body)
+			if tt.code != "" {
+				checkProblem(t, w, tt.status, tt.code)
+				return
+			}
+			checkStatus(t, w, tt.status)
+		})
+	}
+
+	w := request(router, http.MethodGet, "/api/v1/people", "")
+	checkStatus(t, w, http.StatusOK)
+	var list personList
+	decode(t, w, &list)
+	if len(list.Data) != 1 || list.Data[0] != (personModel{ID: "1", Name: "Jane Smith", Email: "jane@example.com"}) {
+		t.Errorf("people = %+v, want only Jane Smith", list.Data)
+	}
+}
+
+// memberNames returns the names and roles of the members of a project.
+func memberNames(t *testing.T, router http.Handler, projectID string) []string {
+	t.Helper()
+	w := request(router, http.MethodGet, "/api/v1/projects/"+projectID+"/members", "")
+	checkStatus(t, w, http.StatusOK)
+
+	var list memberList
+	decode(t, w, &list)
+	names := make([]string, 0, len(list.Data))
+	for _, member := range list.Data {
+		names = append(names, member.Name+" "+member.Role)
+	}
+	return names
+}
+
+func TestMemberHandlers(t *testing.T) {
+	router, _ := newTestServer(t)
+	jane := createPerson(t, router, "Jane Doe")
+	john := createPerson(t, router, "John Roe")
+	anna := createPerson(t, router, "Anna Lee")
+	proj := createProject(t, router, projectBody("Bridge", jane, "3000", "USD", futureDeadline))
+	members := "/api/v1/projects/" + proj.ID + "/members/"
+
+	if names := memberNames(t, router, proj.ID); !slices.Equal(names, []string{"Jane Doe leader"}) {
+		t.Fatalf("members = %q, want the leader only", names)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		code   string
+	}{
+		{name: "add member", method: http.MethodPut, path: members + john, body: `{"role": "member"}`, status: http.StatusOK},
+		{name: "add viewer", method: http.MethodPut, path: members + anna, body: `{"role": "viewer"}`, status: http.StatusOK},
+		{name: "unknown role", method: http.MethodPut, path: members + anna, body: `{"role": "owner"}`, status: http.StatusUnprocessableEntity, code: codeValidationFailed},
+		{name: "unknown person", method: http.MethodPut, path: members + "999", body: `{"role": "member"}`, status: http.StatusNotFound, code: codeNotFound},
+		{name: "unknown project", method: http.MethodPut, path: "/api/v1/projects/999/members/" + john, body: `{"role": "member"}`, status: http.StatusNotFound, code: codeNotFound},
+		{name: "demote leader", method: http.MethodPut, path: members + jane, body: `{"role": "member"}`, status: http.StatusConflict, code: codeLeaderRequired},
+		{name: "remove leader", method: http.MethodDelete, path: members + jane, status: http.StatusConflict, code: codeLeaderRequired},
+		{name: "delete a person belonging to a project", method: http.MethodDelete, path: "/api/v1/people/" + anna, status: http.StatusConflict, code: codeForeignKeyViolation},
+		{name: "remove viewer", method: http.MethodDelete, path: members + anna, status: http.StatusOK},
+		{name: "remove viewer again", method: http.MethodDelete, path: members + anna, status: http.StatusNotFound, code: codeNotFound},
+		{name: "make member leader", method: http.MethodPut, path: members + john, body: `{"role": "leader"}`, status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := request(router, tt.method, tt.path, tt.body)
+			if tt.code != "" {
+				checkProblem(t, w, tt.status, tt.code)
+				return
+			}
+			checkStatus(t, w, tt.status)
+		})
+	}
+
+	// The former leader stays on as a member
+	if names := memberNames(t, router, proj.ID); !slices.Equal(names, []string{"John Roe leader", "Jane Doe member"}) {
+		t.Errorf("members = %q after the change of leader", names)
+	}
+	w := request(router, http.MethodGet, "/api/v1/projects/"+proj.ID, "")
+	var got projectModel
+	decode(t, w, &got)
+	if got.LeaderID != john || got.Leader != "John Roe" {
+		t.Errorf("leader = %s %q, want John Roe", got.LeaderID, got.Leader)
+	}
+
+	// Changing leader_id also keeps the former leader
+	w = request(router, http.MethodPut, "/api/v1/projects/"+proj.ID, projectBody("Bridge", anna, "3000", "USD", futureDeadline))
+	checkStatus(t, w, http.StatusOK)
+	if names := memberNames(t, router, proj.ID); !slices.Equal(names, []string{"Anna Lee leader", "Jane Doe member", "John Roe member"}) {
+		t.Errorf("members = %q after changing leader_id", names)
+	}
+}
+
+func TestPersonProjects(t *testing.T) {
+	router, _ := newTestServer(t)
+	jane := createPerson(t, router, "Jane Doe")
+	john := createPerson(t, router, "John Roe")
+	createProject(t, router, projectBody("Bridge", jane, "3000", "USD", futureDeadline))
+	tunnel := createProject(t, router, projectBody("Tunnel", john, "1000", "USD", futureDeadline))
+	createProject(t, router, projectBody("Canal", john, "2000", "USD", futureDeadline))
+	checkStatus(t, request(router, http.MethodPut, "/api/v1/projects/"+tunnel.ID+"/members/"+jane, `{"role": "viewer"}`), http.StatusOK)
+
+	tests := []struct {
+		name   string
+		path   string
+		status int
+		titles []string
+	}{
+		{name: "all", path: "/api/v1/people/" + jane + "/projects", status: http.StatusOK, titles: []string{"Bridge", "Tunnel"}},
+		{name: "led", path: "/api/v1/people/" + jane + "/projects?role=leader", status: http.StatusOK, titles: []string{"Bridge"}},
+		{name: "viewed", path: "/api/v1/people/" + jane + "/projects?role=viewer", status: http.StatusOK, titles: []string{"Tunnel"}},
+		{name: "sorted", path: "/api/v1/people/" + john + "/projects?sort=title", status: http.StatusOK, titles: []string{"Canal", "Tunnel"}},
+		{name: "unknown role", path: "/api/v1/people/" + jane + "/projects?role=owner", status: http.StatusBadRequest},
+		{name: "unknown person", path: "/api/v1/people/999/projects", status: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := request(router, http.MethodGet, tt.path, "")
+			checkStatus(t, w, tt.status)
+			if tt.status != http.StatusOK {
+				return
+			}
+			if titles := pageTitles(t, w); !slices.Equal(titles, tt.titles) {
+				t.Errorf("titles = %v, want %v", titles, tt.titles)
+			}
+		})
+	}
+}
+
+func TestLeaderRenameChangesETag(t *testing.T) {
+	router, _ := newTestServer(t)
+	jane := createPerson(t, router, "Jane Doe")
+	proj := createProject(t, router, projectBody("Bridge", jane, "3000", "USD", futureDeadline))
+	path := "/api/v1/projects/" + proj.ID
+
+	etag := request(router, http.MethodGet, path, "").Header().Get("ETag")
+	checkStatus(t, request(router, http.MethodPut, "/api/v1/people/"+jane, `{"name": "Jane Smith"}`), http.StatusOK)
+
+	w := request(router, http.MethodGet, path, "", "If-None-Match", etag)
+	checkStatus(t, w, http.StatusOK)
+	if w.Header().Get("ETag") == etag {
+		t.Errorf("ETag %s did not change when the leader was renamed", etag)
+	}
+	checkStatus(t, request(router, http.MethodDelete, path, "", "If-Match", etag), http.StatusPreconditionFailed)
+}
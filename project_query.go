@@ -0,0 +1,291 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultProjectLimit = 100
+	maxProjectLimit     = 1000
+)
+
+// projectSortColumns maps the values accepted by the sort parameter to the
+// columns they order by.
+var projectSortColumns = map[string]string{
+	"id":           "p.id",
+	"title":        "p.title",
+	"leader":       "pe.name",
+	"budget_value": "pb.budget_value",
+	"deadline":     "pb.deadline",
+}
+
+// projectListQuery describes which page of projects a ProjectRepository
+// should return.
+type projectListQuery struct {
+	Limit  int
+	Offset int
+	// Cursor continues a keyset pagination from a previous page. Offset is
+	// ignored when it is set.
+	Cursor *projectCursor
+
+	Sort string
+	Desc bool
+
+	// Leader is the name of the leader, LeaderID their id
+	Leader        string
+	LeaderID      string
+	TitleContains string
+	// Statuses keeps the projects in one of these statuses
+	Statuses []string
+	// MemberID keeps the projects the person with this id belongs to, with
+	// MemberRole when set.
+	MemberID   string
+	MemberRole string
+	Currency   string
+	// MinBudget and MaxBudget are in minor units of Currency.
+	MinBudget *int64
+	MaxBudget *int64
+	// DeadlineAfter and DeadlineBefore bound the deadline, the former
+	// inclusively and the latter exclusively.
+	DeadlineAfter  *time.Time
+	DeadlineBefore *time.Time
+}
+
+// projectCursor points at the first or last project of a page. Value holds
+// the sort column of that project, ID breaks ties between equal values.
+type projectCursor struct {
+	Sort   string `json:"s"`
+	Value  string `json:"v"`
+	ID     int64  `json:"i"`
+	Before bool   `json:"b,omitempty"`
+}
+
+// projectList is a single page of projects along with the number of
+// projects matching the filters across all pages.
+type projectList struct {
+	Projects []projectModel
+	Total    int
+}
+
+type pageLinks struct {
+	Next string `json:"next,omitempty" example:"/projects?cursor=eyJzIjoiaWQiLCJ2IjoiMTAwIiwiaSI6MTAwfQ&limit=100"`
+	Prev string `json:"prev,omitempty"`
+}
+
+type projectPage struct {
+	Data   []projectModel `json:"data"`
+	Total  int            `json:"total" example:"1250"`
+	Limit  int            `json:"limit" example:"100"`
+	Offset int            `json:"offset,omitempty"`
+	Links  pageLinks      `json:"links"`
+}
+
+// parseProjectListQuery reads pagination, sorting and filter parameters from
+// the query string. now is the time overdue and due_within are relative to.
+func parseProjectListQuery(values url.Values, now time.Time) (projectListQuery, error) {
+	query := projectListQuery{Limit: defaultProjectLimit, Sort: "id"}
+
+	if v := values.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > maxProjectLimit {
+			return query, fmt.Errorf("limit must be between 1 and %d", maxProjectLimit)
+		}
+		query.Limit = limit
+	}
+
+	if v := values.Get("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return query, errors.New("offset must be a positive number")
+		}
+		query.Offset = offset
+	}
+
+	if v := values.Get("sort"); v != "" {
+		if _, ok := projectSortColumns[v]; !ok {
+			return query, errors.New("sort must be one of id, title, leader, budget_value, deadline")
+		}
+		query.Sort = v
+	}
+
+	switch strings.ToLower(values.Get("order")) {
+	case "", "asc":
+	case "desc":
+		query.Desc = true
+	default:
+		return query, errors.New("order must be asc or desc")
+	}
+
+	if v := values.Get("cursor"); v != "" {
+		cursor, err := decodeProjectCursor(v)
+		if err != nil || cursor.Sort != query.Sort {
+			return query, errors.New("invalid cursor")
+		}
+		query.Cursor = &cursor
+	}
+
+	query.Leader = values.Get("leader")
+	query.LeaderID = values.Get("leader_id")
+
+	if v := values.Get("status"); v != "" {
+		for _, status := range strings.Split(v, ",") {
+			if _, ok := statusTransitions[status]; !ok {
+				return query, errors.New("status must be a comma separated list of planned, active, on_hold, completed, cancelled")
+			}
+			query.Statuses = append(query.Statuses, status)
+		}
+	}
+	query.TitleContains = values.Get("title~")
+
+	var err error
+	if query.DeadlineBefore, err = parseOptionalTime(values, "deadline_before"); err != nil {
+		return query, err
+	}
+	if query.DeadlineAfter, err = parseOptionalTime(values, "deadline_after"); err != nil {
+		return query, err
+	}
+
+	overdue := false
+	if v := values.Get("overdue"); v != "" {
+		if overdue, err = strconv.ParseBool(v); err != nil {
+			return query, errors.New("overdue must be true or false")
+		}
+	}
+	if overdue {
+		query.DeadlineBefore = earliest(query.DeadlineBefore, now)
+	}
+
+	if v := values.Get("due_within"); v != "" {
+		days, err := strconv.Atoi(v)
+		if err != nil || days < 0 {
+			return query, errors.New("due_within must be a positive number of days")
+		}
+		if overdue {
+			return query, errors.New("overdue and due_within cannot be combined")
+		}
+		query.DeadlineAfter = latest(query.DeadlineAfter, now)
+		query.DeadlineBefore = earliest(query.DeadlineBefore, now.AddDate(0, 0, days))
+	}
+
+	if v := values.Get("currency"); v != "" {
+		if _, err := minorDigits(v); err != nil {
+			return query, errors.New("currency must be an ISO 4217 code")
+		}
+		query.Currency = v
+	}
+	// Budget values are only comparable within a currency
+	if query.Sort == "budget_value" && query.Currency == "" {
+		return query, errors.New("sort=budget_value needs the currency parameter")
+	}
+	if query.MinBudget, err = parseOptionalAmount(values, "min_budget", query.Currency); err != nil {
+		return query, err
+	}
+	if query.MaxBudget, err = parseOptionalAmount(values, "max_budget", query.Currency); err != nil {
+		return query, err
+	}
+
+	return query, nil
+}
+
+// parseOptionalAmount parses an amount in major units of currency into
+// minor units. Amounts are only comparable within a currency, so currency is
+// required along with it.
+func parseOptionalAmount(values url.Values, key, currency string) (*int64, error) {
+	v := values.Get(key)
+	if v == "" {
+		return nil, nil
+	}
+	if currency == "" {
+		return nil, fmt.Errorf("%s needs the currency parameter", key)
+	}
+	m, err := parseMoney(v, currency)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an amount in %s: %v", key, currency, err)
+	}
+	return &m.Amount, nil
+}
+
+func parseOptionalTime(values url.Values, key string) (*time.Time, error) {
+	v := values.Get(key)
+	if v == "" {
+		return nil, nil
+	}
+	t, err := parseDateTime(v)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an RFC 3339 time or a YYYY-MM-DD date", key)
+	}
+	return &t.Time, nil
+}
+
+// earliest returns the earlier of bound and t, bound being unset when nil.
+func earliest(bound *time.Time, t time.Time) *time.Time {
+	if bound != nil && bound.Before(t) {
+		return bound
+	}
+	return &t
+}
+
+// latest returns the later of bound and t, bound being unset when nil.
+func latest(bound *time.Time, t time.Time) *time.Time {
+	if bound != nil && bound.After(t) {
+		return bound
+	}
+	return &t
+}
+
+func encodeProjectCursor(cursor projectCursor) string {
+	b, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeProjectCursor(s string) (projectCursor, error) {
+	var cursor projectCursor
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor, err
+	}
+	err = json.Unmarshal(b, &cursor)
+	return cursor, err
+}
+
+// cursorFor returns a cursor positioned on the given project.
+func cursorFor(sort string, proj projectModel, before bool) projectCursor {
+	return projectCursor{Sort: sort, Value: projectSortValue(sort, proj), ID: projectID(proj), Before: before}
+}
+
+// projectSortValue returns the value of the sort column of proj as a string.
+func projectSortValue(sort string, proj projectModel) string {
+	switch sort {
+	case "title":
+		return proj.Title
+	case "leader":
+		return proj.Leader
+	case "budget_value":
+		return strconv.FormatInt(proj.Budget.BudgetValue.Amount, 10)
+	case "deadline":
+		return proj.Budget.Deadline.String()
+	default:
+		return proj.ID
+	}
+}
+
+// pageURL returns u with the given query parameters replaced. Parameters with
+// an empty value are removed.
+func pageURL(u *url.URL, params map[string]string) string {
+	values := u.Query()
+	for k, v := range params {
+		if v == "" {
+			values.Del(k)
+		} else {
+			values.Set(k, v)
+		}
+	}
+	return u.Path + "?" + values.Encode()
+}
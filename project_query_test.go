@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestParseProjectListQueryDeadlines(t *testing.T) {
+	now := time.Date(2030, 6, 15, 12, 0, 0, 0, time.UTC)
+	at := func(s string) *time.Time {
+		parsed, _ := time.Parse(time.RFC3339, s)
+		return &parsed
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		after  *time.Time
+		before *time.Time
+	}{
+		{name: "none", query: ""},
+		{name: "bounds", query: "deadline_after=2030-01-01&deadline_before=2030-12-31T12:00:00%2B02:00", after: at("2030-01-01T00:00:00Z"), before: at("2030-12-31T10:00:00Z")},
+		{name: "overdue", query: "overdue=true", before: &now},
+		{name: "overdue before an earlier bound", query: "overdue=true&deadline_before=2030-03-01", before: at("2030-03-01T00:00:00Z")},
+		{name: "not overdue", query: "overdue=false"},
+		{name: "due within", query: "due_within=7", after: &now, before: at("2030-06-22T12:00:00Z")},
+		{name: "due within a narrower range", query: "due_within=7&deadline_after=2030-06-20&deadline_before=2030-06-21", after: at("2030-06-20T00:00:00Z"), before: at("2030-06-21T00:00:00Z")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			query, err := parseProjectListQuery(values, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !equalTimes(query.DeadlineAfter, tt.after) || !equalTimes(query.DeadlineBefore, tt.before) {
+				t.Errorf("deadlines = [%v, %v), want [%v, %v)", query.DeadlineAfter, query.DeadlineBefore, tt.after, tt.before)
+			}
+		})
+	}
+}
+
+// equalTimes reports whether a and b are both unset or the same instant.
+func equalTimes(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-example-api/internal/config"
+	"go-example-api/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+)
+
+// Groups of routes limited separately, see rateLimitGroup, and the groups
+// of the limits on every route.
+const (
+	rateLimitList  = "list"
+	rateLimitRead  = "read"
+	rateLimitWrite = "write"
+	// rateLimitAddress limits the requests from each address before they
+	// are authenticated.
+	rateLimitAddress = "address"
+	rateLimitQuota   = "quota"
+)
+
+// rateLimits returns the limit of each group of routes set in cfg.
+func rateLimits(cfg config.RateLimit) map[string]ratelimit.Limit {
+	return map[string]ratelimit.Limit{
+		rateLimitList:  {Rate: cfg.ListRate, Burst: cfg.ListBurst},
+		rateLimitRead:  {Rate: cfg.ReadRate, Burst: cfg.ReadBurst},
+		rateLimitWrite: {Rate: cfg.WriteRate, Burst: cfg.WriteBurst},
+	}
+}
+
+// addressRateLimiting rejects with a 429 problem the requests of an address
+// that exceeds limit. It runs before authentication, so that failed attempts
+// are limited too and API keys cannot be guessed at full speed.
+func addressRateLimiting(store ratelimit.Store, limit ratelimit.Limit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if limit.Rate > 0 {
+			result, err := store.Take(c.Request.Context(), rateLimitAddress+":"+c.ClientIP(), limit, time.Now())
+			if !allowRequest(c, rateLimitAddress, limit.Burst, result, err) {
+				return
+			}
+		}
+		c.Next()
+	}
+}
+
+// rateLimiting rejects with a 429 problem the requests of a client that
+// exceeds the limit of the group of the route, or its quota. Clients are
+// told apart by their name once authenticated, by their address otherwise.
+// Every limited response carries the RateLimit-Limit, RateLimit-Remaining
+// and RateLimit-Reset headers of the IETF draft, and rejected ones
+// Retry-After.
+func rateLimiting(store ratelimit.Store, limits map[string]ratelimit.Limit, quota ratelimit.Quota) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		client := rateLimitClient(c)
+		now := time.Now()
+
+		group := rateLimitGroup(c)
+		if limit := limits[group]; limit.Rate > 0 {
+			result, err := store.Take(ctx, group+":"+client, limit, now)
+			if !allowRequest(c, group, limit.Burst, result, err) {
+				return
+			}
+		}
+		// The quota is spent last, by the requests let through only
+		if quota.Requests > 0 {
+			result, err := store.Spend(ctx, rateLimitQuota+":"+client, quota, now)
+			if !allowRequest(c, rateLimitQuota, quota.Requests, result, err) {
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// allowRequest reports whether the limit of group, of which result is the
+// outcome, lets the request through, and rejects it otherwise. The headers
+// describe the limit rejecting the request, or the one closest to being
+// reached among those checked. Errors
+// of the store let the request through rather than failing them all while
+// it is down.
+func allowRequest(c *gin.Context, group string, limit int, result ratelimit.Result, err error) bool {
+	if err != nil {
+		log.Ctx(c.Request.Context()).Error().Msg("Error checking " + group + " rate limit: " + err.Error())
+		return true
+	}
+
+	remaining, err := strconv.Atoi(c.Writer.Header().Get("RateLimit-Remaining"))
+	if err != nil || !result.Allowed || result.Remaining < remaining {
+		c.Header("RateLimit-Limit", strconv.Itoa(limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+	}
+	if result.Allowed {
+		return true
+	}
+
+	retryAfter := ceilSeconds(result.RetryAfter)
+	detail := "too many requests, retry in " + strconv.Itoa(retryAfter) + " seconds"
+	if group == rateLimitQuota {
+		detail = "request quota spent, retry in " + strconv.Itoa(retryAfter) + " seconds"
+	}
+	httpRequestsThrottled.WithLabelValues(c.FullPath(), group).Inc()
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.Error(newAPIError(http.StatusTooManyRequests, codeRateLimited, detail))
+	c.Abort()
+	return false
+}
+
+// rateLimitGroup returns the group of the route of a request: listing
+// projects loads many rows and has a group of its own, apart from the other
+// reads and the changes.
+func rateLimitGroup(c *gin.Context) string {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		if strings.HasSuffix(c.FullPath(), "/projects") {
+			return rateLimitList
+		}
+		return rateLimitRead
+	}
+	return rateLimitWrite
+}
+
+// rateLimitClient returns the key of the bucket of the client of a request.
+func rateLimitClient(c *gin.Context) string {
+	if user := c.GetString(userKey); user != "" {
+		return "user:" + user
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
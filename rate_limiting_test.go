@@ -0,0 +1,114 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-example-api/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newRateLimitedTestServer returns the API routes backed by a memory
+// repository, limited by limits and quota.
+func newRateLimitedTestServer(t *testing.T, address ratelimit.Limit, limits map[string]ratelimit.Limit, quota ratelimit.Quota) *gin.Engine {
+	t.Helper()
+	store := ratelimit.NewMemoryStore()
+	router := gin.New()
+	router.Use(problemErrors())
+	registerRoutes(router, newProjectHandler(newMemoryProjectRepository()), addressRateLimiting(store, address), rateLimiting(store, limits, quota))
+	return router
+}
+
+// rateLimitHeaders returns the RateLimit-* and Retry-After headers of w.
+func rateLimitHeaders(w *httptest.ResponseRecorder) string {
+	var headers []string
+	for _, name := range []string{"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "Retry-After"} {
+		if value := w.Header().Get(name); value != "" {
+			headers = append(headers, name+"="+value)
+		}
+	}
+	return strings.Join(headers, " ")
+}
+
+func TestRateLimiting(t *testing.T) {
+	router := newRateLimitedTestServer(t, ratelimit.Limit{}, map[string]ratelimit.Limit{
+		rateLimitList:  {Rate: 0.1, Burst: 2},
+		rateLimitRead:  {Rate: 0.1, Burst: 1},
+		rateLimitWrite: {Rate: 1, Burst: 1},
+	}, ratelimit.Quota{})
+
+	// The steps share one store and build on each other
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		status  int
+		headers string
+	}{
+		{name: "list", method: http.MethodGet, path: "/api/v1/projects", status: http.StatusOK, headers: "RateLimit-Limit=2 RateLimit-Remaining=1 RateLimit-Reset=10"},
+		{name: "list again", method: http.MethodGet, path: "/api/v1/projects", status: http.StatusOK, headers: "RateLimit-Limit=2 RateLimit-Remaining=0 RateLimit-Reset=20"},
+		{name: "list throttled", method: http.MethodGet, path: "/api/v1/projects", status: http.StatusTooManyRequests, headers: "RateLimit-Limit=2 RateLimit-Remaining=0 RateLimit-Reset=20 Retry-After=10"},
+		{name: "read apart from listing", method: http.MethodGet, path: "/api/v1/people", status: http.StatusOK, headers: "RateLimit-Limit=1 RateLimit-Remaining=0 RateLimit-Reset=10"},
+		{name: "read throttled", method: http.MethodGet, path: "/api/v1/projects/1", status: http.StatusTooManyRequests, headers: "RateLimit-Limit=1 RateLimit-Remaining=0 RateLimit-Reset=10 Retry-After=10"},
+		{name: "write apart from reading", method: http.MethodDelete, path: "/api/v1/people/1", status: http.StatusNotFound, headers: "RateLimit-Limit=1 RateLimit-Remaining=0 RateLimit-Reset=1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := request(router, tt.method, tt.path, "")
+			if tt.status == http.StatusTooManyRequests {
+				checkProblem(t, w, tt.status, codeRateLimited)
+			} else {
+				checkStatus(t, w, tt.status)
+			}
+			if got := rateLimitHeaders(w); got != tt.headers {
+				t.Errorf("headers = %s, want %s", got, tt.headers)
+			}
+		})
+	}
+
+	// Clients are told apart by their address when not authenticated
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/projects", nil)
+	req.RemoteAddr = "203.0.113.7:1234"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	checkStatus(t, w, http.StatusOK)
+}
+
+func TestQuota(t *testing.T) {
+	router := newRateLimitedTestServer(t, ratelimit.Limit{}, map[string]ratelimit.Limit{
+		rateLimitList: {Rate: 100, Burst: 100},
+	}, ratelimit.Quota{Requests: 2, Window: 24 * time.Hour})
+
+	for i, remaining := range []string{"1", "0"} {
+		w := request(router, http.MethodGet, "/api/v1/projects", "")
+		checkStatus(t, w, http.StatusOK)
+		// The quota is closer to being reached than the bucket
+		if w.Header().Get("RateLimit-Limit") != "2" || w.Header().Get("RateLimit-Remaining") != remaining {
+			t.Errorf("request %d: headers = %s, want the quota with %s left", i+1, rateLimitHeaders(w), remaining)
+		}
+	}
+
+	w := request(router, http.MethodGet, "/api/v1/projects", "")
+	problem := checkProblem(t, w, http.StatusTooManyRequests, codeRateLimited)
+	if !strings.Contains(problem.Detail, "quota") {
+		t.Errorf("detail = %q, want the quota named", problem.Detail)
+	}
+	if w.Header().Get("Retry-After") != w.Header().Get("RateLimit-Reset") || w.Header().Get("Retry-After") == "" {
+		t.Errorf("headers = %s, want a retry once the window ends", rateLimitHeaders(w))
+	}
+}
+
+func TestAddressRateLimiting(t *testing.T) {
+	router := newRateLimitedTestServer(t, ratelimit.Limit{Rate: 1, Burst: 1}, map[string]ratelimit.Limit{}, ratelimit.Quota{})
+
+	checkStatus(t, request(router, http.MethodGet, "/api/v1/projects", ""), http.StatusOK)
+	w := request(router, http.MethodGet, "/api/v1/projects", "")
+	checkProblem(t, w, http.StatusTooManyRequests, codeRateLimited)
+	if got := rateLimitHeaders(w); got != "RateLimit-Limit=1 RateLimit-Remaining=0 RateLimit-Reset=1 Retry-After=1" {
+		t.Errorf("headers = %s", got)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// errProjectNotFound is returned by a ProjectRepository when no project
+// matches the requested id.
+var errProjectNotFound = errors.New("project not found")
+
+// errPreconditionFailed is returned by a ProjectRepository when the stored
+// project does not satisfy the precondition of a write.
+var errPreconditionFailed = errors.New("precondition failed")
+
+// projectPrecondition reports whether a write may go ahead given the project
+// as currently stored. It is checked while the project is locked, so no other
+// write can slip in between the check and the write. A nil precondition
+// always passes.
+type projectPrecondition func(current projectModel) bool
+
+// ProjectRepository is the storage used by the project handlers.
+type ProjectRepository interface {
+	// List returns a page of projects together with their budget, filtered
+	// and ordered as described by query.
+	List(ctx context.Context, query projectListQuery) (projectList, error)
+	// Get returns the project with the given id.
+	Get(ctx context.Context, id string) (projectModel, error)
+	// Create stores a new project and its budget and returns it with the
+	// generated id. It fails with errDeadlinePassed when checkDeadline
+	// rejects the deadline.
+	Create(ctx context.Context, project projectModel) (projectModel, error)
+	// Update replaces the project and budget with the given id. It fails
+	// with errDeadlinePassed when checkDeadline rejects the deadline.
+	Update(ctx context.Context, id string, project projectModel, precondition projectPrecondition) (projectModel, error)
+	// Patch loads the project with the given id, passes it to apply and
+	// stores the fields apply changed, all within a single transaction. Errors
+	// returned by apply are passed through unchanged.
+	Patch(ctx context.Context, id string, precondition projectPrecondition, apply func(projectModel) (projectModel, error)) (projectModel, error)
+	// Delete removes the project with the given id along with its budget,
+	// payments and members.
+	Delete(ctx context.Context, id string, precondition projectPrecondition) error
+
+	PaymentRepository
+	MemberRepository
+	PersonRepository
+	TransitionRepository
+	APIKeyRepository
+	AuditRepository
+}
+
+// AuditRepository reads the audit log. Every change made through a
+// ProjectRepository appends an entry to it within the same transaction.
+type AuditRepository interface {
+	// ListAudit returns a page of entries filtered as described by query,
+	// newest first.
+	ListAudit(ctx context.Context, query auditQuery) ([]auditEntry, error)
+}
+
+// APIKeyRepository is the storage of the API keys of the services calling
+// the API.
+type APIKeyRepository interface {
+	// CreateAPIKey stores a new key under its hash and returns it with the
+	// generated id. It fails with errDuplicateAPIKeyName when another key
+	// has the same name.
+	CreateAPIKey(ctx context.Context, key apiKeyModel, hash string) (apiKeyModel, error)
+	// ListAPIKeys returns every key, revoked and expired ones included,
+	// ordered by id.
+	ListAPIKeys(ctx context.Context) ([]apiKeyModel, error)
+	// FindAPIKey returns the key with the given hash.
+	FindAPIKey(ctx context.Context, hash string) (apiKeyModel, error)
+	// TouchAPIKey records that the key with the given id was used at.
+	TouchAPIKey(ctx context.Context, id string, at time.Time) error
+	// RevokeAPIKey revokes the key with the given id at, unless it already
+	// is revoked.
+	RevokeAPIKey(ctx context.Context, id string, at time.Time) error
+}
+
+// TransitionRepository moves projects through their lifecycle and keeps the
+// history of their statuses.
+type TransitionRepository interface {
+	// Transition moves the project with the given id to status, recording
+	// reason. It fails with errInvalidTransition when checkTransition does
+	// not allow it and bumps the project version otherwise.
+	Transition(ctx context.Context, id string, precondition projectPrecondition, status, reason string) (projectModel, transitionModel, error)
+	// ListTransitions returns the status changes of a project, oldest first.
+	ListTransitions(ctx context.Context, id string) ([]transitionModel, error)
+}
+
+// PaymentRepository is the storage of the payments ledger of the projects.
+// Recording or deleting a payment changes the summary of the budget, so it
+// bumps the budget version.
+type PaymentRepository interface {
+	// ListPayments returns the payments of a project, oldest first.
+	ListPayments(ctx context.Context, projectID string) ([]paymentModel, error)
+	// GetPayment returns the payment with the given id of a project.
+	GetPayment(ctx context.Context, projectID, id string) (paymentModel, error)
+	// CreatePayment records a payment towards the budget of a project and
+	// returns it with the generated id. It fails with errPaymentCurrency or
+	// errPaymentExceedsBalance when checkPayment rejects it, and with
+	// errProjectCompleted once the project is completed.
+	CreatePayment(ctx context.Context, projectID string, payment paymentModel) (paymentModel, error)
+	// DeletePayment removes the payment with the given id of a project. It
+	// fails with errProjectCompleted once the project is completed.
+	DeletePayment(ctx context.Context, projectID, id string) error
+}
+
+// MemberRepository is the storage of the members of the projects. The leader
+// of a project is always one of its members, with the leader role.
+type MemberRepository interface {
+	// ListMembers returns the members of a project ordered by role and name.
+	ListMembers(ctx context.Context, projectID string) ([]memberModel, error)
+	// SetMember adds a person to a project or changes their role. Making
+	// someone leader changes the leader of the project, the former leader
+	// staying on as a member. The leader cannot be given another role and
+	// fails with errLeaderRequired.
+	SetMember(ctx context.Context, projectID, personID, role string) (memberModel, error)
+	// DeleteMember removes a person from a project. It fails with
+	// errLeaderRequired for the leader.
+	DeleteMember(ctx context.Context, projectID, personID string) error
+}
+
+// PersonRepository is the storage of the people leading or taking part in
+// projects.
+type PersonRepository interface {
+	// ListPeople returns every person ordered by name.
+	ListPeople(ctx context.Context) ([]personModel, error)
+	// GetPerson returns the person with the given id.
+	GetPerson(ctx context.Context, id string) (personModel, error)
+	// CreatePerson stores a new person and returns it with the generated id.
+	CreatePerson(ctx context.Context, person personModel) (personModel, error)
+	// UpdatePerson replaces the person with the given id.
+	UpdatePerson(ctx context.Context, id string, person personModel) (personModel, error)
+	// DeletePerson removes the person with the given id. It fails with
+	// errPersonInUse while they belong to a project.
+	DeletePerson(ctx context.Context, id string) error
+}
+
+// withNextVersions returns updated with the versions and status of current,
+// bumping the project and budget versions when their fields have changed.
+// The payment summary is recomputed for the budget value of updated.
+func withNextVersions(current, updated projectModel) projectModel {
+	updated.ID = current.ID
+	updated.Version = current.Version
+	updated.Budget.Version = current.Budget.Version
+	updated.Status = current.Status
+	updated.StatusChangedAt = current.StatusChangedAt
+	updated.Budget.paymentSummary = summarizePayments(updated.Budget.BudgetValue, current.Budget.Paid.Amount)
+
+	if updated.Title != current.Title || updated.LeaderID != current.LeaderID {
+		updated.Version++
+	}
+	if updated.Budget != current.Budget {
+		updated.Budget.Version++
+	}
+	return updated
+}
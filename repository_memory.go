@@ -0,0 +1,427 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryProjectRepository keeps projects in a map. It is meant for tests and
+// local demos where no MySQL server is available.
+type memoryProjectRepository struct {
+	mu               sync.RWMutex
+	nextID           int64
+	nextPaymentID    int64
+	nextPersonID     int64
+	nextTransitionID int64
+	nextAPIKeyID     int64
+	nextAuditID      int64
+	// projects holds the projects without the name of their leader, which
+	// is looked up in people when they are read
+	projects map[string]projectModel
+	// payments holds the payments of each project, oldest first
+	payments map[string][]paymentModel
+	people   map[string]personModel
+	// members maps the id of each project to the roles of its members by
+	// person id
+	members map[string]map[string]string
+	// transitions holds the status changes of each project, oldest first
+	transitions map[string][]transitionModel
+	// apiKeys holds the API keys by hash
+	apiKeys map[string]apiKeyModel
+	// audit holds the audit log, oldest first
+	audit []auditEntry
+}
+
+func newMemoryProjectRepository() *memoryProjectRepository {
+	return &memoryProjectRepository{
+		projects:    make(map[string]projectModel),
+		payments:    make(map[string][]paymentModel),
+		people:      make(map[string]personModel),
+		members:     make(map[string]map[string]string),
+		transitions: make(map[string][]transitionModel),
+		apiKeys:     make(map[string]apiKeyModel),
+	}
+}
+
+func (r *memoryProjectRepository) List(ctx context.Context, query projectListQuery) (projectList, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	projects := make([]projectModel, 0, len(r.projects))
+	for _, proj := range r.projects {
+		proj = r.withLeader(proj)
+		if !matchesProjectFilters(proj, query) {
+			continue
+		}
+		if query.MemberID != "" {
+			role, ok := r.members[proj.ID][query.MemberID]
+			if !ok || query.MemberRole != "" && role != query.MemberRole {
+				continue
+			}
+		}
+		projects = append(projects, proj)
+	}
+	total := len(projects)
+
+	sort.Slice(projects, func(i, j int) bool {
+		cmp := compareProjectKeys(query.Sort, projectSortValue(query.Sort, projects[i]), projectID(projects[i]), projectSortValue(query.Sort, projects[j]), projectID(projects[j]))
+		if query.Desc {
+			return cmp > 0
+		}
+		return cmp < 0
+	})
+
+	if cursor := query.Cursor; cursor != nil {
+		// Keep the projects on the requested side of the cursor
+		var page []projectModel
+		for _, proj := range projects {
+			cmp := compareProjectKeys(query.Sort, projectSortValue(query.Sort, proj), projectID(proj), cursor.Value, cursor.ID)
+			if query.Desc {
+				cmp = -cmp
+			}
+			if (cmp > 0 && !cursor.Before) || (cmp < 0 && cursor.Before) {
+				page = append(page, proj)
+			}
+		}
+		if cursor.Before && len(page) > query.Limit {
+			page = page[len(page)-query.Limit:]
+		}
+		projects = page
+	} else if query.Offset < len(projects) {
+		projects = projects[query.Offset:]
+	} else {
+		projects = nil
+	}
+
+	if len(projects) > query.Limit {
+		projects = projects[:query.Limit]
+	}
+
+	return projectList{Projects: projects, Total: total}, nil
+}
+
+func matchesProjectFilters(proj projectModel, query projectListQuery) bool {
+	if query.Leader != "" && proj.Leader != query.Leader {
+		return false
+	}
+	if query.LeaderID != "" && proj.LeaderID != query.LeaderID {
+		return false
+	}
+	if len(query.Statuses) > 0 && !slices.Contains(query.Statuses, proj.Status) {
+		return false
+	}
+	if query.TitleContains != "" && !strings.Contains(strings.ToLower(proj.Title), strings.ToLower(query.TitleContains)) {
+		return false
+	}
+	if query.Currency != "" && proj.Budget.BudgetValue.Currency != query.Currency {
+		return false
+	}
+	if query.MinBudget != nil && proj.Budget.BudgetValue.Amount < *query.MinBudget {
+		return false
+	}
+	if query.MaxBudget != nil && proj.Budget.BudgetValue.Amount > *query.MaxBudget {
+		return false
+	}
+	if query.DeadlineAfter != nil && proj.Budget.Deadline.Before(*query.DeadlineAfter) {
+		return false
+	}
+	if query.DeadlineBefore != nil && !proj.Budget.Deadline.Before(*query.DeadlineBefore) {
+		return false
+	}
+	return true
+}
+
+// compareProjectKeys orders projects by their sort value and then by id, the
+// same way the MySQL repository does.
+func compareProjectKeys(sortBy string, a string, aID int64, b string, bID int64) int {
+	var cmp int
+	switch sortBy {
+	case "id", "budget_value":
+		x, _ := strconv.ParseInt(a, 10, 64)
+		y, _ := strconv.ParseInt(b, 10, 64)
+		cmp = compareInt64(x, y)
+	default:
+		cmp = strings.Compare(strings.ToLower(a), strings.ToLower(b))
+	}
+	if cmp == 0 {
+		cmp = compareInt64(aID, bID)
+	}
+	return cmp
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func projectID(proj projectModel) int64 {
+	id, _ := strconv.ParseInt(proj.ID, 10, 64)
+	return id
+}
+
+func (r *memoryProjectRepository) Get(ctx context.Context, id string) (projectModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	proj, ok := r.projects[id]
+	if !ok {
+		return projectModel{}, errProjectNotFound
+	}
+	return r.withLeader(proj), nil
+}
+
+func (r *memoryProjectRepository) Create(ctx context.Context, project projectModel) (projectModel, error) {
+	if err := checkDeadline(projectModel{}, project, time.Now()); err != nil {
+		return projectModel{}, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.people[project.LeaderID]; !ok {
+		return projectModel{}, errLeaderNotFound
+	}
+
+	r.nextID++
+	project.ID = strconv.FormatInt(r.nextID, 10)
+	project.Version = 1
+	project.Budget.Version = 1
+	project.Budget.paymentSummary = summarizePayments(project.Budget.BudgetValue, 0)
+	project.Status = statusPlanned
+	project.StatusChangedAt = newDateTime(time.Now())
+	created := r.withLeader(project)
+	if err := r.record(ctx, auditProjectCreate, project.ID, "", nil, created); err != nil {
+		return projectModel{}, err
+	}
+	r.projects[project.ID] = project
+	r.members[project.ID] = map[string]string{project.LeaderID: roleLeader}
+	return created, nil
+}
+
+func (r *memoryProjectRepository) Update(ctx context.Context, id string, project projectModel, precondition projectPrecondition) (projectModel, error) {
+	return r.Patch(ctx, id, precondition, func(current projectModel) (projectModel, error) {
+		if err := checkDeadline(current, project, time.Now()); err != nil {
+			return projectModel{}, err
+		}
+		return project, nil
+	})
+}
+
+func (r *memoryProjectRepository) Patch(ctx context.Context, id string, precondition projectPrecondition, apply func(projectModel) (projectModel, error)) (projectModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, err := r.lookup(id, precondition)
+	if err != nil {
+		return projectModel{}, err
+	}
+
+	patched, err := apply(current)
+	if err != nil {
+		return projectModel{}, err
+	}
+	if err := checkPaidBudget(current, patched); err != nil {
+		return projectModel{}, err
+	}
+	if err := checkCompletedBudget(current, patched); err != nil {
+		return projectModel{}, err
+	}
+
+	patched = withNextVersions(current, patched)
+	if patched.LeaderID != current.LeaderID {
+		if _, ok := r.people[patched.LeaderID]; !ok {
+			return projectModel{}, errLeaderNotFound
+		}
+	}
+	updated := r.withLeader(patched)
+	if err := r.record(ctx, auditProjectUpdate, id, "", current, updated); err != nil {
+		return projectModel{}, err
+	}
+
+	if patched.LeaderID != current.LeaderID {
+		// The former leader stays on as a member
+		r.members[id][current.LeaderID] = roleMember
+		r.members[id][patched.LeaderID] = roleLeader
+	}
+	r.projects[id] = patched
+	return updated, nil
+}
+
+func (r *memoryProjectRepository) Delete(ctx context.Context, id string, precondition projectPrecondition) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, err := r.lookup(id, precondition)
+	if err != nil {
+		return err
+	}
+	if err := r.record(ctx, auditProjectDelete, id, "", current, nil); err != nil {
+		return err
+	}
+
+	delete(r.projects, id)
+	delete(r.payments, id)
+	delete(r.members, id)
+	delete(r.transitions, id)
+	return nil
+}
+
+func (r *memoryProjectRepository) Transition(ctx context.Context, id string, precondition projectPrecondition, status, reason string) (projectModel, transitionModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	proj, err := r.lookup(id, precondition)
+	if err != nil {
+		return projectModel{}, transitionModel{}, err
+	}
+	if err := checkTransition(proj.Status, status); err != nil {
+		return projectModel{}, transitionModel{}, err
+	}
+
+	r.nextTransitionID++
+	transition := transitionModel{
+		ID:     strconv.FormatInt(r.nextTransitionID, 10),
+		From:   proj.Status,
+		To:     status,
+		Reason: reason,
+		At:     newDateTime(time.Now()),
+	}
+
+	updated := proj
+	updated.Status = status
+	updated.StatusChangedAt = transition.At
+	updated.Version++
+	if err := r.record(ctx, auditProjectTransition, id, "", proj, updated); err != nil {
+		return projectModel{}, transitionModel{}, err
+	}
+
+	r.transitions[id] = append(r.transitions[id], transition)
+	r.projects[id] = updated
+	return updated, transition, nil
+}
+
+func (r *memoryProjectRepository) ListTransitions(ctx context.Context, id string) ([]transitionModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.projects[id]; !ok {
+		return nil, errProjectNotFound
+	}
+	return slices.Clone(r.transitions[id]), nil
+}
+
+func (r *memoryProjectRepository) ListPayments(ctx context.Context, projectID string) ([]paymentModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.projects[projectID]; !ok {
+		return nil, errProjectNotFound
+	}
+	return slices.Clone(r.payments[projectID]), nil
+}
+
+func (r *memoryProjectRepository) GetPayment(ctx context.Context, projectID, id string) (paymentModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, payment := range r.payments[projectID] {
+		if payment.ID == id {
+			return payment, nil
+		}
+	}
+	return paymentModel{}, errPaymentNotFound
+}
+
+func (r *memoryProjectRepository) CreatePayment(ctx context.Context, projectID string, payment paymentModel) (paymentModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	proj, err := r.lookup(projectID, nil)
+	if err != nil {
+		return paymentModel{}, err
+	}
+	if err := checkPaymentsOpen(proj); err != nil {
+		return paymentModel{}, err
+	}
+	if err := checkPayment(proj, payment); err != nil {
+		return paymentModel{}, err
+	}
+
+	r.nextPaymentID++
+	payment.ID = strconv.FormatInt(r.nextPaymentID, 10)
+	if err := r.record(ctx, auditPaymentCreate, projectID, "/payments/"+payment.ID, nil, payment); err != nil {
+		return paymentModel{}, err
+	}
+	payments := append(r.payments[projectID], payment)
+	sort.SliceStable(payments, func(i, j int) bool { return payments[i].PaidAt.Before(payments[j].PaidAt.Time) })
+	r.payments[projectID] = payments
+
+	r.updatePaid(proj, payment.Amount.Amount)
+	return payment, nil
+}
+
+func (r *memoryProjectRepository) DeletePayment(ctx context.Context, projectID, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	proj, err := r.lookup(projectID, nil)
+	if err != nil {
+		return err
+	}
+	if err := checkPaymentsOpen(proj); err != nil {
+		return err
+	}
+
+	payments := r.payments[projectID]
+	i := slices.IndexFunc(payments, func(p paymentModel) bool { return p.ID == id })
+	if i < 0 {
+		return errPaymentNotFound
+	}
+	if err := r.record(ctx, auditPaymentDelete, projectID, "/payments/"+id, payments[i], nil); err != nil {
+		return err
+	}
+	amount := payments[i].Amount.Amount
+	r.payments[projectID] = slices.Delete(payments, i, i+1)
+
+	r.updatePaid(proj, -amount)
+	return nil
+}
+
+// updatePaid adds delta to the amount paid towards the budget of proj and
+// bumps the budget version. The caller must hold r.mu.
+func (r *memoryProjectRepository) updatePaid(proj projectModel, delta int64) {
+	proj.Budget.paymentSummary = summarizePayments(proj.Budget.BudgetValue, proj.Budget.Paid.Amount+delta)
+	proj.Budget.Version++
+	r.projects[proj.ID] = proj
+}
+
+// lookup returns the project with the given id if it satisfies precondition.
+// The caller must hold r.mu.
+func (r *memoryProjectRepository) lookup(id string, precondition projectPrecondition) (projectModel, error) {
+	proj, ok := r.projects[id]
+	if !ok {
+		return projectModel{}, errProjectNotFound
+	}
+	proj = r.withLeader(proj)
+	if precondition != nil && !precondition(proj) {
+		return projectModel{}, errPreconditionFailed
+	}
+	return proj, nil
+}
+
+// withLeader returns proj with the current name of its leader. The caller
+// must hold r.mu.
+func (r *memoryProjectRepository) withLeader(proj projectModel) projectModel {
+	proj.Leader = r.people[proj.LeaderID].Name
+	return proj
+}
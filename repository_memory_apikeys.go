@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"time"
+)
+
+func (r *memoryProjectRepository) CreateAPIKey(ctx context.Context, key apiKeyModel, hash string) (apiKeyModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, other := range r.apiKeys {
+		if other.Name == key.Name {
+			return apiKeyModel{}, errDuplicateAPIKeyName
+		}
+	}
+
+	r.nextAPIKeyID++
+	key.ID = strconv.FormatInt(r.nextAPIKeyID, 10)
+	r.apiKeys[hash] = key
+	return key, nil
+}
+
+func (r *memoryProjectRepository) ListAPIKeys(ctx context.Context) ([]apiKeyModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]apiKeyModel, 0, len(r.apiKeys))
+	for _, key := range r.apiKeys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return compareInt64(apiKeyID(keys[i]), apiKeyID(keys[j])) < 0
+	})
+	return keys, nil
+}
+
+func apiKeyID(key apiKeyModel) int64 {
+	id, _ := strconv.ParseInt(key.ID, 10, 64)
+	return id
+}
+
+func (r *memoryProjectRepository) FindAPIKey(ctx context.Context, hash string) (apiKeyModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key, ok := r.apiKeys[hash]
+	if !ok {
+		return apiKeyModel{}, errAPIKeyNotFound
+	}
+	return key, nil
+}
+
+func (r *memoryProjectRepository) TouchAPIKey(ctx context.Context, id string, at time.Time) error {
+	return r.updateAPIKey(id, func(key *apiKeyModel) {
+		key.LastUsedAt = at
+	})
+}
+
+func (r *memoryProjectRepository) RevokeAPIKey(ctx context.Context, id string, at time.Time) error {
+	return r.updateAPIKey(id, func(key *apiKeyModel) {
+		// Revoking twice keeps the time of the first revocation
+		if key.RevokedAt.IsZero() {
+			key.RevokedAt = at
+		}
+	})
+}
+
+// updateAPIKey applies update to the key with the given id.
+func (r *memoryProjectRepository) updateAPIKey(id string, update func(*apiKeyModel)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for hash, key := range r.apiKeys {
+		if key.ID == id {
+			update(&key)
+			r.apiKeys[hash] = key
+			return nil
+		}
+	}
+	return errAPIKeyNotFound
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"strconv"
+)
+
+func (r *memoryProjectRepository) ListAudit(ctx context.Context, query auditQuery) ([]auditEntry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var entries []auditEntry
+	for i := len(r.audit) - 1; i >= 0 && len(entries) < query.Limit; i-- {
+		if query.matches(r.audit[i]) {
+			entries = append(entries, r.audit[i])
+		}
+	}
+	return entries, nil
+}
+
+// record appends the entry recording a change to the audit log, unless
+// nothing changed. The caller must hold r.mu and call it before making the
+// change, so that a failure leaves the repository untouched.
+func (r *memoryProjectRepository) record(ctx context.Context, action, projectID, path string, before, after any) error {
+	entry, err := newAuditEntry(ctx, action, projectID, path, before, after)
+	if err != nil {
+		return err
+	}
+	if len(entry.Changes) == 0 {
+		return nil
+	}
+
+	r.nextAuditID++
+	entry.ID = strconv.FormatInt(r.nextAuditID, 10)
+	r.audit = append(r.audit, entry)
+	return nil
+}
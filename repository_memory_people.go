@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func (r *memoryProjectRepository) ListMembers(ctx context.Context, projectID string) ([]memberModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if _, ok := r.projects[projectID]; !ok {
+		return nil, errProjectNotFound
+	}
+
+	members := make([]memberModel, 0, len(r.members[projectID]))
+	for personID, role := range r.members[projectID] {
+		members = append(members, memberModel{PersonID: personID, Name: r.people[personID].Name, Role: role})
+	}
+	sortMembers(members)
+	return members, nil
+}
+
+func (r *memoryProjectRepository) SetMember(ctx context.Context, projectID, personID, role string) (memberModel, error) {
+	if role == roleLeader {
+		// A new leader changes the project itself
+		patched, err := r.Patch(ctx, projectID, nil, func(current projectModel) (projectModel, error) {
+			current.LeaderID = personID
+			return current, nil
+		})
+		if errors.Is(err, errLeaderNotFound) {
+			return memberModel{}, errPersonNotFound
+		}
+		if err != nil {
+			return memberModel{}, err
+		}
+		return memberModel{PersonID: patched.LeaderID, Name: patched.Leader, Role: roleLeader}, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	proj, err := r.lookup(projectID, nil)
+	if err != nil {
+		return memberModel{}, err
+	}
+	if proj.LeaderID == personID {
+		return memberModel{}, errLeaderRequired
+	}
+	person, ok := r.people[personID]
+	if !ok {
+		return memberModel{}, errPersonNotFound
+	}
+
+	member := memberModel{PersonID: personID, Name: person.Name, Role: role}
+	var before any
+	if current, ok := r.members[projectID][personID]; ok {
+		before = memberModel{PersonID: personID, Name: person.Name, Role: current}
+	}
+	if err := r.record(ctx, auditMemberSet, projectID, "/members/"+personID, before, member); err != nil {
+		return memberModel{}, err
+	}
+
+	r.members[projectID][personID] = role
+	return member, nil
+}
+
+func (r *memoryProjectRepository) DeleteMember(ctx context.Context, projectID, personID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	proj, err := r.lookup(projectID, nil)
+	if err != nil {
+		return err
+	}
+	if proj.LeaderID == personID {
+		return errLeaderRequired
+	}
+	role, ok := r.members[projectID][personID]
+	if !ok {
+		return errMemberNotFound
+	}
+	member := memberModel{PersonID: personID, Name: r.people[personID].Name, Role: role}
+	if err := r.record(ctx, auditMemberDelete, projectID, "/members/"+personID, member, nil); err != nil {
+		return err
+	}
+
+	delete(r.members[projectID], personID)
+	return nil
+}
+
+func (r *memoryProjectRepository) ListPeople(ctx context.Context) ([]personModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	people := make([]personModel, 0, len(r.people))
+	for _, person := range r.people {
+		people = append(people, person)
+	}
+	sort.Slice(people, func(i, j int) bool {
+		if people[i].Name != people[j].Name {
+			return people[i].Name < people[j].Name
+		}
+		return compareInt64(personID(people[i]), personID(people[j])) < 0
+	})
+	return people, nil
+}
+
+func personID(person personModel) int64 {
+	id, _ := strconv.ParseInt(person.ID, 10, 64)
+	return id
+}
+
+func (r *memoryProjectRepository) GetPerson(ctx context.Context, id string) (personModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	person, ok := r.people[id]
+	if !ok {
+		return personModel{}, errPersonNotFound
+	}
+	return person, nil
+}
+
+func (r *memoryProjectRepository) CreatePerson(ctx context.Context, person personModel) (personModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.emailTaken(person.Email, "") {
+		return personModel{}, errDuplicateEmail
+	}
+
+	r.nextPersonID++
+	person.ID = strconv.FormatInt(r.nextPersonID, 10)
+	r.people[person.ID] = person
+	return person, nil
+}
+
+func (r *memoryProjectRepository) UpdatePerson(ctx context.Context, id string, person personModel) (personModel, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.people[id]; !ok {
+		return personModel{}, errPersonNotFound
+	}
+	if r.emailTaken(person.Email, id) {
+		return personModel{}, errDuplicateEmail
+	}
+
+	person.ID = id
+	r.people[id] = person
+	return person, nil
+}
+
+// emailTaken reports whether someone other than the person with id exceptID
+// has the given email. The caller must hold r.mu.
+func (r *memoryProjectRepository) emailTaken(email, exceptID string) bool {
+	if email == "" {
+		return false
+	}
+	for id, person := range r.people {
+		if id != exceptID && strings.EqualFold(person.Email, email) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *memoryProjectRepository) DeletePerson(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.people[id]; !ok {
+		return errPersonNotFound
+	}
+	for _, members := range r.members {
+		if _, ok := members[id]; ok {
+			return errPersonInUse
+		}
+	}
+
+	delete(r.people, id)
+	return nil
+}
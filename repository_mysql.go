@@ -0,0 +1,727 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// mysqlProjectRepository stores projects in the project and project_budget
+// tables.
+type mysqlProjectRepository struct {
+	db *sql.DB
+}
+
+func newMySQLProjectRepository(db *sql.DB) *mysqlProjectRepository {
+	return &mysqlProjectRepository{db: db}
+}
+
+// projectTables joins the tables a project is read from.
+const projectTables = "project p JOIN project_budget pb ON p.id = pb.project_id JOIN person pe ON pe.id = p.leader_id"
+
+const selectProjectQuery = "SELECT p.id, p.title, p.leader_id, pe.name, p.status, p.status_changed_at, p.version, pb.budget_value, pb.down_payment, pb.currency, pb.deadline, pb.version, " +
+	"(SELECT COALESCE(SUM(pp.amount), 0) FROM project_payment pp WHERE pp.project_id = p.id) " +
+	"FROM " + projectTables
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanProject reads a project selected with selectProjectQuery.
+func scanProject(row rowScanner) (projectModel, error) {
+	var proj projectModel
+	var currency string
+	var paid int64
+	err := row.Scan(&proj.ID, &proj.Title, &proj.LeaderID, &proj.Leader, &proj.Status, &proj.StatusChangedAt, &proj.Version, &proj.Budget.BudgetValue.Amount, &proj.Budget.DownPayment.Amount, &currency, &proj.Budget.Deadline, &proj.Budget.Version, &paid)
+	proj.Budget.BudgetValue.Currency = currency
+	proj.Budget.DownPayment.Currency = currency
+	proj.Budget.paymentSummary = summarizePayments(proj.Budget.BudgetValue, paid)
+	return proj, err
+}
+
+func (r *mysqlProjectRepository) List(ctx context.Context, query projectListQuery) (projectList, error) {
+	var list projectList
+
+	where, args := projectFilterClause(query)
+
+	countQuery := "SELECT COUNT(*) FROM " + projectTables + where
+	done := observeQuery(ctx, "count_projects", countQuery)
+	err := r.db.QueryRowContext(ctx, countQuery, args...).Scan(&list.Total)
+	done(err)
+	if err != nil {
+		return list, err
+	}
+
+	column := projectSortColumns[query.Sort]
+	// Walking backwards from a cursor reads the rows in reverse order and
+	// flips them afterwards
+	desc := query.Desc
+	if query.Cursor != nil && query.Cursor.Before {
+		desc = !desc
+	}
+
+	if cursor := query.Cursor; cursor != nil {
+		op := ">"
+		if desc {
+			op = "<"
+		}
+		cursorClause := fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND p.id %[2]s ?))", column, op)
+		if where == "" {
+			where = " WHERE " + cursorClause
+		} else {
+			where += " AND " + cursorClause
+		}
+		value, err := cursorArg(cursor)
+		if err != nil {
+			return list, err
+		}
+		args = append(args, value, value, cursor.ID)
+	}
+
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+	listQuery := fmt.Sprintf("%s%s ORDER BY %s %s, p.id %s LIMIT ?", selectProjectQuery, where, column, direction, direction)
+	args = append(args, query.Limit)
+	if query.Cursor == nil {
+		listQuery += " OFFSET ?"
+		args = append(args, query.Offset)
+	}
+
+	done = observeQuery(ctx, "list_projects", listQuery)
+	list.Projects, err = r.queryProjects(ctx, listQuery, args...)
+	done(err)
+	if err != nil {
+		return list, err
+	}
+
+	if query.Cursor != nil && query.Cursor.Before {
+		slices.Reverse(list.Projects)
+	}
+
+	return list, nil
+}
+
+// queryProjects runs a query selecting columns like selectProjectQuery and
+// reads every row.
+func (r *mysqlProjectRepository) queryProjects(ctx context.Context, query string, args ...any) ([]projectModel, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var projects []projectModel
+	for rows.Next() {
+		proj, err := scanProject(rows)
+		if err != nil {
+			return nil, err
+		}
+		projects = append(projects, proj)
+	}
+	return projects, rows.Err()
+}
+
+// cursorArg converts the sort value of cursor to the type of its column.
+func cursorArg(cursor *projectCursor) (any, error) {
+	if cursor.Sort != "deadline" {
+		return cursor.Value, nil
+	}
+	deadline, err := parseDateTime(cursor.Value)
+	if err != nil {
+		return nil, err
+	}
+	return deadline, nil
+}
+
+// projectFilterClause builds the WHERE clause for the filters of query.
+func projectFilterClause(query projectListQuery) (string, []any) {
+	var conditions []string
+	var args []any
+
+	if query.Leader != "" {
+		conditions = append(conditions, "pe.name = ?")
+		args = append(args, query.Leader)
+	}
+	if query.LeaderID != "" {
+		conditions = append(conditions, "p.leader_id = ?")
+		args = append(args, query.LeaderID)
+	}
+	if len(query.Statuses) > 0 {
+		conditions = append(conditions, "p.status IN (?"+strings.Repeat(", ?", len(query.Statuses)-1)+")")
+		for _, status := range query.Statuses {
+			args = append(args, status)
+		}
+	}
+	if query.MemberID != "" {
+		condition := "EXISTS (SELECT 1 FROM project_member pm WHERE pm.project_id = p.id AND pm.person_id = ?"
+		args = append(args, query.MemberID)
+		if query.MemberRole != "" {
+			condition += " AND pm.role = ?"
+			args = append(args, query.MemberRole)
+		}
+		conditions = append(conditions, condition+")")
+	}
+	if query.TitleContains != "" {
+		conditions = append(conditions, "p.title LIKE ?")
+		args = append(args, "%"+escapeLike(query.TitleContains)+"%")
+	}
+	if query.Currency != "" {
+		conditions = append(conditions, "pb.currency = ?")
+		args = append(args, query.Currency)
+	}
+	if query.MinBudget != nil {
+		conditions = append(conditions, "pb.budget_value >= ?")
+		args = append(args, *query.MinBudget)
+	}
+	if query.MaxBudget != nil {
+		conditions = append(conditions, "pb.budget_value <= ?")
+		args = append(args, *query.MaxBudget)
+	}
+	if query.DeadlineAfter != nil {
+		conditions = append(conditions, "pb.deadline >= ?")
+		args = append(args, query.DeadlineAfter.UTC())
+	}
+	if query.DeadlineBefore != nil {
+		conditions = append(conditions, "pb.deadline < ?")
+		args = append(args, query.DeadlineBefore.UTC())
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// escapeLike escapes the wildcard characters of a LIKE pattern.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+func (r *mysqlProjectRepository) Get(ctx context.Context, id string) (projectModel, error) {
+	query := selectProjectQuery + " WHERE p.id = ?"
+	done := observeQuery(ctx, "get_project", query)
+	proj, err := scanProject(r.db.QueryRowContext(ctx, query, id))
+	done(err)
+	if errors.Is(err, sql.ErrNoRows) {
+		return projectModel{}, errProjectNotFound
+	}
+	return proj, err
+}
+
+func (r *mysqlProjectRepository) Create(ctx context.Context, project projectModel) (projectModel, error) {
+	if err := checkDeadline(projectModel{}, project, time.Now()); err != nil {
+		return projectModel{}, err
+	}
+
+	// Start a transaction
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return projectModel{}, err
+	}
+	// Rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	if project.Leader, err = lockLeader(ctx, tx, project.LeaderID); err != nil {
+		return projectModel{}, err
+	}
+
+	project.Status = statusPlanned
+	project.StatusChangedAt = newDateTime(time.Now())
+
+	// Insert query for the project table within the transaction
+	projectQuery := "INSERT INTO project (title, leader_id, status, status_changed_at) VALUES (?, ?, ?, ?)"
+	projectResult, err := execQuery(ctx, tx, "insert_project", projectQuery, project.Title, project.LeaderID, project.Status, project.StatusChangedAt)
+	if err != nil {
+		log.Ctx(ctx).Error().Msg("Error inserting project to database: " + err.Error())
+		return projectModel{}, err
+	}
+
+	projectID, err := projectResult.LastInsertId()
+	if err != nil {
+		log.Ctx(ctx).Error().Msg("Error getting last inserted id: " + err.Error())
+		return projectModel{}, err
+	}
+
+	// Insert query for the project_budget table within the transaction
+	budgetQuery := "INSERT INTO project_budget (budget_value, down_payment, currency, deadline, project_id) VALUES (?, ?, ?, ?, ?)"
+	_, err = execQuery(ctx, tx, "insert_project_budget", budgetQuery, project.Budget.BudgetValue.Amount, project.Budget.DownPayment.Amount, project.Budget.BudgetValue.Currency, project.Budget.Deadline, projectID)
+	if err != nil {
+		log.Ctx(ctx).Error().Msg("Error inserting into project_budget table: " + err.Error())
+		return projectModel{}, err
+	}
+
+	project.ID = strconv.FormatInt(projectID, 10)
+	project.Version = 1
+	project.Budget.Version = 1
+	project.Budget.paymentSummary = summarizePayments(project.Budget.BudgetValue, 0)
+
+	if err := setLeaderMember(ctx, tx, project.ID, "", project.LeaderID); err != nil {
+		return projectModel{}, err
+	}
+	if err := insertAudit(ctx, tx, auditProjectCreate, project.ID, "", nil, project); err != nil {
+		return projectModel{}, err
+	}
+
+	// Commit the transaction if all insertions were successful
+	if err := commit(ctx, tx); err != nil {
+		return projectModel{}, err
+	}
+
+	return project, nil
+}
+
+func (r *mysqlProjectRepository) Update(ctx context.Context, id string, project projectModel, precondition projectPrecondition) (projectModel, error) {
+	return r.Patch(ctx, id, precondition, func(current projectModel) (projectModel, error) {
+		if err := checkDeadline(current, project, time.Now()); err != nil {
+			return projectModel{}, err
+		}
+		return project, nil
+	})
+}
+
+func (r *mysqlProjectRepository) Patch(ctx context.Context, id string, precondition projectPrecondition, apply func(projectModel) (projectModel, error)) (projectModel, error) {
+	// Start a transaction
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return projectModel{}, err
+	}
+	// Rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	current, err := lockProject(ctx, tx, id, precondition)
+	if err != nil {
+		return projectModel{}, err
+	}
+
+	patched, err := apply(current)
+	if err != nil {
+		return projectModel{}, err
+	}
+	if err := checkPaidBudget(current, patched); err != nil {
+		return projectModel{}, err
+	}
+	if err := checkCompletedBudget(current, patched); err != nil {
+		return projectModel{}, err
+	}
+	patched = withNextVersions(current, patched)
+
+	patched.Leader = current.Leader
+	if patched.LeaderID != current.LeaderID {
+		if patched.Leader, err = lockLeader(ctx, tx, patched.LeaderID); err != nil {
+			return projectModel{}, err
+		}
+		if err := setLeaderMember(ctx, tx, id, current.LeaderID, patched.LeaderID); err != nil {
+			return projectModel{}, err
+		}
+	}
+
+	// Only write the columns that were changed by the patch
+	projectColumns := changedColumns(
+		column{"title", current.Title, patched.Title},
+		column{"leader_id", current.LeaderID, patched.LeaderID},
+		column{"version", current.Version, patched.Version},
+	)
+	if err := updateColumns(ctx, tx, "project", "id", id, projectColumns); err != nil {
+		log.Ctx(ctx).Error().Msg("Error updating project table: " + err.Error())
+		return projectModel{}, err
+	}
+
+	budgetColumns := changedColumns(
+		column{"budget_value", current.Budget.BudgetValue.Amount, patched.Budget.BudgetValue.Amount},
+		column{"down_payment", current.Budget.DownPayment.Amount, patched.Budget.DownPayment.Amount},
+		column{"currency", current.Budget.BudgetValue.Currency, patched.Budget.BudgetValue.Currency},
+		column{"deadline", current.Budget.Deadline, patched.Budget.Deadline},
+		column{"version", current.Budget.Version, patched.Budget.Version},
+	)
+	if err := updateColumns(ctx, tx, "project_budget", "project_id", id, budgetColumns); err != nil {
+		log.Ctx(ctx).Error().Msg("Error updating project_budget table: " + err.Error())
+		return projectModel{}, err
+	}
+
+	if err := insertAudit(ctx, tx, auditProjectUpdate, id, "", current, patched); err != nil {
+		return projectModel{}, err
+	}
+
+	// Commit the transaction if all updates were successful
+	if err := commit(ctx, tx); err != nil {
+		return projectModel{}, err
+	}
+
+	return patched, nil
+}
+
+// column is a column of a row along with its stored and its new value.
+type column struct {
+	name     string
+	old, new any
+}
+
+func changedColumns(columns ...column) []column {
+	var changed []column
+	for _, c := range columns {
+		if c.old != c.new {
+			changed = append(changed, c)
+		}
+	}
+	return changed
+}
+
+// updateColumns sets the new values of columns on the rows of table where
+// key equals id. It does nothing when columns is empty.
+func updateColumns(ctx context.Context, tx *sql.Tx, table, key, id string, columns []column) error {
+	if len(columns) == 0 {
+		return nil
+	}
+
+	assignments := make([]string, len(columns))
+	args := make([]any, 0, len(columns)+1)
+	for i, c := range columns {
+		assignments[i] = c.name + " = ?"
+		args = append(args, c.new)
+	}
+	args = append(args, id)
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = ?", table, strings.Join(assignments, ", "), key)
+	_, err := execQuery(ctx, tx, "update_"+table, query, args...)
+	return err
+}
+
+func (r *mysqlProjectRepository) Delete(ctx context.Context, id string, precondition projectPrecondition) error {
+	// Start a transaction
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	// Rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	current, err := lockProject(ctx, tx, id, precondition)
+	if err != nil {
+		return err
+	}
+
+	// Delete query for the project_payment table within the transaction
+	paymentQuery := "DELETE FROM project_payment WHERE project_id = ?"
+	if _, err := execQuery(ctx, tx, "delete_project_payments", paymentQuery, id); err != nil {
+		log.Ctx(ctx).Error().Msg("Error deleting from project_payment table: " + err.Error())
+		return err
+	}
+
+	// Delete query for the project_transition table within the transaction
+	transitionQuery := "DELETE FROM project_transition WHERE project_id = ?"
+	if _, err := execQuery(ctx, tx, "delete_project_transitions", transitionQuery, id); err != nil {
+		log.Ctx(ctx).Error().Msg("Error deleting from project_transition table: " + err.Error())
+		return err
+	}
+
+	// Delete query for the project_member table within the transaction
+	memberQuery := "DELETE FROM project_member WHERE project_id = ?"
+	if _, err := execQuery(ctx, tx, "delete_project_members", memberQuery, id); err != nil {
+		log.Ctx(ctx).Error().Msg("Error deleting from project_member table: " + err.Error())
+		return err
+	}
+
+	// Delete query for the project_budget table within the transaction
+	budgetQuery := "DELETE FROM project_budget WHERE project_id = ?"
+	if _, err := execQuery(ctx, tx, "delete_project_budget", budgetQuery, id); err != nil {
+		log.Ctx(ctx).Error().Msg("Error deleting from project_budget table: " + err.Error())
+		return err
+	}
+
+	// Delete query for the project table within the transaction
+	projectQuery := "DELETE FROM project WHERE id = ?"
+	deleteProjectResult, err := execQuery(ctx, tx, "delete_project", projectQuery, id)
+	if err != nil {
+		log.Ctx(ctx).Error().Msg("Error deleting from project table: " + err.Error())
+		return err
+	}
+
+	if rowAffected, _ := deleteProjectResult.RowsAffected(); rowAffected == 0 {
+		return errProjectNotFound
+	}
+
+	if err := insertAudit(ctx, tx, auditProjectDelete, id, "", current, nil); err != nil {
+		return err
+	}
+
+	// Commit the transaction if all deletions were successful
+	return commit(ctx, tx)
+}
+
+// lockProject reads the project with the given id and locks its rows until
+// tx ends. It returns errPreconditionFailed if the project does not satisfy
+// precondition.
+func lockProject(ctx context.Context, tx *sql.Tx, id string, precondition projectPrecondition) (projectModel, error) {
+	query := selectProjectQuery + " WHERE p.id = ? FOR UPDATE"
+	done := observeQuery(ctx, "lock_project", query)
+	proj, err := scanProject(tx.QueryRowContext(ctx, query, id))
+	done(err)
+	if errors.Is(err, sql.ErrNoRows) {
+		return projectModel{}, errProjectNotFound
+	}
+	if err != nil {
+		return projectModel{}, err
+	}
+
+	if precondition != nil && !precondition(proj) {
+		return projectModel{}, errPreconditionFailed
+	}
+	return proj, nil
+}
+
+const selectPaymentQuery = "SELECT id, amount, currency, paid_at, payer, note FROM project_payment"
+
+func scanPayment(row rowScanner) (paymentModel, error) {
+	var payment paymentModel
+	err := row.Scan(&payment.ID, &payment.Amount.Amount, &payment.Amount.Currency, &payment.PaidAt, &payment.Payer, &payment.Note)
+	return payment, err
+}
+
+func (r *mysqlProjectRepository) ListPayments(ctx context.Context, projectID string) ([]paymentModel, error) {
+	if err := r.projectExists(ctx, projectID); err != nil {
+		return nil, err
+	}
+
+	query := selectPaymentQuery + " WHERE project_id = ? ORDER BY paid_at, id"
+	done := observeQuery(ctx, "list_payments", query)
+	payments, err := r.queryPayments(ctx, query, projectID)
+	done(err)
+	return payments, err
+}
+
+func (r *mysqlProjectRepository) queryPayments(ctx context.Context, query string, args ...any) ([]paymentModel, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var payments []paymentModel
+	for rows.Next() {
+		payment, err := scanPayment(rows)
+		if err != nil {
+			return nil, err
+		}
+		payments = append(payments, payment)
+	}
+	return payments, rows.Err()
+}
+
+func (r *mysqlProjectRepository) GetPayment(ctx context.Context, projectID, id string) (paymentModel, error) {
+	query := selectPaymentQuery + " WHERE project_id = ? AND id = ?"
+	done := observeQuery(ctx, "get_payment", query)
+	payment, err := scanPayment(r.db.QueryRowContext(ctx, query, projectID, id))
+	done(err)
+	if errors.Is(err, sql.ErrNoRows) {
+		return paymentModel{}, errPaymentNotFound
+	}
+	return payment, err
+}
+
+func (r *mysqlProjectRepository) CreatePayment(ctx context.Context, projectID string, payment paymentModel) (paymentModel, error) {
+	// Start a transaction
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return paymentModel{}, err
+	}
+	// Rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	// Locking the project keeps concurrent payments from overdrawing the
+	// remaining balance
+	proj, err := lockProject(ctx, tx, projectID, nil)
+	if err != nil {
+		return paymentModel{}, err
+	}
+	if err := checkPaymentsOpen(proj); err != nil {
+		return paymentModel{}, err
+	}
+	if err := checkPayment(proj, payment); err != nil {
+		return paymentModel{}, err
+	}
+
+	query := "INSERT INTO project_payment (project_id, amount, currency, paid_at, payer, note) VALUES (?, ?, ?, ?, ?, ?)"
+	result, err := execQuery(ctx, tx, "insert_payment", query, projectID, payment.Amount.Amount, payment.Amount.Currency, payment.PaidAt, payment.Payer, payment.Note)
+	if err != nil {
+		log.Ctx(ctx).Error().Msg("Error inserting into project_payment table: " + err.Error())
+		return paymentModel{}, err
+	}
+
+	paymentID, err := result.LastInsertId()
+	if err != nil {
+		log.Ctx(ctx).Error().Msg("Error getting last inserted id: " + err.Error())
+		return paymentModel{}, err
+	}
+
+	if err := bumpBudgetVersion(ctx, tx, projectID); err != nil {
+		return paymentModel{}, err
+	}
+
+	payment.ID = strconv.FormatInt(paymentID, 10)
+	if err := insertAudit(ctx, tx, auditPaymentCreate, projectID, "/payments/"+payment.ID, nil, payment); err != nil {
+		return paymentModel{}, err
+	}
+
+	// Commit the transaction if all writes were successful
+	if err := commit(ctx, tx); err != nil {
+		return paymentModel{}, err
+	}
+
+	return payment, nil
+}
+
+func (r *mysqlProjectRepository) DeletePayment(ctx context.Context, projectID, id string) error {
+	// Start a transaction
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	// Rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	proj, err := lockProject(ctx, tx, projectID, nil)
+	if err != nil {
+		return err
+	}
+	if err := checkPaymentsOpen(proj); err != nil {
+		return err
+	}
+
+	// The payment is read first to record it in the audit log
+	query := selectPaymentQuery + " WHERE project_id = ? AND id = ?"
+	done := observeQuery(ctx, "get_payment", query)
+	payment, err := scanPayment(tx.QueryRowContext(ctx, query, projectID, id))
+	done(err)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errPaymentNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	query = "DELETE FROM project_payment WHERE project_id = ? AND id = ?"
+	if _, err := execQuery(ctx, tx, "delete_payment", query, projectID, id); err != nil {
+		log.Ctx(ctx).Error().Msg("Error deleting from project_payment table: " + err.Error())
+		return err
+	}
+
+	if err := bumpBudgetVersion(ctx, tx, projectID); err != nil {
+		return err
+	}
+	if err := insertAudit(ctx, tx, auditPaymentDelete, projectID, "/payments/"+id, payment, nil); err != nil {
+		return err
+	}
+
+	// Commit the transaction if all writes were successful
+	return commit(ctx, tx)
+}
+
+// bumpBudgetVersion changes the ETag of a project whose payment summary has
+// changed.
+func bumpBudgetVersion(ctx context.Context, tx *sql.Tx, projectID string) error {
+	query := "UPDATE project_budget SET version = version + 1 WHERE project_id = ?"
+	if _, err := execQuery(ctx, tx, "update_project_budget", query, projectID); err != nil {
+		log.Ctx(ctx).Error().Msg("Error updating project_budget table: " + err.Error())
+		return err
+	}
+	return nil
+}
+
+func (r *mysqlProjectRepository) Transition(ctx context.Context, id string, precondition projectPrecondition, status, reason string) (projectModel, transitionModel, error) {
+	// Start a transaction
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return projectModel{}, transitionModel{}, err
+	}
+	// Rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	proj, err := lockProject(ctx, tx, id, precondition)
+	if err != nil {
+		return projectModel{}, transitionModel{}, err
+	}
+	if err := checkTransition(proj.Status, status); err != nil {
+		return projectModel{}, transitionModel{}, err
+	}
+
+	transition := transitionModel{From: proj.Status, To: status, Reason: reason, At: newDateTime(time.Now())}
+	query := "INSERT INTO project_transition (project_id, from_status, to_status, reason, created_at) VALUES (?, ?, ?, ?, ?)"
+	result, err := execQuery(ctx, tx, "insert_transition", query, id, transition.From, transition.To, transition.Reason, transition.At)
+	if err != nil {
+		log.Ctx(ctx).Error().Msg("Error inserting into project_transition table: " + err.Error())
+		return projectModel{}, transitionModel{}, err
+	}
+	transitionID, err := result.LastInsertId()
+	if err != nil {
+		log.Ctx(ctx).Error().Msg("Error getting last inserted id: " + err.Error())
+		return projectModel{}, transitionModel{}, err
+	}
+
+	updated := proj
+	updated.Status = status
+	updated.StatusChangedAt = transition.At
+	updated.Version++
+	projectColumns := changedColumns(
+		column{"status", proj.Status, updated.Status},
+		column{"status_changed_at", proj.StatusChangedAt, updated.StatusChangedAt},
+		column{"version", proj.Version, updated.Version},
+	)
+	if err := updateColumns(ctx, tx, "project", "id", id, projectColumns); err != nil {
+		log.Ctx(ctx).Error().Msg("Error updating project table: " + err.Error())
+		return projectModel{}, transitionModel{}, err
+	}
+	if err := insertAudit(ctx, tx, auditProjectTransition, id, "", proj, updated); err != nil {
+		return projectModel{}, transitionModel{}, err
+	}
+
+	// Commit the transaction if all writes were successful
+	if err := commit(ctx, tx); err != nil {
+		return projectModel{}, transitionModel{}, err
+	}
+
+	transition.ID = strconv.FormatInt(transitionID, 10)
+	return updated, transition, nil
+}
+
+func (r *mysqlProjectRepository) ListTransitions(ctx context.Context, id string) ([]transitionModel, error) {
+	if err := r.projectExists(ctx, id); err != nil {
+		return nil, err
+	}
+
+	query := "SELECT id, from_status, to_status, reason, created_at FROM project_transition WHERE project_id = ? ORDER BY id"
+	done := observeQuery(ctx, "list_transitions", query)
+	transitions, err := r.queryTransitions(ctx, query, id)
+	done(err)
+	return transitions, err
+}
+
+func (r *mysqlProjectRepository) queryTransitions(ctx context.Context, query string, args ...any) ([]transitionModel, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var transitions []transitionModel
+	for rows.Next() {
+		var transition transitionModel
+		if err := rows.Scan(&transition.ID, &transition.From, &transition.To, &transition.Reason, &transition.At); err != nil {
+			return nil, err
+		}
+		transitions = append(transitions, transition)
+	}
+	return transitions, rows.Err()
+}
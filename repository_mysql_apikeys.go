@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/rs/zerolog/log"
+)
+
+const selectAPIKeyQuery = "SELECT id, name, prefix, scopes, created_at, expires_at, last_used_at, revoked_at FROM api_key"
+
+func scanAPIKey(row rowScanner) (apiKeyModel, error) {
+	var key apiKeyModel
+	var scopes string
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+	err := row.Scan(&key.ID, &key.Name, &key.Prefix, &scopes, &key.CreatedAt, &expiresAt, &lastUsedAt, &revokedAt)
+	key.Scopes = strings.Split(scopes, ",")
+	key.ExpiresAt, key.LastUsedAt, key.RevokedAt = expiresAt.Time, lastUsedAt.Time, revokedAt.Time
+	return key, err
+}
+
+// nullTime stores zero times as NULL.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+func (r *mysqlProjectRepository) CreateAPIKey(ctx context.Context, key apiKeyModel, hash string) (apiKeyModel, error) {
+	query := "INSERT INTO api_key (name, prefix, hash, scopes, created_at, expires_at) VALUES (?, ?, ?, ?, ?, ?)"
+	done := observeQuery(ctx, "insert_api_key", query)
+	result, err := r.db.ExecContext(ctx, query, key.Name, key.Prefix, hash, strings.Join(key.Scopes, ","), key.CreatedAt, nullTime(key.ExpiresAt))
+	done(err)
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDupEntry {
+		return apiKeyModel{}, errDuplicateAPIKeyName
+	}
+	if err != nil {
+		log.Ctx(ctx).Error().Msg("Error inserting into api_key table: " + err.Error())
+		return apiKeyModel{}, err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		log.Ctx(ctx).Error().Msg("Error getting last inserted id: " + err.Error())
+		return apiKeyModel{}, err
+	}
+
+	key.ID = strconv.FormatInt(id, 10)
+	return key, nil
+}
+
+func (r *mysqlProjectRepository) ListAPIKeys(ctx context.Context) ([]apiKeyModel, error) {
+	query := selectAPIKeyQuery + " ORDER BY id"
+	done := observeQuery(ctx, "list_api_keys", query)
+	keys, err := r.queryAPIKeys(ctx, query)
+	done(err)
+	return keys, err
+}
+
+func (r *mysqlProjectRepository) queryAPIKeys(ctx context.Context, query string, args ...any) ([]apiKeyModel, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var keys []apiKeyModel
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (r *mysqlProjectRepository) FindAPIKey(ctx context.Context, hash string) (apiKeyModel, error) {
+	query := selectAPIKeyQuery + " WHERE hash = ?"
+	done := observeQuery(ctx, "find_api_key", query)
+	key, err := scanAPIKey(r.db.QueryRowContext(ctx, query, hash))
+	done(err)
+	if errors.Is(err, sql.ErrNoRows) {
+		return apiKeyModel{}, errAPIKeyNotFound
+	}
+	return key, err
+}
+
+func (r *mysqlProjectRepository) TouchAPIKey(ctx context.Context, id string, at time.Time) error {
+	query := "UPDATE api_key SET last_used_at = ? WHERE id = ?"
+	done := observeQuery(ctx, "touch_api_key", query)
+	_, err := r.db.ExecContext(ctx, query, at, id)
+	done(err)
+	return err
+}
+
+func (r *mysqlProjectRepository) RevokeAPIKey(ctx context.Context, id string, at time.Time) error {
+	query := "UPDATE api_key SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL"
+	done := observeQuery(ctx, "revoke_api_key", query)
+	result, err := r.db.ExecContext(ctx, query, at, id)
+	done(err)
+	if err != nil {
+		log.Ctx(ctx).Error().Msg("Error updating api_key table: " + err.Error())
+		return err
+	}
+
+	// Revoking twice keeps the time of the first revocation
+	if rowAffected, _ := result.RowsAffected(); rowAffected == 0 {
+		query = "SELECT 1 FROM api_key WHERE id = ?"
+		done = observeQuery(ctx, "api_key_exists", query)
+		var exists int
+		err = r.db.QueryRowContext(ctx, query, id).Scan(&exists)
+		done(err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return errAPIKeyNotFound
+		}
+		return err
+	}
+	return nil
+}
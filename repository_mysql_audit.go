@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+func (r *mysqlProjectRepository) ListAudit(ctx context.Context, query auditQuery) ([]auditEntry, error) {
+	var conditions []string
+	var args []any
+
+	if query.Before != 0 {
+		conditions = append(conditions, "id < ?")
+		args = append(args, query.Before)
+	}
+	if query.ProjectID != "" {
+		conditions = append(conditions, "project_id = ?")
+		args = append(args, query.ProjectID)
+	}
+	if query.Actor != "" {
+		conditions = append(conditions, "actor = ?")
+		args = append(args, query.Actor)
+	}
+	if query.Action != "" {
+		conditions = append(conditions, "action = ?")
+		args = append(args, query.Action)
+	}
+	if query.Since != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, query.Since.UTC())
+	}
+	if query.Until != nil {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, query.Until.UTC())
+	}
+
+	listQuery := "SELECT id, created_at, actor, action, project_id, request_id, changes FROM audit_log"
+	if len(conditions) > 0 {
+		listQuery += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	listQuery += " ORDER BY id DESC LIMIT ?"
+	args = append(args, query.Limit)
+
+	done := observeQuery(ctx, "list_audit", listQuery)
+	entries, err := r.queryAudit(ctx, listQuery, args...)
+	done(err)
+	return entries, err
+}
+
+func (r *mysqlProjectRepository) queryAudit(ctx context.Context, query string, args ...any) ([]auditEntry, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var entries []auditEntry
+	for rows.Next() {
+		var entry auditEntry
+		var changes []byte
+		if err := rows.Scan(&entry.ID, &entry.At, &entry.Actor, &entry.Action, &entry.ProjectID, &entry.RequestID, &changes); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(changes, &entry.Changes); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// insertAudit appends the entry recording a change to the audit log within
+// tx, unless nothing changed. See newAuditEntry for the arguments.
+func insertAudit(ctx context.Context, tx *sql.Tx, action, projectID, path string, before, after any) error {
+	entry, err := newAuditEntry(ctx, action, projectID, path, before, after)
+	if err != nil {
+		return err
+	}
+	if len(entry.Changes) == 0 {
+		return nil
+	}
+
+	changes, err := json.Marshal(entry.Changes)
+	if err != nil {
+		return err
+	}
+
+	query := "INSERT INTO audit_log (created_at, actor, action, project_id, request_id, changes) VALUES (?, ?, ?, ?, ?, ?)"
+	if _, err := execQuery(ctx, tx, "insert_audit", query, entry.At, entry.Actor, entry.Action, projectID, entry.RequestID, string(changes)); err != nil {
+		log.Ctx(ctx).Error().Msg("Error inserting into audit_log table: " + err.Error())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/rs/zerolog/log"
+)
+
+const selectPersonQuery = "SELECT id, name, email FROM person"
+
+func scanPerson(row rowScanner) (personModel, error) {
+	var person personModel
+	var email sql.NullString
+	err := row.Scan(&person.ID, &person.Name, &email)
+	person.Email = email.String
+	return person, err
+}
+
+// nullString stores empty strings as NULL, so that unique keys only apply to
+// the values that are set.
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// projectExists returns errProjectNotFound unless there is a project with
+// the given id.
+func (r *mysqlProjectRepository) projectExists(ctx context.Context, id string) error {
+	query := "SELECT 1 FROM project WHERE id = ?"
+	done := observeQuery(ctx, "project_exists", query)
+	var exists int
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&exists)
+	done(err)
+	if errors.Is(err, sql.ErrNoRows) {
+		return errProjectNotFound
+	}
+	return err
+}
+
+// lockPerson returns the name of the person with the given id and keeps them
+// from being deleted until tx ends.
+func lockPerson(ctx context.Context, tx *sql.Tx, id string) (string, error) {
+	query := "SELECT name FROM person WHERE id = ? LOCK IN SHARE MODE"
+	done := observeQuery(ctx, "lock_person", query)
+	var name string
+	err := tx.QueryRowContext(ctx, query, id).Scan(&name)
+	done(err)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", errPersonNotFound
+	}
+	return name, err
+}
+
+// lockLeader is lockPerson for the leader of a project.
+func lockLeader(ctx context.Context, tx *sql.Tx, id string) (string, error) {
+	name, err := lockPerson(ctx, tx, id)
+	if errors.Is(err, errPersonNotFound) {
+		return "", errLeaderNotFound
+	}
+	return name, err
+}
+
+// setLeaderMember gives the leader role to the member newLeaderID of a
+// project, adding them when needed. The former leader oldLeaderID, if any,
+// stays on as a member.
+func setLeaderMember(ctx context.Context, tx *sql.Tx, projectID, oldLeaderID, newLeaderID string) error {
+	if oldLeaderID != "" {
+		query := "UPDATE project_member SET role = ? WHERE project_id = ? AND person_id = ? AND role = ?"
+		if _, err := execQuery(ctx, tx, "demote_leader", query, roleMember, projectID, oldLeaderID, roleLeader); err != nil {
+			log.Ctx(ctx).Error().Msg("Error updating project_member table: " + err.Error())
+			return err
+		}
+	}
+	return upsertMember(ctx, tx, projectID, newLeaderID, roleLeader)
+}
+
+// lockMember reads a member of a project and locks their row until tx ends.
+// It returns errMemberNotFound if the person is not a member.
+func lockMember(ctx context.Context, tx *sql.Tx, projectID, personID string) (memberModel, error) {
+	query := "SELECT pm.person_id, pe.name, pm.role FROM project_member pm JOIN person pe ON pe.id = pm.person_id WHERE pm.project_id = ? AND pm.person_id = ? FOR UPDATE"
+	done := observeQuery(ctx, "lock_member", query)
+	var member memberModel
+	err := tx.QueryRowContext(ctx, query, projectID, personID).Scan(&member.PersonID, &member.Name, &member.Role)
+	done(err)
+	if errors.Is(err, sql.ErrNoRows) {
+		return memberModel{}, errMemberNotFound
+	}
+	return member, err
+}
+
+func upsertMember(ctx context.Context, tx *sql.Tx, projectID, personID, role string) error {
+	query := "INSERT INTO project_member (project_id, person_id, role) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE role = ?"
+	if _, err := execQuery(ctx, tx, "upsert_member", query, projectID, personID, role, role); err != nil {
+		log.Ctx(ctx).Error().Msg("Error inserting into project_member table: " + err.Error())
+		return err
+	}
+	return nil
+}
+
+func (r *mysqlProjectRepository) ListMembers(ctx context.Context, projectID string) ([]memberModel, error) {
+	if err := r.projectExists(ctx, projectID); err != nil {
+		return nil, err
+	}
+
+	query := "SELECT pm.person_id, pe.name, pm.role FROM project_member pm JOIN person pe ON pe.id = pm.person_id WHERE pm.project_id = ?"
+	done := observeQuery(ctx, "list_members", query)
+	members, err := r.queryMembers(ctx, query, projectID)
+	done(err)
+	if err != nil {
+		return nil, err
+	}
+
+	sortMembers(members)
+	return members, nil
+}
+
+func (r *mysqlProjectRepository) queryMembers(ctx context.Context, query string, args ...any) ([]memberModel, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var members []memberModel
+	for rows.Next() {
+		var member memberModel
+		if err := rows.Scan(&member.PersonID, &member.Name, &member.Role); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+func (r *mysqlProjectRepository) SetMember(ctx context.Context, projectID, personID, role string) (memberModel, error) {
+	if role == roleLeader {
+		// A new leader changes the project itself
+		patched, err := r.Patch(ctx, projectID, nil, func(current projectModel) (projectModel, error) {
+			current.LeaderID = personID
+			return current, nil
+		})
+		if errors.Is(err, errLeaderNotFound) {
+			return memberModel{}, errPersonNotFound
+		}
+		if err != nil {
+			return memberModel{}, err
+		}
+		return memberModel{PersonID: patched.LeaderID, Name: patched.Leader, Role: roleLeader}, nil
+	}
+
+	// Start a transaction
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return memberModel{}, err
+	}
+	// Rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	proj, err := lockProject(ctx, tx, projectID, nil)
+	if err != nil {
+		return memberModel{}, err
+	}
+	if proj.LeaderID == personID {
+		return memberModel{}, errLeaderRequired
+	}
+
+	name, err := lockPerson(ctx, tx, personID)
+	if err != nil {
+		return memberModel{}, err
+	}
+	// The former role, if any, is recorded in the audit log
+	var before any
+	current, err := lockMember(ctx, tx, projectID, personID)
+	switch {
+	case err == nil:
+		before = current
+	case !errors.Is(err, errMemberNotFound):
+		return memberModel{}, err
+	}
+	if err := upsertMember(ctx, tx, projectID, personID, role); err != nil {
+		return memberModel{}, err
+	}
+
+	member := memberModel{PersonID: personID, Name: name, Role: role}
+	if err := insertAudit(ctx, tx, auditMemberSet, projectID, "/members/"+personID, before, member); err != nil {
+		return memberModel{}, err
+	}
+
+	// Commit the transaction if all writes were successful
+	if err := commit(ctx, tx); err != nil {
+		return memberModel{}, err
+	}
+
+	return member, nil
+}
+
+func (r *mysqlProjectRepository) DeleteMember(ctx context.Context, projectID, personID string) error {
+	// Start a transaction
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	// Rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	proj, err := lockProject(ctx, tx, projectID, nil)
+	if err != nil {
+		return err
+	}
+	if proj.LeaderID == personID {
+		return errLeaderRequired
+	}
+	member, err := lockMember(ctx, tx, projectID, personID)
+	if err != nil {
+		return err
+	}
+
+	query := "DELETE FROM project_member WHERE project_id = ? AND person_id = ?"
+	if _, err := execQuery(ctx, tx, "delete_member", query, projectID, personID); err != nil {
+		log.Ctx(ctx).Error().Msg("Error deleting from project_member table: " + err.Error())
+		return err
+	}
+	if err := insertAudit(ctx, tx, auditMemberDelete, projectID, "/members/"+personID, member, nil); err != nil {
+		return err
+	}
+
+	// Commit the transaction if the deletion was successful
+	return commit(ctx, tx)
+}
+
+func (r *mysqlProjectRepository) ListPeople(ctx context.Context) ([]personModel, error) {
+	query := selectPersonQuery + " ORDER BY name, id"
+	done := observeQuery(ctx, "list_people", query)
+	people, err := r.queryPeople(ctx, query)
+	done(err)
+	return people, err
+}
+
+func (r *mysqlProjectRepository) queryPeople(ctx context.Context, query string, args ...any) ([]personModel, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var people []personModel
+	for rows.Next() {
+		person, err := scanPerson(rows)
+		if err != nil {
+			return nil, err
+		}
+		people = append(people, person)
+	}
+	return people, rows.Err()
+}
+
+func (r *mysqlProjectRepository) GetPerson(ctx context.Context, id string) (personModel, error) {
+	query := selectPersonQuery + " WHERE id = ?"
+	done := observeQuery(ctx, "get_person", query)
+	person, err := scanPerson(r.db.QueryRowContext(ctx, query, id))
+	done(err)
+	if errors.Is(err, sql.ErrNoRows) {
+		return personModel{}, errPersonNotFound
+	}
+	return person, err
+}
+
+func (r *mysqlProjectRepository) CreatePerson(ctx context.Context, person personModel) (personModel, error) {
+	query := "INSERT INTO person (name, email) VALUES (?, ?)"
+	done := observeQuery(ctx, "insert_person", query)
+	result, err := r.db.ExecContext(ctx, query, person.Name, nullString(person.Email))
+	done(err)
+	if err != nil {
+		return personModel{}, duplicateEmail(err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		log.Ctx(ctx).Error().Msg("Error getting last inserted id: " + err.Error())
+		return personModel{}, err
+	}
+
+	person.ID = strconv.FormatInt(id, 10)
+	return person, nil
+}
+
+func (r *mysqlProjectRepository) UpdatePerson(ctx context.Context, id string, person personModel) (personModel, error) {
+	query := "UPDATE person SET name = ?, email = ? WHERE id = ?"
+	done := observeQuery(ctx, "update_person", query)
+	result, err := r.db.ExecContext(ctx, query, person.Name, nullString(person.Email), id)
+	done(err)
+	if err != nil {
+		return personModel{}, duplicateEmail(err)
+	}
+
+	// MySQL does not count rows left unchanged as affected
+	if rowAffected, _ := result.RowsAffected(); rowAffected == 0 {
+		if _, err := r.GetPerson(ctx, id); err != nil {
+			return personModel{}, err
+		}
+	}
+
+	person.ID = id
+	return person, nil
+}
+
+// duplicateEmail turns the duplicate key error of the unique email key into
+// errDuplicateEmail.
+func duplicateEmail(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlErrDupEntry {
+		return errDuplicateEmail
+	}
+	return err
+}
+
+func (r *mysqlProjectRepository) DeletePerson(ctx context.Context, id string) error {
+	// Start a transaction
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	// Rollback is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	query := "SELECT EXISTS (SELECT 1 FROM project_member WHERE person_id = ?)"
+	done := observeQuery(ctx, "person_in_use", query)
+	var inUse bool
+	err = tx.QueryRowContext(ctx, query, id).Scan(&inUse)
+	done(err)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return errPersonInUse
+	}
+
+	query = "DELETE FROM person WHERE id = ?"
+	result, err := execQuery(ctx, tx, "delete_person", query, id)
+	if err != nil {
+		log.Ctx(ctx).Error().Msg("Error deleting from person table: " + err.Error())
+		return err
+	}
+	if rowAffected, _ := result.RowsAffected(); rowAffected == 0 {
+		return errPersonNotFound
+	}
+
+	// Commit the transaction if the deletion was successful
+	return commit(ctx, tx)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiVersion is a version of the API mounted under /api/<name>. A new
+// version is added by appending to apiVersions; older versions keep being
+// served alongside it until they are removed.
+type apiVersion struct {
+	name     string
+	register func(api *gin.RouterGroup, h *projectHandler)
+}
+
+var apiVersions = []apiVersion{
+	{name: "v1", register: registerV1Routes},
+}
+
+// The unversioned routes are kept for existing clients until legacySunset.
+var (
+	legacyDeprecation = time.Date(2026, time.October, 18, 0, 0, 0, 0, time.UTC)
+	legacySunset      = time.Date(2027, time.April, 30, 0, 0, 0, 0, time.UTC)
+)
+
+// registerRoutes mounts every API version as well as the deprecated
+// unversioned routes on router. middleware runs before every API route, e.g.
+// to authenticate the caller.
+func registerRoutes(router *gin.Engine, h *projectHandler, middleware ...gin.HandlerFunc) {
+	for _, version := range apiVersions {
+		version.register(router.Group("/api/"+version.name, middleware...), h)
+	}
+
+	legacy := router.Group("", deprecated("/api/v1"))
+	legacy.Use(middleware...)
+	legacy.GET("/projects", h.getProjects)
+	legacy.GET("/projects/:id", h.getProjectById)
+	legacy.POST("/projects", h.postProjects)
+	legacy.PUT("/project/:id", h.updateProject)
+	legacy.DELETE("/project/:id", h.deleteProject)
+}
+
+func registerV1Routes(api *gin.RouterGroup, h *projectHandler) {
+	api.GET("/projects", h.getProjects)
+	api.POST("/projects", h.postProjects)
+	api.GET("/projects/:id", h.getProjectById)
+	api.PUT("/projects/:id", h.updateProject)
+	api.PATCH("/projects/:id", h.patchProject)
+	api.DELETE("/projects/:id", h.deleteProject)
+	api.GET("/projects/:id/payments", h.getPayments)
+	api.POST("/projects/:id/payments", h.postPayment)
+	api.GET("/projects/:id/payments/:paymentId", h.getPaymentById)
+	api.DELETE("/projects/:id/payments/:paymentId", h.deletePayment)
+	api.GET("/projects/:id/transitions", h.getTransitions)
+	api.POST("/projects/:id/transitions", h.postTransition)
+	api.GET("/projects/:id/history", h.getProjectHistory)
+	api.GET("/projects/:id/members", h.getMembers)
+	api.PUT("/projects/:id/members/:personId", h.putMember)
+	api.DELETE("/projects/:id/members/:personId", h.deleteMember)
+	api.GET("/people", h.getPeople)
+	api.POST("/people", h.postPeople)
+	api.GET("/people/:id", h.getPersonById)
+	api.PUT("/people/:id", h.updatePerson)
+	api.DELETE("/people/:id", h.deletePerson)
+	api.GET("/people/:id/projects", h.getPersonProjects)
+	api.GET("/audit", h.getAudit)
+}
+
+// deprecated marks the responses of a route as deprecated and points clients
+// at the same resource under successorPrefix.
+func deprecated(successorPrefix string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		successor := successorPrefix + strings.Replace(c.Request.URL.Path, "/project/", "/projects/", 1)
+
+		c.Header("Deprecation", "@"+strconv.FormatInt(legacyDeprecation.Unix(), 10))
+		c.Header("Sunset", legacySunset.Format(http.TimeFormat))
+		c.Header("Link", "<"+successor+`>; rel="successor-version"`)
+		c.Next()
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// spanRecorder records the spans of every test. The tracer only picks up
+// the first tracer provider installed, so it is shared by all tests.
+var spanRecorder = sync.OnceValue(func() *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return recorder
+})
+
+// recordSpans records the spans ended while run is called and returns them.
+func recordSpans(run func()) []sdktrace.ReadOnlySpan {
+	before := len(spanRecorder().Ended())
+	run()
+	return spanRecorder().Ended()[before:]
+}
+
+func TestRequestTracing(t *testing.T) {
+	router := gin.New()
+	router.Use(requestTracing())
+	router.GET("/api/v1/things/:id", func(c *gin.Context) {
+		observeQuery(c.Request.Context(), "select_thing", "SELECT 1")(errors.New("connection reset"))
+		c.Status(http.StatusInternalServerError)
+	})
+
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	spans := recordSpans(func() {
+		request(router, http.MethodGet, "/api/v1/things/1", "", "traceparent", traceparent)
+	})
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want the query and the request", len(spans))
+	}
+	query, server := spans[0], spans[1]
+
+	if server.Name() != "GET /api/v1/things/:id" || server.SpanKind() != trace.SpanKindServer {
+		t.Errorf("server span = %s %s, want GET /api/v1/things/:id server", server.Name(), server.SpanKind())
+	}
+	if got := server.SpanContext().TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("trace id = %s, want the one of the traceparent header", got)
+	}
+	if got := server.Parent().SpanID().String(); got != "00f067aa0ba902b7" {
+		t.Errorf("parent span id = %s, want the one of the traceparent header", got)
+	}
+	if server.Status().Code != codes.Error {
+		t.Errorf("server span status = %v, want an error for a 500", server.Status())
+	}
+
+	if query.Name() != "select_thing" || query.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Errorf("query span %s is not a child of the request span", query.Name())
+	}
+	if query.Status().Code != codes.Error || len(query.Events()) == 0 {
+		t.Errorf("query span does not record its error: %v", query.Status())
+	}
+}
+
+func TestObserveQueryNoRows(t *testing.T) {
+	spans := recordSpans(func() {
+		observeQuery(context.Background(), "select_missing", "SELECT 1")(sql.ErrNoRows)
+	})
+	if len(spans) != 1 {
+		t.Fatalf("recorded %d spans, want 1", len(spans))
+	}
+	for _, span := range spans {
+		if span.Status().Code == codes.Error {
+			t.Errorf("span %s failed for a successful query", span.Name())
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getTransitions godoc
+// @Summary      Get status history of a project
+// @Description  Get the status changes of a project, oldest first
+// @Tags         Lifecycle
+// @Produce      json
+// @Param        id   path      int  true  "Project ID"
+// @Success      200  {object}  transitionList
+// @Failure      401  {object}  HTTPError
+// @Failure      403  {object}  HTTPError
+// @Failure      404  {object}  HTTPError
+// @Failure      429  {object}  HTTPError
+// @Failure      500  {object}  HTTPError
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /projects/{id}/transitions [get]
+func (h *projectHandler) getTransitions(c *gin.Context) {
+	id := c.Param("id")
+
+	transitions, err := h.repo.ListTransitions(c.Request.Context(), id)
+	if err != nil {
+		c.Error(fmt.Errorf("listing transitions of project %s: %w", id, err))
+		return
+	}
+	if transitions == nil {
+		transitions = []transitionModel{}
+	}
+
+	c.IndentedJSON(http.StatusOK, transitionList{Data: transitions})
+}
+
+// postTransition godoc
+// @Summary      Change status of a project
+// @Description  Move a project to another status. Planned projects can become active or cancelled, active ones on hold, completed or cancelled, and projects on hold active or cancelled. Completed and cancelled projects keep their status. Putting a project on hold or cancelling it needs a reason.
+// @Tags         Lifecycle
+// @Accept       json
+// @Produce      json
+// @Param        id          path      int                true   "Project ID"
+// @Param        If-Match    header    string             false  "Only change the status if the project still has one of these ETags"
+// @Param        transition  body      transitionRequest  true   "New status"
+// @Success      201  {object}  transitionModel
+// @Header       201  {string}  ETag  "Version of the updated project"
+// @Failure      400  {object}  HTTPError
+// @Failure      401  {object}  HTTPError
+// @Failure      403  {object}  HTTPError
+// @Failure      404  {object}  HTTPError
+// @Failure      409  {object}  HTTPError
+// @Failure      412  {object}  HTTPError
+// @Failure      422  {object}  HTTPError
+// @Failure      429  {object}  HTTPError
+// @Failure      500  {object}  HTTPError
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Router       /projects/{id}/transitions [post]
+func (h *projectHandler) postTransition(c *gin.Context) {
+	id := c.Param("id")
+
+	var request transitionRequest
+	if err := bindBody(c, &request, "a transition"); err != nil {
+		c.Error(err)
+		return
+	}
+
+	updated, transition, err := h.repo.Transition(c.Request.Context(), id, ifMatchPrecondition(c.GetHeader("If-Match")), request.Status, request.Reason)
+	if err != nil {
+		c.Error(fmt.Errorf("moving project %s to %s: %w", id, request.Status, err))
+		return
+	}
+
+	c.Header("ETag", projectETag(updated))
+	c.IndentedJSON(http.StatusCreated, transition)
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestCheckTransition(t *testing.T) {
+	for from, allowed := range statusTransitions {
+		for to := range statusTransitions {
+			err := checkTransition(from, to)
+			if want := slices.Contains(allowed, to); (err == nil) != want {
+				t.Errorf("checkTransition(%s, %s) = %v, want allowed %t", from, to, err, want)
+			}
+		}
+	}
+}
+
+// transition moves the project with the given id to status.
+func transition(t *testing.T, router http.Handler, id, status, reason string) {
+	t.Helper()
+	body := `{"status": "` + status + `", "reason": "` + reason + `"}`
+	checkStatus(t, request(router, http.MethodPost, "/api/v1/projects/"+id+"/transitions", body), http.StatusCreated)
+}
+
+func TestPostTransition(t *testing.T) {
+	router, _ := newTestServer(t)
+	jane := createPerson(t, router, "Jane Doe")
+	proj := createProject(t, router, projectBody("Bridge", jane, "3000", "USD", futureDeadline))
+	if proj.Status != statusPlanned {
+		t.Errorf("new project is %s, want planned", proj.Status)
+	}
+	transitions := "/api/v1/projects/" + proj.ID + "/transitions"
+
+	// The steps share one project and build on each other
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		code   string
+		field  string
+	}{
+		{name: "skip to completed", body: `{"status": "completed"}`, status: http.StatusConflict, code: codeInvalidTransition},
+		{name: "start", body: `{"status": "active"}`, status: http.StatusCreated},
+		{name: "hold without reason", body: `{"status": "on_hold", "reason": " "}`, status: http.StatusUnprocessableEntity, code: codeValidationFailed, field: "reason"},
+		{name: "unknown status", body: `{"status": "paused"}`, status: http.StatusUnprocessableEntity, code: codeValidationFailed, field: "status"},
+		{name: "hold", body: `{"status": "on_hold", "reason": "Waiting for the building permit"}`, status: http.StatusCreated},
+		{name: "complete while on hold", body: `{"status": "completed"}`, status: http.StatusConflict, code: codeInvalidTransition},
+		{name: "resume", body: `{"status": "active"}`, status: http.StatusCreated},
+		{name: "complete", body: `{"status": "completed"}`, status: http.StatusCreated},
+		{name: "cancel a completed project", body: `{"status": "cancelled", "reason": "Too late"}`, status: http.StatusConflict, code: codeInvalidTransition},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := request(router, http.MethodPost, transitions, tt.body)
+			if tt.code == "" {
+				checkStatus(t, w, tt.status)
+				return
+			}
+			problem := checkProblem(t, w, tt.status, tt.code)
+			if tt.field != "" && (len(problem.Errors) == 0 || problem.Errors[0].Field != tt.field) {
+				t.Errorf("errors = %+v, want %s failing", problem.Errors, tt.field)
+			}
+		})
+	}
+
+	w := request(router, http.MethodGet, transitions, "")
+	checkStatus(t, w, http.StatusOK)
+	var list transitionList
+	decode(t, w, &list)
+	var moves []string
+	for _, transition := range list.Data {
+		moves = append(moves, transition.From+">"+transition.To)
+	}
+	if !slices.Equal(moves, []string{"planned>active", "active>on_hold", "on_hold>active", "active>completed"}) {
+		t.Errorf("transitions = %q", moves)
+	}
+	if list.Data[1].Reason != "Waiting for the building permit" || list.Data[1].At.IsZero() {
+		t.Errorf("transition to on_hold = %+v, want its reason and time", list.Data[1])
+	}
+
+	checkStatus(t, request(router, http.MethodGet, "/api/v1/projects/999/transitions", ""), http.StatusNotFound)
+	checkStatus(t, request(router, http.MethodPost, "/api/v1/projects/999/transitions", `{"status": "active"}`), http.StatusNotFound)
+}
+
+func TestTransitionPrecondition(t *testing.T) {
+	router, _ := newTestServer(t)
+	jane := createPerson(t, router, "Jane Doe")
+	proj := createProject(t, router, projectBody("Bridge", jane, "3000", "USD", futureDeadline))
+	path := "/api/v1/projects/" + proj.ID
+	etag := request(router, http.MethodGet, path, "").Header().Get("ETag")
+
+	w := request(router, http.MethodPost, path+"/transitions", `{"status": "active"}`, "If-Match", etag)
+	checkStatus(t, w, http.StatusCreated)
+	next := w.Header().Get("ETag")
+	if next == etag || next != request(router, http.MethodGet, path, "").Header().Get("ETag") {
+		t.Errorf("ETag after the transition = %s, want the new ETag of the project", next)
+	}
+
+	w = request(router, http.MethodPost, path+"/transitions", `{"status": "completed"}`, "If-Match", etag)
+	checkProblem(t, w, http.StatusPreconditionFailed, codePreconditionFailed)
+}
+
+func TestCompletedProject(t *testing.T) {
+	router, _ := newTestServer(t)
+	jane := createPerson(t, router, "Jane Doe")
+	proj := createProject(t, router, projectBody("Bridge", jane, "3000", "USD", futureDeadline))
+	path := "/api/v1/projects/" + proj.ID
+
+	w := request(router, http.MethodPost, path+"/payments", paymentBody("1000", "USD"))
+	checkStatus(t, w, http.StatusCreated)
+	var payment paymentModel
+	decode(t, w, &payment)
+
+	transition(t, router, proj.ID, statusActive, "")
+	transition(t, router, proj.ID, statusCompleted, "")
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		code   string
+	}{
+		{name: "change budget", method: http.MethodPut, path: path, body: projectBody("Bridge", jane, "4000", "USD", futureDeadline), status: http.StatusConflict, code: codeProjectCompleted},
+		{name: "record payment", method: http.MethodPost, path: path + "/payments", body: paymentBody("10", "USD"), status: http.StatusConflict, code: codeProjectCompleted},
+		{name: "delete payment", method: http.MethodDelete, path: path + "/payments/" + payment.ID, status: http.StatusConflict, code: codeProjectCompleted},
+		{name: "rename", method: http.MethodPut, path: path, body: projectBody("Bridge 2", jane, "3000", "USD", futureDeadline), status: http.StatusOK},
+		{name: "list payments", method: http.MethodGet, path: path + "/payments", status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := request(router, tt.method, tt.path, tt.body)
+			if tt.code != "" {
+				checkProblem(t, w, tt.status, tt.code)
+				return
+			}
+			checkStatus(t, w, tt.status)
+		})
+	}
+}
+
+func TestGetProjectsByStatus(t *testing.T) {
+	router, _ := newTestServer(t)
+	jane := createPerson(t, router, "Jane Doe")
+	createProject(t, router, projectBody("Bridge", jane, "3000", "USD", futureDeadline))
+	tunnel := createProject(t, router, projectBody("Tunnel", jane, "3000", "USD", futureDeadline))
+	canal := createProject(t, router, projectBody("Canal", jane, "3000", "USD", futureDeadline))
+	transition(t, router, tunnel.ID, statusActive, "")
+	transition(t, router, canal.ID, statusCancelled, "Not funded")
+
+	tests := []struct {
+		query  string
+		status int
+		titles []string
+	}{
+		{query: "?status=planned", status: http.StatusOK, titles: []string{"Bridge"}},
+		{query: "?status=active,cancelled", status: http.StatusOK, titles: []string{"Tunnel", "Canal"}},
+		{query: "?status=completed", status: http.StatusOK, titles: []string{}},
+		{query: "?status=paused", status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			w := request(router, http.MethodGet, "/api/v1/projects"+tt.query, "")
+			checkStatus(t, w, tt.status)
+			if tt.status != http.StatusOK {
+				return
+			}
+			if titles := pageTitles(t, w); !slices.Equal(titles, tt.titles) {
+				t.Errorf("titles = %v, want %v", titles, tt.titles)
+			}
+		})
+	}
+}
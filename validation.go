@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+type fieldError struct {
+	Field   string `json:"field" example:"budget.down_payment"`
+	Rule    string `json:"rule" example:"ltefield"`
+	Message string `json:"message" example:"must not be greater than budget_value"`
+}
+
+// registerValidators teaches gin's validator the custom rules used in the
+// binding tags of the request models and makes it report json field names.
+func registerValidators() error {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return errors.New("unexpected validator engine")
+	}
+
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	// Validate dateTime fields as the time.Time they hold, so that required
+	// rejects the zero time
+	v.RegisterCustomTypeFunc(func(field reflect.Value) any {
+		return field.Interface().(dateTime).Time
+	}, dateTime{})
+
+	v.RegisterStructValidation(validateBudget, budgetModel{})
+	v.RegisterStructValidation(validatePayment, paymentModel{})
+	v.RegisterStructValidation(validateTransitionRequest, transitionRequest{})
+
+	if err := v.RegisterValidation("currency", func(fl validator.FieldLevel) bool {
+		_, err := minorDigits(fl.Field().String())
+		return err == nil
+	}); err != nil {
+		return err
+	}
+
+	return v.RegisterValidation("past_date", func(fl validator.FieldLevel) bool {
+		t, ok := fl.Field().Interface().(time.Time)
+		return ok && !t.After(time.Now())
+	})
+}
+
+// errDeadlinePassed is returned by a ProjectRepository when checkDeadline
+// rejects the deadline of a project.
+var errDeadlinePassed = errors.New("deadline is not in the future")
+
+// checkDeadline returns errDeadlinePassed unless the deadline of updated is in
+// the future or is the deadline of current, so that overdue projects can
+// still be updated without moving their deadline. New projects are checked
+// against the zero projectModel.
+func checkDeadline(current, updated projectModel, now time.Time) error {
+	if updated.Budget.Deadline == current.Budget.Deadline || updated.Budget.Deadline.After(now) {
+		return nil
+	}
+	return errDeadlinePassed
+}
+
+// validateBudget checks the amounts of a budget: none is negative, both are
+// in the same currency and the down payment does not exceed the budget.
+func validateBudget(sl validator.StructLevel) {
+	budget := sl.Current().Interface().(budgetModel)
+
+	if budget.BudgetValue.Amount < 0 {
+		sl.ReportError(budget.BudgetValue, "budget_value", "BudgetValue", "gte", "0")
+	}
+	if budget.DownPayment.Amount < 0 {
+		sl.ReportError(budget.DownPayment, "down_payment", "DownPayment", "gte", "0")
+	}
+	if budget.DownPayment.Currency != budget.BudgetValue.Currency {
+		sl.ReportError(budget.DownPayment.Currency, "down_payment.currency", "DownPayment.Currency", "same_currency", "BudgetValue")
+	} else if budget.DownPayment.Amount > budget.BudgetValue.Amount {
+		sl.ReportError(budget.DownPayment, "down_payment", "DownPayment", "ltefield", "BudgetValue")
+	}
+}
+
+// validatePayment checks that the amount of a payment is positive.
+func validatePayment(sl validator.StructLevel) {
+	payment := sl.Current().Interface().(paymentModel)
+
+	if payment.Amount.Amount <= 0 {
+		sl.ReportError(payment.Amount, "amount", "Amount", "gt", "0")
+	}
+}
+
+// validateTransitionRequest requires a reason to put a project on hold or
+// to cancel it.
+func validateTransitionRequest(sl validator.StructLevel) {
+	request := sl.Current().Interface().(transitionRequest)
+
+	if (request.Status == statusOnHold || request.Status == statusCancelled) && strings.TrimSpace(request.Reason) == "" {
+		sl.ReportError(request.Reason, "reason", "Reason", "required", "")
+	}
+}
+
+// validationFieldErrors turns the errors reported by the validator into the
+// field errors sent to clients.
+func validationFieldErrors(errs validator.ValidationErrors) []fieldError {
+	fieldErrs := make([]fieldError, 0, len(errs))
+	for _, fe := range errs {
+		// Drop the name of the top level struct from the namespace
+		_, field, _ := strings.Cut(fe.Namespace(), ".")
+		fieldErrs = append(fieldErrs, fieldError{
+			Field:   field,
+			Rule:    fe.Tag(),
+			Message: validationMessage(fe),
+		})
+	}
+	return fieldErrs
+}
+
+func validationMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "max":
+		return "must be at most " + fe.Param() + " characters long"
+	case "email":
+		return "must be an email address"
+	case "oneof":
+		return "must be one of " + strings.ReplaceAll(fe.Param(), " ", ", ")
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "gte":
+		return "must be greater than or equal to " + fe.Param()
+	case "ltefield":
+		return "must not be greater than " + jsonFieldName(fe.Param())
+	case "currency":
+		return "must be an ISO 4217 currency code"
+	case "same_currency":
+		return "must be in the currency of " + jsonFieldName(fe.Param())
+	case "past_date":
+		return "must not be in the future"
+	default:
+		return "failed on the " + fe.Tag() + " rule"
+	}
+}
+
+// jsonFieldName converts the Go field names used as rule parameters to the
+// names clients see.
+func jsonFieldName(field string) string {
+	switch field {
+	case "BudgetValue":
+		return "budget_value"
+	case "DownPayment":
+		return "down_payment"
+	default:
+		return field
+	}
+}